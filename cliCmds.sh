@@ -6,17 +6,83 @@ go run main.go fit -d $DATAFILE -y Var4 -g 5 -o coeff.json
 
 echo "Testing pred command"
 go run main.go pred -d $DATAFILE -m coeff.json
+go run main.go pred -d $DATAFILE -m coeff.json --level 0.9
+go run main.go pred -d $DATAFILE -m coeff.json --bootstrap 20 --seed 1
 rm "${FOLDER}/dataset_predictions.csv"
 
+echo "Testing prediction without the training data"
+cp $DATAFILE moved.csv
+go run main.go fit -d moved.csv -y Var4 -g 5 -o moved.json
+rm moved.csv
+go run main.go pred -d $DATAFILE -m moved.json
+rm moved.json "${FOLDER}/dataset_predictions.csv"
+
+echo "Testing multi-target fit"
+go run main.go fit -d $DATAFILE -y Var3,Var4 -g 5 -o multi.json
+go run main.go fit -d $DATAFILE -y Var3,Var4 --shared -g 5 -o multi.json
+go run main.go pred -d $DATAFILE -m multi.json
+go run main.go rmse -d $DATAFILE -m multi.json --format csv
+go run main.go plot -d $DATAFILE -m multi.json -o multi.png
+rm multi.json multi.png "${FOLDER}/dataset_predictions.csv"
+
+echo "Testing classification"
+go run main.go fit -d "${FOLDER}/classification.csv" -y species --type cls -g 5 -o cls.json
+go run main.go pred -d "${FOLDER}/classification.csv" -m cls.json
+go run main.go metrics -d "${FOLDER}/classification.csv" -m cls.json
+go run main.go metrics -d "${FOLDER}/classification.csv" -m cls.json --format json
+rm cls.json "${FOLDER}/classification_predictions.csv"
+
 echo "Test RMSE"
 go run main.go rmse -d $DATAFILE -m coeff.json
+go run main.go rmse -d $DATAFILE -m coeff.json --format json
+go run main.go rmse -d $DATAFILE -m coeff.json --format csv
+
+echo "Test model command"
+go run main.go model validate -m coeff.json
+go run main.go model validate -m coeff.json -d $DATAFILE
+go run main.go model upgrade -m coeff.json -o upgraded.json
+rm upgraded.json
+
+echo "Test compare command"
+go run main.go fit -d $DATAFILE -y Var4 -g 5 -c bic -o bic.json
+go run main.go compare -m coeff.json,bic.json -d $DATAFILE
+go run main.go compare -m coeff.json,bic.json --format json -o comparison.json
+rm bic.json comparison.json
+
+echo "Test summary command"
+go run main.go summary -m coeff.json
+go run main.go summary -m coeff.json -d $DATAFILE --level 0.9
+go run main.go summary -m coeff.json --bootstrap 20 --bootstrap-method residual
+go run main.go summary -m coeff.json --bootstrap 5 --reselect --numgen 5
+
+echo "Test stability command"
+go run main.go stability -d $DATAFILE -y Var4 -n 3 -g 5 --fraction 1 --plot stability.png
+go run main.go stability -d $DATAFILE -y Var4 -n 3 -g 5 --method bootstrap --format json -o stability.json
+rm stability.png stability.json
+
+echo "Test export command"
+go run main.go export -m coeff.json --lang go
+go run main.go export -m coeff.json --lang python -o model.py
+go run main.go export -m coeff.json --lang c --name predict_var4 -o model.c
+go run main.go export -m coeff.json --lang sql --table dataset
+go run main.go export -m coeff.json --lang pmml
+go run main.go export -m coeff.json --lang onnx -o model.onnx
+rm model.py model.c model.onnx
 
 echo "Test poly command"
 go run main.go poly -d $DATAFILE -y Var4 -o 3 -p Var
-rm "${FOLDER}/dataset_poly.csv"
+rm "${FOLDER}/dataset_poly.csv" "${FOLDER}/dataset_poly_pipeline.json"
 
 echo "Test plot command"
 go run main.go plot -d $DATAFILE -m coeff.json -o plot.png
+go run main.go plot -d $DATAFILE -m coeff.json --kind residuals -o plot.png
+go run main.go plot -d $DATAFILE -m coeff.json --kind histogram -o plot.svg
+go run main.go plot -d $DATAFILE -m coeff.json --kind qq -o plot.pdf
+go run main.go plot -d $DATAFILE -m coeff.json --kind leverage -o plot.png
+go run main.go fit -d $DATAFILE -y Var4 -g 5 -o history_model.json --history history.csv
+go run main.go plot -d history.csv --kind convergence -o plot.png
+rm history_model.json history.csv
+rm plot.svg plot.pdf
 rm coeff.json
 rm plot.png
 
@@ -26,10 +92,23 @@ rm "${FOLDER}/dataset_train.csv"
 rm "${FOLDER}/dataset_test.csv"
 
 echo "Testing ELM command"
-go run main.go elm -d $DATAFILE -y Var4 -r 20 -s 10
-rm "${FOLDER}/dataset_elm.csv"
+go run main.go elm -d $DATAFILE -y Var4 -r 20 -s 10 --tanh 5 --gaussian 5 --sine 5 --softplus 5 --leakyrelu 5 --hardlimit 5 --save-layer layer.json
+go run main.go elm -d $DATAFILE --layer layer.json
+go run main.go elm -d $DATAFILE -y Var4 -r 10 -s 10 --scale minmax --init data --train -o elm_model.json
+go run main.go elm -d $DATAFILE -y Var4 -r 10 -s 10 --train --method ga -g 5 -o elm_model.json
+go run main.go elm -d $DATAFILE -y Var4 -r 10 -s 0 --autoencoder 8,4 --train -o elm_model.json
+go run main.go elm -d $DATAFILE -y Var4 --kernel rbf --train -o elm_model.json
+go run main.go elm -d $DATAFILE -y Var4 --kernel poly --degree 2 --train --method ga -g 5 -o elm_model.json
+go run main.go pred -d $DATAFILE -m elm_model.json
+rm "${FOLDER}/dataset_elm.csv" "${FOLDER}/dataset_elm_pipeline.json" layer.json elm_model.json "${FOLDER}/dataset_predictions.csv"
 
 echo "Test template script"
 go run main.go hook -t cost -p python -o myhook.py
 go run main.go fit -d $DATAFILE -y Var4 -g 5 -o coeff.json -c ./myhook.py
-rm myhook.py
\ No newline at end of file
+rm myhook.py
+
+echo "Test features command"
+go run main.go features -d $DATAFILE -y Var4 -p Var --interactions 2 --log -e "s=sin(Var1)*Var2"
+rm "${FOLDER}/dataset_features.csv" "${FOLDER}/dataset_features_pipeline.json"
+go run main.go features -d $DATAFILE -a "${FOLDER}/dataset_features.json"
+rm "${FOLDER}/dataset_features.csv" "${FOLDER}/dataset_features.json" "${FOLDER}/dataset_features_pipeline.json"
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/davidkleiven/gogafit/gafit"
+	"github.com/spf13/cobra"
+)
+
+// bootstrapSettings holds the bootstrap flags shared by the summary and pred commands
+type bootstrapSettings struct {
+	numResamples int
+	method       string
+	reselect     bool
+	numGen       uint
+	popSize      uint
+	fdratio      float64
+	mutRate      float64
+	numSplits    uint
+	workers      int
+	seed         int64
+}
+
+// addBootstrapFlags adds the bootstrap flags to a command
+func addBootstrapFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("bootstrap", 0, "Number of bootstrap resamples (0 disables the bootstrap)")
+	cmd.Flags().String("bootstrap-method", gafit.CaseResampling, "Bootstrap resampling method (case or residual)")
+	cmd.Flags().Bool("reselect", false, "Rerun the feature selection on each bootstrap resample")
+	cmd.Flags().Uint("numgen", 50, "Number of generations of each GA run when reselecting features")
+	cmd.Flags().Uint("popsize", 30, "Population size when reselecting features")
+	cmd.Flags().Float64("fdratio", 0.8, "Maximum ratio between number of selected features and number of data points when reselecting features")
+	cmd.Flags().Float64("mutrate", 0.5, "Mutation rate of the GA when reselecting features")
+	cmd.Flags().Uint("csplits", 2, "Number of splits used for cross over operations when reselecting features")
+	cmd.Flags().Int("workers", 0, "Number of resamples fitted in parallel (default the number of CPUs)")
+	cmd.Flags().Int64("seed", 0, "Seed of the bootstrap resampling (default based on the current time)")
+}
+
+// readBootstrapSettings reads the flags added by addBootstrapFlags
+func readBootstrapSettings(cmd *cobra.Command) (bootstrapSettings, error) {
+	var s bootstrapSettings
+	var err error
+	if s.numResamples, err = cmd.Flags().GetInt("bootstrap"); err != nil {
+		return s, err
+	}
+	if s.method, err = cmd.Flags().GetString("bootstrap-method"); err != nil {
+		return s, err
+	}
+	if s.reselect, err = cmd.Flags().GetBool("reselect"); err != nil {
+		return s, err
+	}
+	if s.numGen, err = cmd.Flags().GetUint("numgen"); err != nil {
+		return s, err
+	}
+	if s.popSize, err = cmd.Flags().GetUint("popsize"); err != nil {
+		return s, err
+	}
+	if s.fdratio, err = cmd.Flags().GetFloat64("fdratio"); err != nil {
+		return s, err
+	}
+	if s.mutRate, err = cmd.Flags().GetFloat64("mutrate"); err != nil {
+		return s, err
+	}
+	if s.numSplits, err = cmd.Flags().GetUint("csplits"); err != nil {
+		return s, err
+	}
+	if s.workers, err = cmd.Flags().GetInt("workers"); err != nil {
+		return s, err
+	}
+	if s.seed, err = cmd.Flags().GetInt64("seed"); err != nil {
+		return s, err
+	}
+
+	if s.numResamples < 0 {
+		return s, fmt.Errorf("The number of bootstrap resamples can not be negative. Got %d", s.numResamples)
+	}
+
+	if s.seed == 0 {
+		s.seed = time.Now().UnixNano()
+	}
+	return s, nil
+}
+
+// run bootstraps the model on its training data
+func (s bootstrapSettings) run(model gafit.Model, data gafit.Dataset) (gafit.Bootstrap, error) {
+	conf := gafit.BootstrapConfig{
+		Method:       s.method,
+		NumResamples: s.numResamples,
+		NumWorkers:   s.workers,
+		Seed:         s.seed,
+	}
+
+	features := "fixed features"
+	if s.reselect {
+		conf.Reselect = s.selector(model)
+		features = "reselection of the features"
+	}
+	log.Printf("Fitting %d %s bootstrap resamples of %s with %s\n", s.numResamples, s.method, model.TargetName, features)
+	return gafit.NewBootstrap(model, data, conf)
+}
+
+// selector returns a function that runs the feature selection of the fit command on a
+// resample, with the cost function and standardization of the model. The GA uses the random
+// number generator of the resample, such that the result is reproducible
+func (s bootstrapSettings) selector(model gafit.Model) gafit.ModelSelector {
+	return func(data gafit.Dataset, rng *rand.Rand) (gafit.Model, error) {
+		var std *gafit.Standardization
+		fitData := data
+		if model.Standardization != nil {
+			st := gafit.NewStandardization(data, model.Standardization.TargetScale != 1.0)
+			std = &st
+			fitData = st.Apply(data)
+		}
+
+		factory := newFactory(fitData, gaSettings{
+			popSize:   s.popSize,
+			numGen:    s.numGen,
+			mutRate:   s.mutRate,
+			numSplits: s.numSplits,
+			fdratio:   s.fdratio,
+			cost:      model.Score.Name,
+		})
+
+		if factory.Config.LargestModel() < 1 {
+			return gafit.Model{}, fmt.Errorf("%d rows are too few to select features with fdratio %g", data.NumData(), s.fdratio)
+		}
+
+		best, err := minimize(factory, s.popSize, s.numGen, nil, rng)
+		if err != nil {
+			return gafit.Model{}, err
+		}
+
+		res := gafit.NewModel(best, fitData, model.Score.Name, model.Datafile)
+		if std != nil {
+			res = std.Unscale(res)
+		}
+		res.Categories = model.Categories
+		res.Pipeline = model.Pipeline
+		return res, nil
+	}
+}
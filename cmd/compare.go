@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/davidkleiven/gogafit/gafit"
+	"github.com/spf13/cobra"
+)
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare the features and performance of several models",
+	Long: `Compares several models of the same target, for instance models fitted with different cost
+functions or random seeds. The comparison consists of three tables
+
+1. The union of the selected features, with the coefficient of each feature in each model (- if
+   the model did not select it) and the number of models that selected it
+2. The number of features, the cost function and the in-sample score of each model. If a test
+   dataset is given with -d, the number of rows, RMSE, MAE, max error, R^2, adjusted R^2 and
+   MAPE on the test data are added
+3. The Jaccard similarity between the feature sets of all pairs of models (the number of
+   features selected by both models divided by the number of features selected by any of them)
+
+Example:
+
+gogafit compare -m aicc.json,bic.json,seed2.json -d test.csv
+
+With --format json, the features, coefficients, score and test metrics of each model are
+written together with the Jaccard similarity matrix.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		modelFiles, err := cmd.Flags().GetString("models")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		dataFile, err := cmd.Flags().GetString("data")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		out, err := cmd.Flags().GetString("out")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if format != "text" && format != "json" {
+			log.Fatalf("Unknown format %s. Must be text or json\n", format)
+			return
+		}
+
+		names := strings.Split(modelFiles, ",")
+		models := make([]gafit.Model, len(names))
+		for i := range names {
+			names[i] = strings.TrimSpace(names[i])
+			multi, err := gafit.ReadMultiTargetModel(names[i])
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+
+			if len(multi.Models) > 1 {
+				log.Fatalf("%s holds a multi-target model. Only single target models can be compared\n", names[i])
+				return
+			}
+			models[i] = multi.Models[0]
+		}
+
+		comparison, err := gafit.NewModelComparison(names, models)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if dataFile != "" {
+			for _, model := range models {
+				data, err := gafit.ReadForModel(dataFile, model.TargetName, model)
+				if err != nil {
+					log.Fatalf("%s\n", err)
+					return
+				}
+				comparison.Metrics = append(comparison.Metrics, gafit.NewRegressionMetrics(model, data))
+			}
+		}
+
+		var w io.Writer = os.Stdout
+		if out != "" {
+			f, err := os.Create(out)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if format == "json" {
+			err = comparison.WriteJSON(w)
+		} else {
+			err = comparison.WriteTable(w)
+		}
+
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if out != "" {
+			log.Printf("Comparison written to %s\n", out)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringP("models", "m", "", "Comma separated list of JSON files with models")
+	compareCmd.Flags().StringP("data", "d", "", "Csv file with test data (optional)")
+	compareCmd.Flags().String("format", "text", "Output format (text or json)")
+	compareCmd.Flags().StringP("out", "o", "", "File where the comparison is written (default stdout)")
+}
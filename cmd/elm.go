@@ -9,6 +9,7 @@ import (
 	"github.com/davidkleiven/gogafit/elm"
 	"github.com/davidkleiven/gogafit/gafit"
 	"github.com/spf13/cobra"
+	"gonum.org/v1/gonum/mat"
 )
 
 // elmCmd represents the elm command
@@ -20,7 +21,21 @@ The hidden layer consists of the given number of activation functions.
 
 gogafit elm -d mydata.csv -r 100 -s 200 -t feat3 
 
-creates an ELM with 100 relu neurons and 200 sigmoid neurons. Similar to the other commands,
+creates an ELM with 100 relu neurons and 200 sigmoid neurons. Neurons with other activation
+functions are added via --tanh, --gaussian, --sine, --softplus, --leakyrelu and --hardlimit.
+Each neuron calculates activation(w.x + b). By default the inputs enter the neurons unscaled.
+With --scale standard they are scaled to zero mean and unit variance, and with --scale minmax to
+the range [-1, 1]. The weights w and the bias b are drawn from a standard normal distribution.
+Other initialization schemes are selected via --init
+
+uniform: weights and bias drawn uniformly from [-1, 1]
+xavier:  weights drawn uniformly from [-a, a] with a = sqrt(6/(n+1)), n being the number of inputs
+he:      weights drawn from a normal distribution with standard deviation sqrt(2/n)
+data:    the bias places the transition of each neuron at a randomly sampled input row, and the
+         weights are scaled such that w.x + b has unit standard deviation over the data
+
+The scaling and the initialization scheme are stored together with the hidden layer.
+Similar to the other commands,
 the format of the data file is
 
 feat1, feat2, feat3
@@ -28,6 +43,39 @@ feat1, feat2, feat3
 ...
 
 where the name of the column corresponding to the target feature is specified via the -y flag.
+The weights of the neurons are stored in mydata_elm_pipeline.json, such that models fitted to
+mydata_elm.csv can make predictions directly from data with the original columns.
+
+The hidden layer can be stored in a separate file via --save-layer and applied to new data
+(which must contain the same input columns) via --layer
+
+gogafit elm -d mydata.csv -r 100 -s 200 -y feat3 --save-layer layer.json
+gogafit elm -d newdata.csv --layer layer.json
+
+With --train the output weights of the network are fitted as well, and the resulting model is
+stored in the file given by -o. The weights are either found by regularized least squares over
+all neurons (--method ridge) or by selecting a subset of the neurons with the genetic algorithm
+(--method ga). The genetic algorithm is controlled by -g, -p, -c, --mutrate, --csplits and
+--fdratio as in the fit command. The model can be used directly with the pred, rmse and plot
+commands. Only models trained with --method ga hold the covariance of the output weights, since
+the weights are then fitted by ordinary least squares. The prediction errors of ridge models are
+not available
+
+gogafit elm -d mydata.csv -r 100 -s 200 -y feat3 --train -o elm_model.json
+gogafit pred -d newdata.csv -m elm_model.json
+
+Multi-layer networks are created by stacking ELM autoencoder layers in front of the hidden layer.
+The output weights of each autoencoder layer are fitted such that the layer reconstructs its
+input, and are then used as input weights for the next layer
+
+gogafit elm -d mydata.csv -r 100 -s 0 -y feat3 --autoencoder 50,20 --train -o elm_model.json
+
+creates two autoencoder layers with 50 and 20 neurons followed by a hidden layer with 100 relu
+neurons. A kernel ELM is created via --kernel. Instead of random neurons, the hidden layer then
+holds the kernel between the input and each of the training rows, exp(-gamma*|x - c|^2) for rbf
+and (gamma*x.c + coef0)^degree for poly
+
+gogafit elm -d mydata.csv -y feat3 --kernel rbf --gamma 0.5 --train -o kelm_model.json
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		dataFile, err := cmd.Flags().GetString("data")
@@ -41,20 +89,34 @@ where the name of the column corresponding to the target feature is specified vi
 			log.Fatalf("%s\n", err)
 			return
 		}
-		target, err = ClosestHeaderName(dataFile, target)
+		if target != "" {
+			target, err = ClosestHeaderName(dataFile, target)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			log.Printf("Using %s as target column\n", target)
+		}
+
+		numRelu, err := cmd.Flags().GetUint("relu")
 		if err != nil {
 			log.Fatalf("%s\n", err)
 			return
 		}
-		log.Printf("Using %s as target column\n", target)
 
-		numRelu, err := cmd.Flags().GetUint("relu")
+		numSig, err := cmd.Flags().GetUint("sig")
 		if err != nil {
 			log.Fatalf("%s\n", err)
 			return
 		}
 
-		numSig, err := cmd.Flags().GetUint("sig")
+		layerFile, err := cmd.Flags().GetString("layer")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		saveLayer, err := cmd.Flags().GetString("save-layer")
 		if err != nil {
 			log.Fatalf("%s\n", err)
 			return
@@ -65,33 +127,389 @@ where the name of the column corresponding to the target feature is specified vi
 			log.Fatalf("%s\n", err)
 			return
 		}
-		nWeights := dataset.NumFeatures()
+		pipeline, err := inputPipeline(dataFile, dataset)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
 
-		neurons := []elm.Neuron{}
-		names := []string{}
+		scaling, err := cmd.Flags().GetString("scale")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
 
-		src := rand.NewSource(time.Now().UnixNano())
-		rng := rand.New(src)
+		scheme, err := cmd.Flags().GetString("init")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
 
-		// Add relu neurons
-		for i := 0; i < int(numRelu); i++ {
-			neurons = append(neurons, elm.RandomReluNeuronFactory(nWeights, rng))
-			names = append(names, fmt.Sprintf("relu%d", i))
+		kernelType, err := cmd.Flags().GetString("kernel")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
 		}
 
-		// Add sigmoid neurons
-		for i := 0; i < int(numSig); i++ {
-			neurons = append(neurons, elm.RandomSigmoidNeuronFactory(nWeights, rng))
-			names = append(names, fmt.Sprintf("sigmoid%d", i))
+		aeSizes, err := cmd.Flags().GetUintSlice("autoencoder")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if kernelType != "" && (layerFile != "" || saveLayer != "") {
+			log.Fatalf("--layer and --save-layer can not be combined with --kernel\n")
+			return
+		}
+
+		if layerFile != "" && len(aeSizes) > 0 {
+			log.Fatalf("--layer can not be combined with --autoencoder\n")
+			return
+		}
+
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+		// The autoencoder layers are applied to the data before the final hidden layer
+		input := dataset
+		for i, size := range aeSizes {
+			step, err := autoencoderStep(cmd, input, i, size, scaling, scheme, rng)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+
+			input, err = step.Apply(input)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			pipeline = pipeline.Append(step)
+			log.Printf("Autoencoder layer %d with %d neurons trained\n", i+1, size)
+		}
+
+		var step gafit.PipelineStep
+		if kernelType != "" {
+			kernel, err := kernelFromFlags(cmd, kernelType, input, scaling)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			step.Kernel = &gafit.KernelStep{Kernel: kernel}
+			log.Printf("Using %s kernel with %d centers\n", kernelType, len(kernel.Centers))
+		} else {
+			var layer elm.Layer
+			if layerFile != "" {
+				layer, err = elm.ReadLayer(layerFile)
+				if err != nil {
+					log.Fatalf("%s\n", err)
+					return
+				}
+				log.Printf("Using hidden layer with %d neurons from %s\n", len(layer.Neurons), layerFile)
+			} else {
+				counts := []neuronCount{{Activation: "relu", Num: numRelu}, {Activation: "sigmoid", Num: numSig}}
+				for _, name := range extraActivations {
+					num, err := cmd.Flags().GetUint(name)
+					if err != nil {
+						log.Fatalf("%s\n", err)
+						return
+					}
+					counts = append(counts, neuronCount{Activation: name, Num: num})
+				}
+
+				layer, err = randomLayer(input.ColNames, counts, input.X, scaling, scheme, rng)
+				if err != nil {
+					log.Fatalf("%s\n", err)
+					return
+				}
+			}
+			step.ELM = &gafit.ELMStep{Layer: layer}
+		}
+
+		hidden, err := step.Apply(input)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
 		}
 
-		G := elm.HiddenLayerMatrix(dataset.X, neurons)
 		outfile := dataFile[:len(dataFile)-4] + "_elm.csv"
-		gafit.Write(outfile, G, dataset.Y, names, dataset.TargetName)
+		if target == "" {
+			err = gafit.Write(outfile, hidden.X, nil, hidden.ColNames, "")
+		} else {
+			err = gafit.Write(outfile, hidden.X, hidden.Y, hidden.ColNames, hidden.TargetName)
+		}
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
 		log.Printf("Data for ELM written to %s\n", outfile)
+
+		if saveLayer != "" {
+			if err = elm.SaveLayer(saveLayer, step.ELM.Layer); err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			log.Printf("Hidden layer written to %s\n", saveLayer)
+		}
+
+		if err = saveOutputPipeline(outfile, pipeline, step); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		train, err := cmd.Flags().GetBool("train")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if !train {
+			return
+		}
+
+		if target == "" {
+			log.Fatalf("A target column (-y) is required for training\n")
+			return
+		}
+
+		model, err := trainELM(cmd, step, pipeline, input, hidden, dataFile, rng)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		out, err := cmd.Flags().GetString("out")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if err = gafit.SaveModel(out, model); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		log.Printf("ELM model with %d neurons written to %s (%s: %f)\n", len(model.Coeffs), out, model.Score.Name, model.Score.Value)
 	},
 }
 
+// trainELM solves for the output weights of the network, either by ridge regression over all
+// neurons (or kernel centers) or by selecting a subset of them with the genetic algorithm.
+// step is the hidden layer or kernel, and input holds the data passed to it. rng is used by
+// the genetic algorithm
+func trainELM(cmd *cobra.Command, step gafit.PipelineStep, pipeline gafit.Pipeline, input gafit.Dataset, hidden gafit.Dataset, dataFile string, rng *rand.Rand) (gafit.Model, error) {
+	method, err := cmd.Flags().GetString("method")
+	if err != nil {
+		return gafit.Model{}, err
+	}
+
+	switch method {
+	case "ridge":
+		lambda, err := cmd.Flags().GetFloat64("lambda")
+		if err != nil {
+			return gafit.Model{}, err
+		}
+
+		if step.Kernel != nil {
+			network := elm.KernelModel{Kernel: step.Kernel.Kernel, Regularization: lambda}
+			if err := network.Train(input.Submatrix(network.Kernel.Inputs), input.Y); err != nil {
+				return gafit.Model{}, err
+			}
+			return gafit.NewKernelELMModel(network, pipeline, input, dataFile), nil
+		}
+
+		network := elm.Model{Layer: step.ELM.Layer, Regularization: lambda}
+		if err := network.Train(input.Submatrix(network.Layer.Inputs), input.Y); err != nil {
+			return gafit.Model{}, err
+		}
+		return gafit.NewELMModel(network, pipeline, input, dataFile), nil
+	case "ga":
+		selected, err := selectHiddenFeatures(cmd, hidden, dataFile, rng)
+		if err != nil {
+			return gafit.Model{}, err
+		}
+
+		// Keep only the selected neurons (or kernel centers) in the network
+		var model gafit.Model
+		if step.Kernel != nil {
+			network := elm.KernelModel{}
+			network.Kernel, err = step.Kernel.Subset(selected.Features())
+			if err != nil {
+				return gafit.Model{}, err
+			}
+			for _, name := range network.Kernel.Names {
+				network.Weights = append(network.Weights, selected.Coeffs[name])
+			}
+			model = gafit.NewKernelELMModel(network, pipeline, input, dataFile)
+		} else {
+			network := elm.Model{}
+			network.Layer, err = step.ELM.Subset(selected.Features())
+			if err != nil {
+				return gafit.Model{}, err
+			}
+			for _, name := range network.Layer.Names {
+				network.OutputWeights = append(network.OutputWeights, selected.Coeffs[name])
+			}
+			model = gafit.NewELMModel(network, pipeline, input, dataFile)
+		}
+		model.Score = selected.Score
+
+		// The output weights are fitted by ordinary least squares, such that the
+		// covariance of the selected features is valid
+		return model.WithInference(hidden), nil
+	default:
+		return gafit.Model{}, fmt.Errorf("Unknown training method %s", method)
+	}
+}
+
+// selectHiddenFeatures runs the genetic algorithm on the output of the hidden layer
+func selectHiddenFeatures(cmd *cobra.Command, hidden gafit.Dataset, dataFile string, rng *rand.Rand) (gafit.Model, error) {
+	numGen, err := cmd.Flags().GetUint("numgen")
+	if err != nil {
+		return gafit.Model{}, err
+	}
+
+	popSize, err := cmd.Flags().GetUint("popsize")
+	if err != nil {
+		return gafit.Model{}, err
+	}
+
+	cost, err := cmd.Flags().GetString("cost")
+	if err != nil {
+		return gafit.Model{}, err
+	}
+
+	mutRate, err := cmd.Flags().GetFloat64("mutrate")
+	if err != nil {
+		return gafit.Model{}, err
+	}
+
+	numSplits, err := cmd.Flags().GetUint("csplits")
+	if err != nil {
+		return gafit.Model{}, err
+	}
+
+	fdratio, err := cmd.Flags().GetFloat64("fdratio")
+	if err != nil {
+		return gafit.Model{}, err
+	}
+
+	settings := gaSettings{
+		popSize:   popSize,
+		numGen:    numGen,
+		mutRate:   mutRate,
+		numSplits: numSplits,
+		fdratio:   fdratio,
+		cost:      cost,
+	}
+
+	best, err := minimize(newFactory(hidden, settings), popSize, numGen, nil, rng)
+	if err != nil {
+		return gafit.Model{}, err
+	}
+	return gafit.NewModel(best, hidden, cost, dataFile), nil
+}
+
+// autoencoderStep creates the pipeline step for autoencoder layer number idx (starting at
+// zero) with the given number of neurons. The neurons are named ae<idx+1>_0, ae<idx+1>_1, ...
+func autoencoderStep(cmd *cobra.Command, input gafit.Dataset, idx int, size uint, scaling string, scheme string, rng *rand.Rand) (gafit.PipelineStep, error) {
+	activation, err := cmd.Flags().GetString("ae-activation")
+	if err != nil {
+		return gafit.PipelineStep{}, err
+	}
+
+	lambda, err := cmd.Flags().GetFloat64("lambda")
+	if err != nil {
+		return gafit.PipelineStep{}, err
+	}
+
+	counts := []neuronCount{{Activation: activation, Num: size}}
+	random, err := randomLayer(input.ColNames, counts, input.X, scaling, scheme, rng)
+	if err != nil {
+		return gafit.PipelineStep{}, err
+	}
+
+	ae, err := elm.TrainAutoencoder(input.X, random, lambda)
+	if err != nil {
+		return gafit.PipelineStep{}, err
+	}
+	for i := range ae.Names {
+		ae.Names[i] = fmt.Sprintf("ae%d_%d", idx+1, i)
+	}
+	return gafit.PipelineStep{ELM: &gafit.ELMStep{Layer: ae}}, nil
+}
+
+// kernelFromFlags creates a kernel where the rows in input are used as centers
+func kernelFromFlags(cmd *cobra.Command, kernelType string, input gafit.Dataset, scaling string) (elm.Kernel, error) {
+	gamma, err := cmd.Flags().GetFloat64("gamma")
+	if err != nil {
+		return elm.Kernel{}, err
+	}
+
+	degree, err := cmd.Flags().GetUint("degree")
+	if err != nil {
+		return elm.Kernel{}, err
+	}
+
+	coef0, err := cmd.Flags().GetFloat64("coef0")
+	if err != nil {
+		return elm.Kernel{}, err
+	}
+
+	var inputScaling *elm.InputScaling
+	if scaling != "none" {
+		s, err := elm.NewInputScaling(input.X, scaling)
+		if err != nil {
+			return elm.Kernel{}, err
+		}
+		inputScaling = &s
+	}
+	return elm.NewKernel(kernelType, input.ColNames, input.X, inputScaling, gamma, int(degree), coef0)
+}
+
+// extraActivations lists activation functions that can be added to the hidden layer via a
+// flag with the same name, in addition to relu and sigmoid
+var extraActivations = []string{"tanh", "gaussian", "sine", "softplus", "leakyrelu", "hardlimit"}
+
+// neuronCount gives the number of neurons with a given activation function
+type neuronCount struct {
+	Activation string
+	Num        uint
+}
+
+// randomLayer creates a hidden layer with randomly initialized neurons. The neurons are named
+// after their activation function followed by a counter (e.g. relu0, relu1, tanh0). If scaling
+// is different from none, the input scaling is calculated from X and stored in the layer. The
+// weights are initialized with the given scheme (see elm.InitSchemes)
+func randomLayer(inputs []string, counts []neuronCount, X *mat.Dense, scaling string, scheme string, rng *rand.Rand) (elm.Layer, error) {
+	layer := elm.Layer{Inputs: inputs, Init: scheme}
+	nWeights := len(inputs)
+
+	if scaling != "none" {
+		s, err := elm.NewInputScaling(X, scaling)
+		if err != nil {
+			return layer, err
+		}
+		layer.Scaling = &s
+		X = s.Apply(X)
+	}
+
+	for _, c := range counts {
+		activation, err := elm.GetActivation(c.Activation)
+		if err != nil {
+			return layer, err
+		}
+
+		for i := 0; i < int(c.Num); i++ {
+			neuron, err := elm.InitNeuron(scheme, nWeights, X, rng, activation)
+			if err != nil {
+				return layer, err
+			}
+			layer.Neurons = append(layer.Neurons, neuron)
+			layer.Names = append(layer.Names, fmt.Sprintf("%s%d", c.Activation, i))
+		}
+	}
+	return layer, nil
+}
+
 func init() {
 	rootCmd.AddCommand(elmCmd)
 
@@ -99,4 +517,30 @@ func init() {
 	elmCmd.Flags().StringP("target", "y", "", "Name of columns that represent the target values")
 	elmCmd.Flags().UintP("relu", "r", 1, "Number of rectifier activation functions in the hidden layer")
 	elmCmd.Flags().UintP("sig", "s", 1, "Number of sigmoid activation functions in the hidden layer")
+	elmCmd.Flags().Uint("tanh", 0, "Number of hyperbolic tangent activation functions in the hidden layer")
+	elmCmd.Flags().Uint("gaussian", 0, "Number of gaussian (exp(-x^2)) activation functions in the hidden layer")
+	elmCmd.Flags().Uint("sine", 0, "Number of sine activation functions in the hidden layer")
+	elmCmd.Flags().Uint("softplus", 0, "Number of softplus (log(1 + exp(x))) activation functions in the hidden layer")
+	elmCmd.Flags().Uint("leakyrelu", 0, "Number of leaky rectifier activation functions in the hidden layer")
+	elmCmd.Flags().Uint("hardlimit", 0, "Number of hard-limit (step) activation functions in the hidden layer")
+	elmCmd.Flags().String("scale", "none", "Scaling of the inputs before they enter the neurons (none, standard or minmax)")
+	elmCmd.Flags().String("init", "normal", "Initialization of the weights (normal, uniform, xavier, he or data)")
+	elmCmd.Flags().UintSlice("autoencoder", []uint{}, "Number of neurons in each stacked ELM autoencoder layer applied before the hidden layer (e.g. 50,20)")
+	elmCmd.Flags().String("ae-activation", "sigmoid", "Activation function used in the autoencoder layers")
+	elmCmd.Flags().String("kernel", "", "Use a kernel ELM with the given kernel (rbf or poly) instead of a random hidden layer")
+	elmCmd.Flags().Float64("gamma", 0.0, "Kernel parameter gamma (0 means 1/number of inputs)")
+	elmCmd.Flags().Uint("degree", 3, "Degree of the polynomial kernel")
+	elmCmd.Flags().Float64("coef0", 1.0, "Constant term in the polynomial kernel")
+	elmCmd.Flags().String("save-layer", "", "JSON file where the hidden layer (weights and activations) is stored")
+	elmCmd.Flags().Bool("train", false, "Train the output weights of the network and store the model")
+	elmCmd.Flags().String("method", "ridge", "Training method: regularized least squares (ridge) or GA selection of neurons (ga)")
+	elmCmd.Flags().Float64("lambda", 1e-6, "Regularization strength used by the ridge method, the kernel ELM and the autoencoder layers")
+	elmCmd.Flags().UintP("numgen", "g", 50, "Number of generations used by the ga method")
+	elmCmd.Flags().UintP("popsize", "p", 30, "Population size used by the ga method")
+	elmCmd.Flags().StringP("cost", "c", "aicc", "Cost function used by the ga method (aic|aicc|bic|ebic)")
+	elmCmd.Flags().Float64("mutrate", 0.5, "Mutation rate used by the ga method")
+	elmCmd.Flags().Uint("csplits", 2, "Number of splits used for cross over operations by the ga method")
+	elmCmd.Flags().Float64("fdratio", 0.8, "Maximum ratio between number of selected neurons and number of data points used by the ga method")
+	elmCmd.Flags().StringP("out", "o", "elm_model.json", "File where the trained model is stored")
+	elmCmd.Flags().String("layer", "", "JSON file with an existing hidden layer that is applied instead of creating a new one")
 }
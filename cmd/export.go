@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/davidkleiven/gogafit/gafit"
+	"github.com/spf13/cobra"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a fitted model as standalone code, PMML or ONNX",
+	Long: `Converts a fitted regression model into a self-contained prediction function in Go, Python,
+C or SQL. The generated code does not depend on gogafit and evaluates the pipeline of the model
+(polynomial and generated features, ELM hidden layers and kernels) followed by the coefficients
+and the intercept. Only the parts of the pipeline used by the model are included.
+
+The function takes the raw input columns (before the pipeline is applied) in alphabetical order.
+The input columns are listed in the comment of the generated function, and column names that
+are not valid identifiers are converted (e.g. "my col" is passed as my_col). Categorical columns
+are passed as their indicator columns (e.g. material_steel). For SQL, a query that adds the
+column prediction to all rows of a table (--table) is generated.
+
+Examples:
+
+gogafit export -m model.json --lang go --package mymodel -o model.go
+gogafit export -m model.json --lang python -o model.py
+gogafit export -m model.json --lang c --name predict_energy -o model.c
+gogafit export -m model.json --lang sql --table measurements -o model.sql
+
+For integration with model serving platforms, linear models can in addition be exported as a
+PMML 4.4 RegressionModel (--lang pmml) or an ONNX graph with a LinearRegressor node (--lang onnx).
+In these formats, the inputs are the features of the model (the pipeline is not included). If
+the model was fitted to standardized data, the standardization is included as derived fields
+(PMML) or a Scaler node (ONNX), and the coefficients refer to the standardized features. The ONNX
+graph has one input of shape [N, 1] for each feature and the output prediction. Since ONNX files
+are binary, the output file must be given with -o.
+
+gogafit export -m model.json --lang pmml -o model.pmml
+gogafit export -m model.json --lang onnx -o model.onnx
+
+For multi-target models (see gogafit fit -h), the target to export is selected with -y.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		modelFile, err := cmd.Flags().GetString("model")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		lang, err := cmd.Flags().GetString("lang")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		out, err := cmd.Flags().GetString("out")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		target, err := cmd.Flags().GetString("target")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		name, err := cmd.Flags().GetString("name")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		pkg, err := cmd.Flags().GetString("package")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		table, err := cmd.Flags().GetString("table")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		multi, err := gafit.ReadMultiTargetModel(modelFile)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		model := multi.Models[0]
+		if target != "" {
+			model, err = multi.Model(target)
+		} else if len(multi.Models) > 1 {
+			err = fmt.Errorf("%s holds a multi-target model. Select the target with -y (one of %s)", modelFile, strings.Join(multi.Targets, ", "))
+		}
+
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if lang == "pmml" || lang == "onnx" {
+			exportLinearModel(model, lang, out)
+			return
+		}
+
+		exported, err := gafit.ExportModel(model, gafit.ExportOptions{
+			Lang:     lang,
+			FuncName: name,
+			Package:  pkg,
+			Table:    table,
+		})
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if out == "" {
+			fmt.Print(exported.Code)
+			return
+		}
+
+		if err := ioutil.WriteFile(out, []byte(exported.Code), 0644); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		log.Printf("Model exported to %s. Inputs: %s\n", out, strings.Join(exported.Inputs, ", "))
+	},
+}
+
+// exportLinearModel writes the model in PMML or ONNX format
+func exportLinearModel(model gafit.Model, format string, out string) {
+	if format == "onnx" && out == "" {
+		log.Fatalf("The output file must be given with -o for ONNX exports\n")
+		return
+	}
+
+	if model.Pipeline != nil {
+		log.Printf("The pipeline of the model is not part of %s exports. The inputs are the features of the model\n", strings.ToUpper(format))
+	}
+
+	var buf bytes.Buffer
+	write := gafit.WritePMML
+	if format == "onnx" {
+		write = gafit.WriteONNX
+	}
+
+	if err := write(&buf, model); err != nil {
+		log.Fatalf("%s\n", err)
+		return
+	}
+
+	if out == "" {
+		fmt.Print(buf.String())
+		return
+	}
+
+	if err := ioutil.WriteFile(out, buf.Bytes(), 0644); err != nil {
+		log.Fatalf("%s\n", err)
+		return
+	}
+	log.Printf("Model exported to %s. Inputs: %s\n", out, strings.Join(model.Features(), ", "))
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringP("model", "m", "model.json", "JSON file with the model")
+	exportCmd.Flags().String("lang", "go", "Language of the generated code ("+strings.Join(gafit.ExportLanguages, ", ")+") or model format (pmml, onnx)")
+	exportCmd.Flags().StringP("out", "o", "", "File where the code is written (default stdout)")
+	exportCmd.Flags().StringP("target", "y", "", "Target to export from a multi-target model")
+	exportCmd.Flags().String("name", "", "Name of the generated function (default Predict for Go and predict otherwise)")
+	exportCmd.Flags().String("package", "model", "Package of the generated Go code")
+	exportCmd.Flags().String("table", "data", "Table queried by the generated SQL")
+}
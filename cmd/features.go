@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/davidkleiven/gogafit/gafit"
+	"github.com/spf13/cobra"
+)
+
+// featuresCmd represents the features command
+var featuresCmd = &cobra.Command{
+	Use:   "features",
+	Short: "Generate interaction and basis-function features",
+	Long: `Adds columns that are generated from the columns whose name contains the given
+pattern. Supported features are interactions (products of 2 up to the given order of distinct
+columns), logarithms, inverses, square roots and exponentials. In addition, arbitrary expressions
+can be passed via -e. Expressions support + - * / ^, parentheses and the functions
+sin, cos, tan, tanh, exp, log, log10, sqrt and abs. Column names that are not valid identifiers
+can be quoted with backticks.
+
+Example:
+We have the following csv file named data.csv
+
+x1,x2,y
+1.0,2.0,3.0
+2.0,1.0,2.0
+
+Run
+
+gogafit features -d data.csv -y y --interactions 2 --log -e "s=sin(x1)*x2"
+
+this will create a file data_features.csv with the columns
+
+x1,x2,x1*x2,log(x1),log(x2),s,y
+
+and a manifest data_features.json listing the generated features. The same expansion
+can be replayed on new data (e.g. data used for prediction) by
+
+gogafit features -d newdata.csv --apply data_features.json
+
+The expansion is also recorded in data_features_pipeline.json. Models fitted to data_features.csv
+store this pipeline, such that the pred command can be used directly on data with the original columns.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dataFile, err := cmd.Flags().GetString("data")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		target, err := cmd.Flags().GetString("target")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if target != "" {
+			target, err = ClosestHeaderName(dataFile, target)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			log.Printf("Using %s as target value\n", target)
+		}
+
+		manifestIn, err := cmd.Flags().GetString("apply")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		manifestOut, err := cmd.Flags().GetString("manifest")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		var manifest gafit.FeatureManifest
+		var data gafit.Dataset
+		var pipeline gafit.Pipeline
+		if manifestIn != "" {
+			manifest, err = gafit.ReadFeatureManifest(manifestIn)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+
+			data, err = gafit.ReadWithOptions(dataFile, target, gafit.ReadOptions{Encodings: manifest.Categories})
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			pipeline, err = inputPipeline(dataFile, data)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+		} else {
+			data, err = gafit.Read(dataFile, target)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+
+			manifest, err = featureManifestFromFlags(cmd, data)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+
+			pipeline, err = inputPipeline(dataFile, data)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+		}
+
+		newData, err := manifest.Apply(data)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		outfile := outfname(dataFile, "_features")
+		if target == "" {
+			err = gafit.Write(outfile, newData.X, nil, newData.ColNames, "")
+		} else {
+			err = gafit.Write(outfile, newData.X, newData.Y, newData.ColNames, newData.TargetName)
+		}
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		log.Printf("%d features added. New dataset written to %s\n", len(manifest.Features), outfile)
+
+		step := gafit.PipelineStep{Features: &gafit.FeatureManifest{Features: manifest.Features}}
+		if err = saveOutputPipeline(outfile, pipeline, step); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if manifestIn == "" {
+			if manifestOut == "" {
+				manifestOut = strings.TrimSuffix(outfile, filepath.Ext(outfile)) + ".json"
+			}
+			if err = gafit.SaveFeatureManifest(manifestOut, manifest); err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			log.Printf("Feature manifest written to %s\n", manifestOut)
+		}
+	},
+}
+
+// featureManifestFromFlags builds the list of generated features requested on the command line.
+// Unary features that are not finite for all rows (e.g. the logarithm of negative values) are skipped.
+func featureManifestFromFlags(cmd *cobra.Command, data gafit.Dataset) (gafit.FeatureManifest, error) {
+	manifest := gafit.FeatureManifest{Categories: data.Categories}
+
+	pattern, err := cmd.Flags().GetString("pattern")
+	if err != nil {
+		return manifest, err
+	}
+
+	names := []string{}
+	for _, c := range data.Columns(pattern) {
+		names = append(names, data.ColNames[c])
+	}
+	log.Printf("Generating features from %d columns\n", len(names))
+
+	order, err := cmd.Flags().GetUint("interactions")
+	if err != nil {
+		return manifest, err
+	}
+	manifest.Features = append(manifest.Features, gafit.InteractionFeatures(names, int(order))...)
+
+	for _, fn := range []string{"log", "inv", "sqrt", "exp"} {
+		active, err := cmd.Flags().GetBool(fn)
+		if err != nil {
+			return manifest, err
+		}
+
+		if !active {
+			continue
+		}
+
+		specs, err := gafit.UnaryFeatures(names, fn)
+		if err != nil {
+			return manifest, err
+		}
+
+		for _, spec := range specs {
+			if _, err := spec.Evaluate(data); err != nil {
+				log.Printf("Skipping %s: %s\n", spec.Name, err)
+				continue
+			}
+			manifest.Features = append(manifest.Features, spec)
+		}
+	}
+
+	exprs, err := cmd.Flags().GetStringArray("expr")
+	if err != nil {
+		return manifest, err
+	}
+
+	for _, e := range exprs {
+		spec, err := gafit.ParseFeatureSpec(e)
+		if err != nil {
+			return manifest, err
+		}
+		manifest.Features = append(manifest.Features, spec)
+	}
+	return manifest, nil
+}
+
+func init() {
+	rootCmd.AddCommand(featuresCmd)
+
+	featuresCmd.Flags().StringP("data", "d", "", "Original datafile")
+	featuresCmd.Flags().StringP("target", "y", "", "Name of the quantity used as target property")
+	featuresCmd.Flags().StringP("pattern", "p", "", "Features are generated from all columns containing this substring")
+	featuresCmd.Flags().Uint("interactions", 0, "Maximum order of interaction products (0 or 1 means no interactions)")
+	featuresCmd.Flags().Bool("log", false, "Add the logarithm of the selected columns")
+	featuresCmd.Flags().Bool("inv", false, "Add the inverse of the selected columns")
+	featuresCmd.Flags().Bool("sqrt", false, "Add the square root of the selected columns")
+	featuresCmd.Flags().Bool("exp", false, "Add the exponential of the selected columns")
+	featuresCmd.Flags().StringArrayP("expr", "e", []string{}, "User defined feature on the form name=expr (e.g. s=sin(x1)*x2). Can be repeated")
+	featuresCmd.Flags().StringP("manifest", "m", "", "File where the feature manifest is written (default <data>_features.json)")
+	featuresCmd.Flags().StringP("apply", "a", "", "Replay the features in an existing manifest instead of generating new ones")
+}
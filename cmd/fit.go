@@ -2,9 +2,12 @@ package cmd
 
 import (
 	"encoding/csv"
+	"fmt"
 	"log"
+	"math/rand"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/MaxHalford/eaopt"
 	"github.com/davidkleiven/gogafit/gafit"
@@ -35,6 +38,46 @@ gogafit fit -d myfile.csv -t feat3
 
 will take the columns corresponding to feat1 and feat2 as the X matrix, and use the last column
 (here named feat3) as the y vector.
+
+Columns containing non-numeric values (e.g. material names) are treated as categorical and
+expanded into one indicator column per level, named <column>_<level>. With --dummy the first
+level is used as reference level and does not get its own column. The encoding is stored in
+the model, such that the same encoding is applied by the pred command.
+
+With --standardize all features are centered and divided by their standard deviation before
+fitting, and the target is centered. Add --scale-target to also divide the target by its standard
+deviation. The means and scales are stored in the model, and the reported coefficients (and the
+resulting intercept) are converted back to the original units. Note that the score is evaluated
+on the standardized data.
+
+With --type cls a classification model is fitted instead. The target column then holds class
+labels (numbers or strings), and a logistic regression model with an intercept is fitted by
+iteratively reweighted least squares. Two classes give binary logistic regression, while more
+classes give multinomial logistic regression where the first class (in sorted order) is the
+reference class. The cost functions aic, aicc and bic are then based on the log-likelihood of
+the model. The pred command outputs the probability of each class.
+
+gogafit fit -d myfile.csv -y label --type cls -c bic
+
+Several targets can be fitted in one run by passing a comma separated list of target columns.
+All target columns are removed from the features, and one model per target is stored in a
+multi-target model file that is understood by the pred, rmse and plot commands. By default the
+features of each target are selected independently. With --shared a single set of features is
+selected for all targets, where the cost is the sum of the costs of the individual targets.
+
+gogafit fit -d myfile.csv -y prop1,prop2,prop3 --shared
+
+If the datafile was produced by poly, features or elm, the transformation pipeline stored alongside
+it (e.g. myfile_pipeline.json) is included in the model. The pred, rmse and plot commands then
+accept data with the original columns and reproduce the transformations.
+
+With --history the statistics of each generation of the genetic algorithm are written to a
+file: the best, mean and worst cost in the population, the diversity of the population (the
+mean fraction of features where two genomes differ) and the number of features selected by the
+best genome. Files with extension .json are written in JSON format, all others as CSV. The
+convergence can be visualized with gogafit plot --kind convergence.
+
+gogafit fit -d myfile.csv -y feat3 --history history.csv
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fitType, err := cmd.Flags().GetString("type")
@@ -73,12 +116,22 @@ will take the columns corresponding to feat1 and feat2 as the X matrix, and use
 			return
 		}
 
-		target, err = ClosestHeaderName(dataFile, target)
+		targets := strings.Split(target, ",")
+		for i := range targets {
+			targets[i], err = ClosestHeaderName(dataFile, strings.TrimSpace(targets[i]))
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			log.Printf("Using %s as target column\n", targets[i])
+		}
+		target = targets[0]
+
+		shared, err := cmd.Flags().GetBool("shared")
 		if err != nil {
 			log.Fatalf("%s\n", err)
 			return
 		}
-		log.Printf("Using %s as target column\n", target)
 
 		ng, err := cmd.Flags().GetUint("numgen")
 		if err != nil {
@@ -116,65 +169,362 @@ will take the columns corresponding to feat1 and feat2 as the X matrix, and use
 			return
 		}
 
-		dataset, err := gafit.Read(dataFile, target)
+		dummy, err := cmd.Flags().GetBool("dummy")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if fitType != "reg" && fitType != "cls" {
+			log.Fatalf("Unknown fit type %s. Must be reg or cls\n", fitType)
+			return
+		}
+		classify := fitType == "cls"
 
+		standardize, err := cmd.Flags().GetBool("standardize")
 		if err != nil {
 			log.Fatalf("%s\n", err)
 			return
 		}
 
-		if fitType != "reg" {
-			log.Fatalf("Currently only regression is supported\n")
+		scaleTarget, err := cmd.Flags().GetBool("scale-target")
+		if err != nil {
+			log.Fatalf("%s\n", err)
 			return
 		}
 
-		// Initialize GA
-		conf := eaopt.NewDefaultGAConfig()
-		conf.PopSize = popsize
-		ga, err := conf.NewGA()
+		historyFile, err := cmd.Flags().GetString("history")
 		if err != nil {
 			log.Fatalf("%s\n", err)
 			return
 		}
 
-		// Set the number of generations to run for
-		ga.NGenerations = ng
+		var history *gafit.GAHistory
+		if historyFile != "" {
+			history = &gafit.GAHistory{Cost: cost}
+		}
 
-		callback := gafit.GABackupCB{
-			Cost:       cost,
-			Dataset:    dataset,
-			DataFile:   dataFile,
-			Rate:       lograte,
-			BackupFile: out,
+		pipeline, hasPipeline, err := gafit.ReadPipelineIfExists(dataFile)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
 		}
 
-		// Add a custom print function to track progress
-		ga.Callback = callback.Build()
+		var pipelinePtr *gafit.Pipeline
+		if hasPipeline {
+			pipelinePtr = &pipeline
+			log.Printf("Storing transformation pipeline from %s in the model\n", gafit.PipelineFile(dataFile))
+		}
 
-		// Initialize the linear model factory
-		factory := gafit.LinearModelFactory{
-			Config: gafit.LinearModelConfig{
-				Data:               dataset,
-				MutationRate:       mutRate,
-				NumSplits:          ns,
-				Cost:               getCostFunc(cost, dataset.NumFeatures()),
-				MaxFeatToDataRatio: fdratio,
-			},
-			Prob: iprob,
+		settings := gaSettings{
+			popSize:   popsize,
+			numGen:    ng,
+			logRate:   lograte,
+			mutRate:   mutRate,
+			numSplits: ns,
+			iprob:     iprob,
+			fdratio:   fdratio,
+			cost:      cost,
+			history:   history,
+		}
+
+		if len(targets) > 1 {
+			if classify {
+				log.Fatalf("Multiple targets are only supported for regression\n")
+				return
+			}
+
+			fitMultiTarget(dataFile, targets, shared, gafit.ReadOptions{DropFirst: dummy}, standardize, scaleTarget, pipelinePtr, settings, out)
+			saveHistory(historyFile, history)
+			return
 		}
 
-		// Find the minimum
-		err = ga.Minimize(factory.Generate)
+		dataset, err := gafit.ReadWithOptions(dataFile, target, gafit.ReadOptions{DropFirst: dummy, ClassTarget: classify})
+
 		if err != nil {
 			log.Fatalf("%s\n", err)
 			return
 		}
 
-		model := gafit.NewModel(ga.HallOfFame[0], dataset, cost, dataFile)
+		var std *gafit.Standardization
+		fitData := dataset
+		if standardize {
+			s := gafit.NewStandardization(dataset, scaleTarget)
+			std = &s
+			fitData = s.Apply(dataset)
+			log.Printf("Features are standardized prior to fitting\n")
+		}
+
+		var classifier *gafit.ClassifierConfig
+		if classify {
+			if standardize {
+				log.Fatalf("--standardize is not supported for classification\n")
+				return
+			}
+
+			if len(dataset.Classes) < 2 {
+				log.Fatalf("The target column must contain at least two classes\n")
+				return
+			}
+			classCost, err := getClassificationCost(cost)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			classifier = &gafit.ClassifierConfig{
+				NumClasses: len(dataset.Classes),
+				Cost:       classCost,
+			}
+			log.Printf("Fitting logistic regression model with %d classes: %v\n", len(dataset.Classes), dataset.Classes)
+		}
+
+		callback := gafit.GABackupCB{
+			Cost:            cost,
+			Dataset:         fitData,
+			DataFile:        dataFile,
+			Rate:            lograte,
+			BackupFile:      out,
+			Standardization: std,
+			Pipeline:        pipelinePtr,
+		}
+
+		// Initialize the linear model factory
+		factory := newFactory(fitData, settings)
+		factory.Config.Classifier = classifier
+
+		// Find the minimum. The callback tracks the progress
+		best, err := minimize(factory, popsize, ng, withHistory(callback.Build(), history, dataset.TargetName), nil)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		model := gafit.NewModel(best, fitData, cost, dataFile)
+		if std != nil {
+			model = std.Unscale(model)
+		}
+		model.Pipeline = pipelinePtr
+		model = model.WithInference(dataset)
 		gafit.SaveModel(out, model)
+		saveHistory(historyFile, history)
 	},
 }
 
+// gaSettings holds the settings of the genetic algorithm. The fields that only apply to the fit
+// command (logRate and history) are ignored by the other commands
+// of a multi-target fit
+type gaSettings struct {
+	popSize   uint
+	numGen    uint
+	logRate   uint
+	mutRate   float64
+	numSplits uint
+	iprob     float64
+	fdratio   float64
+	cost      string
+
+	// history records the statistics of each generation if not nil
+	history *gafit.GAHistory
+}
+
+// newFactory returns the factory of linear models with the GA settings, used by all commands
+// that select features of data. The cost function is normalized with the number of features
+// in data
+func newFactory(data gafit.Dataset, settings gaSettings) gafit.LinearModelFactory {
+	return gafit.LinearModelFactory{
+		Config: gafit.LinearModelConfig{
+			Data:               data,
+			MutationRate:       settings.mutRate,
+			NumSplits:          settings.numSplits,
+			Cost:               getCostFunc(settings.cost, data.NumFeatures()),
+			MaxFeatToDataRatio: settings.fdratio,
+		},
+		Prob: settings.iprob,
+	}
+}
+
+// minimize runs the genetic algorithm and returns the best individual. If rng is nil, the
+// random number generator of the GA is seeded with the current time
+func minimize(factory gafit.LinearModelFactory, popSize uint, numGen uint, callback func(ga *eaopt.GA), rng *rand.Rand) (eaopt.Individual, error) {
+	conf := eaopt.NewDefaultGAConfig()
+	conf.PopSize = popSize
+	if rng != nil {
+		conf.RNG = rng
+	}
+	ga, err := conf.NewGA()
+	if err != nil {
+		return eaopt.Individual{}, err
+	}
+
+	ga.NGenerations = numGen
+	ga.Callback = callback
+	if err = ga.Minimize(factory.Generate); err != nil {
+		return eaopt.Individual{}, err
+	}
+	return ga.HallOfFame[0], nil
+}
+
+// withHistory returns a callback that calls callback and records the statistics of the
+// generation in history. If history is nil, callback is returned unchanged
+func withHistory(callback func(ga *eaopt.GA), history *gafit.GAHistory, target string) func(ga *eaopt.GA) {
+	if history == nil {
+		return callback
+	}
+
+	record := history.Recorder(target)
+	return func(ga *eaopt.GA) {
+		callback(ga)
+		record(ga)
+	}
+}
+
+// saveHistory writes the history of the GA runs to fname if history is not nil
+func saveHistory(fname string, history *gafit.GAHistory) {
+	if history == nil {
+		return
+	}
+
+	if err := gafit.SaveGAHistory(fname, *history); err != nil {
+		log.Fatalf("%s\n", err)
+		return
+	}
+	log.Printf("GA history written to %s\n", fname)
+}
+
+// progressLogger returns a callback that logs the best fitness every rate generation
+func progressLogger(rate uint, cost string, target string) func(ga *eaopt.GA) {
+	return func(ga *eaopt.GA) {
+		if ga.Generations%rate == 0 {
+			log.Printf("Best %s for %s at generation %d: %f\n", cost, target, ga.Generations, ga.HallOfFame[0].Fitness)
+		}
+	}
+}
+
+// multiTargetBackup returns a callback that calls callback and every rate generation writes a
+// backup of a multi-target fit to out. The backup holds the models in done (the targets that
+// are already fitted) followed by the models created by current from the best individual of
+// the running GA
+func multiTargetBackup(callback func(ga *eaopt.GA), rate uint, out string, done gafit.MultiTargetModel, current func(best eaopt.Individual) []gafit.Model) func(ga *eaopt.GA) {
+	return func(ga *eaopt.GA) {
+		callback(ga)
+		if ga.Generations%rate != 0 {
+			return
+		}
+
+		backup := done
+		backup.Models = append(append([]gafit.Model{}, done.Models...), current(ga.HallOfFame[0])...)
+		backup.Targets = done.Targets[:len(backup.Models)]
+		if err := gafit.SaveMultiTargetModel(out, backup); err != nil {
+			log.Printf("Error while writing backup: %s\n", err)
+		}
+	}
+}
+
+// fitMultiTarget fits one model per target and stores them in a multi-target model file. If
+// shared is true, a single GA run selects the same features for all targets. Otherwise, the
+// features of each target are selected independently
+func fitMultiTarget(dataFile string, targets []string, shared bool, opts gafit.ReadOptions, standardize bool, scaleTarget bool, pipeline *gafit.Pipeline, settings gaSettings, out string) {
+	all, err := gafit.ReadWithOptions(dataFile, "", opts)
+	if err != nil {
+		log.Fatalf("%s\n", err)
+		return
+	}
+
+	names := make([]string, len(targets))
+	for i, t := range targets {
+		names[i] = strings.Trim(t, "#/ \n\t\r\v")
+	}
+
+	datasets, err := gafit.SplitTargets(all, names)
+	if err != nil {
+		log.Fatalf("%s\n", err)
+		return
+	}
+
+	fitData := make([]gafit.Dataset, len(datasets))
+	stds := make([]*gafit.Standardization, len(datasets))
+	for i, data := range datasets {
+		fitData[i] = data
+		if standardize {
+			s := gafit.NewStandardization(data, scaleTarget)
+			stds[i] = &s
+			fitData[i] = s.Apply(data)
+		}
+	}
+	if standardize {
+		log.Printf("Features are standardized prior to fitting\n")
+	}
+
+	// The data of the factory is set below, depending on whether the features are shared
+	factory := newFactory(fitData[0], settings)
+
+	// unscale converts the model of target i back to original units and attaches the pipeline
+	unscale := func(i int, model gafit.Model) gafit.Model {
+		if stds[i] != nil {
+			model = stds[i].Unscale(model)
+		}
+		model.Pipeline = pipeline
+		return model
+	}
+
+	result := gafit.MultiTargetModel{
+		Targets:        names,
+		SharedFeatures: shared,
+	}
+	if shared {
+		factory.Config.Data = fitData[0]
+		for _, data := range fitData[1:] {
+			factory.Config.ExtraTargets = append(factory.Config.ExtraTargets, data.Y)
+		}
+
+		log.Printf("Selecting a shared set of features for %d targets\n", len(names))
+		sharedModels := func(best eaopt.Individual) []gafit.Model {
+			models := gafit.NewSharedFeatureModels(best, fitData, settings.cost, dataFile)
+			for i := range models {
+				models[i] = unscale(i, models[i])
+			}
+			return models
+		}
+
+		logger := progressLogger(settings.logRate, settings.cost, strings.Join(names, ", "))
+		backup := multiTargetBackup(logger, settings.logRate, out, result, sharedModels)
+		best, err := minimize(factory, settings.popSize, settings.numGen, withHistory(backup, settings.history, strings.Join(names, ",")), nil)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		result.Models = sharedModels(best)
+	} else {
+		for i, data := range fitData {
+			factory.Config.Data = data
+			targetModel := func(best eaopt.Individual) []gafit.Model {
+				return []gafit.Model{unscale(i, gafit.NewModel(best, data, settings.cost, dataFile))}
+			}
+
+			log.Printf("Selecting features for %s\n", data.TargetName)
+			logger := progressLogger(settings.logRate, settings.cost, data.TargetName)
+			backup := multiTargetBackup(logger, settings.logRate, out, result, targetModel)
+			best, err := minimize(factory, settings.popSize, settings.numGen, withHistory(backup, settings.history, data.TargetName), nil)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			result.Models = append(result.Models, targetModel(best)...)
+		}
+	}
+
+	for i := range result.Models {
+		result.Models[i] = result.Models[i].WithInference(datasets[i])
+		log.Printf("%s: %d features, %s = %f\n", names[i], len(result.Models[i].Coeffs), settings.cost, result.Models[i].Score.Value)
+	}
+
+	if err := gafit.SaveMultiTargetModel(out, result); err != nil {
+		log.Fatalf("%s\n", err)
+		return
+	}
+	log.Printf("Multi-target model written to %s\n", out)
+}
+
 func saveCoeff(fname string, features []string, coeff *mat.VecDense) {
 	// Save features
 	f, err := os.Create(fname)
@@ -210,18 +560,23 @@ func init() {
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
-	fitCmd.Flags().StringP("type", "t", "reg", "Fit-type: regression (reg) or classify (cls)")
+	fitCmd.Flags().StringP("type", "t", "reg", "Fit-type: regression (reg) or classification (cls)")
 	fitCmd.Flags().StringP("data", "d", "", "Datafile. Should be stored in CSV format")
-	fitCmd.Flags().StringP("target", "y", "lastCol", "Name of the column used as target in the fit")
+	fitCmd.Flags().StringP("target", "y", "lastCol", "Name of the column used as target in the fit (comma separated list for multiple targets)")
 	fitCmd.Flags().Float64P("mutrate", "m", 0.5, "Mutation rate in genetic algorithm")
 	fitCmd.Flags().StringP("out", "o", "model.json", "File where the result of the best model is placed")
 	fitCmd.Flags().UintP("numgen", "g", 100, "Number of generations to run")
-	fitCmd.Flags().StringP("cost", "c", "aicc", "Cost function (aic|aicc|bic|ebic)")
+	fitCmd.Flags().StringP("cost", "c", "aicc", "Cost function (aic|aicc|bic|ebic). Classification supports aic, aicc and bic")
 	fitCmd.Flags().UintP("csplits", "s", 2, "Number of splits used for cross over operations")
 	fitCmd.Flags().Float64P("iprob", "i", 0.5, "Probability of activating a feature in the initial pool of genomes")
 	fitCmd.Flags().UintP("lograte", "r", 100, "Number generation between each log and backup of best solution")
 	fitCmd.Flags().UintP("popsize", "p", 30, "Population size")
 	fitCmd.Flags().Float64P("fdratio", "f", 0.8, "Maximum ratio between number of selected features and number of data points")
+	fitCmd.Flags().Bool("standardize", false, "Standardize the features prior to fitting")
+	fitCmd.Flags().String("history", "", "File where the statistics of each generation are written (CSV, or JSON if the extension is .json)")
+	fitCmd.Flags().Bool("scale-target", false, "Divide the target by its standard deviation (only used together with --standardize)")
+	fitCmd.Flags().Bool("shared", false, "Select the same features for all targets when several targets are given")
+	fitCmd.Flags().Bool("dummy", false, "Use dummy encoding with a reference level for categorical columns instead of one-hot encoding")
 }
 
 func getCostFunc(name string, numFeat int) gafit.CostFunction {
@@ -244,6 +599,20 @@ func getCostFunc(name string, numFeat int) gafit.CostFunction {
 	}
 }
 
+// getClassificationCost returns the cost function for classification models. The criteria are
+// based on the binomial/multinomial log-likelihood
+func getClassificationCost(name string) (gafit.ClassificationCost, error) {
+	switch name {
+	case "aicc":
+		return gafit.LogisticAicc, nil
+	case "aic":
+		return gafit.LogisticAic, nil
+	case "bic":
+		return gafit.LogisticBic, nil
+	}
+	return nil, fmt.Errorf("Cost function %s is not supported for classification. Must be aic, aicc or bic", name)
+}
+
 func isScript(name string) bool {
 	if _, err := os.Stat(name); os.IsNotExist(err) {
 		return false
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"io"
+	"log"
+	"os"
+
+	"github.com/davidkleiven/gogafit/gafit"
+	"github.com/spf13/cobra"
+)
+
+// metricsCmd represents the metrics command
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Calculate classification metrics for a model",
+	Long: `Evaluates a classification model (fitted with gogafit fit --type cls) on a labelled
+dataset. The data file must contain the target column of the model. The following metrics are
+reported
+
+accuracy:   fraction of correctly classified rows
+precision:  fraction of rows predicted as a class that belong to the class
+recall:     fraction of rows belonging to a class that are predicted as the class
+F1:         harmonic mean of precision and recall
+log-loss:   average negative log-probability of the true class
+ROC AUC:    area under the ROC curve (one-vs-rest for each class). For binary models the AUC
+            of the second class is reported, otherwise the average over the classes
+confusion:  number of rows of each true class (rows) predicted as each class (columns)
+
+Example:
+
+gogafit metrics -m model.json -d test.csv
+gogafit metrics -m model.json -d test.csv --format json -o metrics.json
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dataFile, err := cmd.Flags().GetString("data")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		modelFile, err := cmd.Flags().GetString("model")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		out, err := cmd.Flags().GetString("out")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		model, err := gafit.ReadModel(modelFile)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if !model.IsClassifier() {
+			log.Fatalf("The metrics command requires a classification model. Use rmse for regression models\n")
+			return
+		}
+
+		data, err := gafit.ReadForModel(dataFile, model.TargetName, model)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		metrics := gafit.NewClassificationMetrics(model.Classes, data.Y, model.PredictProba(data))
+
+		var w io.Writer = os.Stdout
+		if out != "" {
+			f, err := os.Create(out)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			defer f.Close()
+			w = f
+		}
+
+		switch format {
+		case "table":
+			err = metrics.WriteTable(w)
+		case "json":
+			err = metrics.WriteJSON(w)
+		default:
+			log.Fatalf("Unknown format %s. Must be table or json\n", format)
+			return
+		}
+
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if out != "" {
+			log.Printf("Metrics written to %s\n", out)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(metricsCmd)
+
+	metricsCmd.Flags().StringP("data", "d", "", "Csv file with labelled data")
+	metricsCmd.Flags().StringP("model", "m", "model.json", "JSON file with a fitted classification model")
+	metricsCmd.Flags().String("format", "table", "Output format (table or json)")
+	metricsCmd.Flags().StringP("out", "o", "", "File where the metrics are written (default stdout)")
+}
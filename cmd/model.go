@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/davidkleiven/gogafit/gafit"
+	"github.com/spf13/cobra"
+)
+
+// modelCmd represents the model command
+var modelCmd = &cobra.Command{
+	Use:   "model",
+	Short: "Validate and upgrade model files",
+	Long: `Commands for inspecting model files written by fit and elm.
+
+Model files carry a schema version (SchemaVersion). When a model file written with an older
+schema version is read by any command, it is upgraded automatically in memory without reading
+any other files. Use gogafit model upgrade to write the upgraded file. The upgrade command in
+addition calculates the coefficient covariance of regression models written by older versions
+(without SchemaVersion) from the training data, if it is still available.
+
+All commands validate model files when they are read. A model is rejected if
+- the file contains fields that are not part of the schema
+- the target column (TargetName) is missing
+- a coefficient, intercept or covariance element is NaN or infinite
+- the classes, standardization, covariance or pipeline refer to unknown features
+- the schema version is newer than supported by this version of gogafit
+	`,
+}
+
+// modelValidateCmd represents the model validate command
+var modelValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate a model file",
+	Long: `Checks that a model file (single or multi-target) is valid (see gogafit model -h). If a
+datafile is given, it is in addition checked that the data holds the columns needed by the model
+(after applying the pipeline of the model).
+
+Example:
+
+gogafit model validate -m model.json
+gogafit model validate -m model.json -d newdata.csv
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		modelFile, err := cmd.Flags().GetString("model")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		dataFile, err := cmd.Flags().GetString("data")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		bytes, err := ioutil.ReadFile(modelFile)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		multi, upgraded, err := gafit.ParseMultiTargetModel(bytes)
+		if err != nil {
+			log.Fatalf("%s is not valid: %s\n", modelFile, err)
+			return
+		}
+
+		for _, model := range multi.Models {
+			if dataFile != "" {
+				data, err := gafit.ReadWithOptions(dataFile, "", model.ReadOptions())
+				if err != nil {
+					log.Fatalf("%s: %s\n", dataFile, err)
+					return
+				}
+
+				if _, err := model.Prepare(data); err != nil {
+					log.Fatalf("%s can not be used with the model of %s: %s\n", dataFile, model.TargetName, err)
+					return
+				}
+			}
+			fmt.Printf("%s: %d features\n", model.TargetName, len(model.Features()))
+		}
+
+		if upgraded {
+			fmt.Printf("%s uses an older schema version. Run gogafit model upgrade to update the file\n", modelFile)
+		}
+		fmt.Printf("%s is valid (schema version %d)\n", modelFile, gafit.ModelSchemaVersion)
+	},
+}
+
+// modelUpgradeCmd represents the model upgrade command
+var modelUpgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade a model file to the latest schema version",
+	Long: `Reads a model file (single or multi-target), upgrades it to the latest schema version and
+validates it. Regression models without the coefficient covariance get it calculated from the
+training data given by the Datafile field, such that pred and summary no longer need the
+training data. If the training data is not available, the model is upgraded without it. The
+upgraded model is written to the file given by -o (by default the input file is overwritten).
+
+Example:
+
+gogafit model upgrade -m old_model.json -o model.json
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		modelFile, err := cmd.Flags().GetString("model")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		out, err := cmd.Flags().GetString("out")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if out == "" {
+			out = modelFile
+		}
+
+		bytes, err := ioutil.ReadFile(modelFile)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		isMulti, err := gafit.IsMultiTargetModel(bytes)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		multi, upgraded, err := gafit.ParseMultiTargetModel(bytes)
+		if err != nil {
+			log.Fatalf("%s is not valid: %s\n", modelFile, err)
+			return
+		}
+
+		added := addInference(&multi)
+		if !upgraded && added == 0 {
+			log.Printf("%s already has the latest schema version (%d)\n", modelFile, gafit.ModelSchemaVersion)
+		}
+
+		if isMulti {
+			err = gafit.SaveMultiTargetModel(out, multi)
+		} else {
+			err = gafit.SaveModel(out, multi.Models[0])
+		}
+
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		log.Printf("Model with schema version %d written to %s\n", gafit.ModelSchemaVersion, out)
+	},
+}
+
+// addInference embeds the coefficient covariance in the regression models that do not hold it,
+// using the training data given by their Datafile field. Models with regularized network
+// weights are skipped. It returns the number of models that were updated
+func addInference(multi *gafit.MultiTargetModel) int {
+	added := 0
+	for i, model := range multi.Models {
+		if model.Inference != nil || model.IsClassifier() || model.IsNetwork() {
+			continue
+		}
+
+		data, err := readTrainingData(model)
+		if err != nil {
+			log.Printf("%s. The model of %s holds no covariance information\n", err, model.TargetName)
+			continue
+		}
+		multi.Models[i] = model.WithInference(data)
+		added++
+	}
+	return added
+}
+
+func init() {
+	rootCmd.AddCommand(modelCmd)
+	modelCmd.AddCommand(modelValidateCmd)
+	modelCmd.AddCommand(modelUpgradeCmd)
+
+	modelValidateCmd.Flags().StringP("model", "m", "model.json", "JSON file with the model")
+	modelValidateCmd.Flags().StringP("data", "d", "", "Csv file that should be checked against the model (optional)")
+
+	modelUpgradeCmd.Flags().StringP("model", "m", "model.json", "JSON file with the model")
+	modelUpgradeCmd.Flags().StringP("out", "o", "", "File where the upgraded model is written (default: overwrite the input)")
+}
@@ -1,25 +1,60 @@
 package cmd
 
 import (
+	"fmt"
+	"image/color"
 	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/davidkleiven/gogafit/gafit"
 	"github.com/spf13/cobra"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
 )
 
 // plotCmd represents the plot command
 var plotCmd = &cobra.Command{
 	Use:   "plot",
-	Short: "Plot the fit in a scatter plot",
+	Short: "Plot the fit and the residuals",
 	Long: `Create a scatter plot of the predictions of one or multiple datasets.
 If we have the training data in a file called train.csv and validation data in a file
 validate.csv. Our trained model is stored in model.json, it can be plotted by
 
 gogafit plot -d train.csv,validate.csv -m model.json -o plot.png
+
+The type of plot is selected with --kind
+
+parity    - predicted values against the reference values (default)
+residuals - residuals against the predicted values
+histogram - histogram of the residuals together with a normal density with the same mean
+            and standard deviation
+qq        - normal Q-Q plot of the studentized residuals
+leverage  - studentized residuals against the leverage (the diagonal of the hat matrix),
+            with contours of Cook's distance 0.5 and 1. Points outside the contours have a
+            large influence on the fit. The leverages are calculated for each dataset, and
+            are only meaningful for the training data
+
+gogafit plot -d train.csv -m model.json --kind leverage -o leverage.pdf
+
+With --kind convergence, -d is a comma separated list of GA history files written by fit with
+--history, and no model is needed. Three plots are placed side by side: the best, mean and
+worst cost, the diversity of the population and the number of features of the best genome as
+a function of the generation.
+
+gogafit plot -d history.csv --kind convergence -o convergence.png
+
+The image format is deduced from the extension of the output file (e.g. png, svg or pdf).
+
+For multi-target models (see gogafit fit -h) there is one scatter plot per target, placed side
+by side in the same image.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		dataFiles, err := cmd.Flags().GetString("data")
@@ -40,89 +75,495 @@ gogafit plot -d train.csv,validate.csv -m model.json -o plot.png
 			return
 		}
 
-		// Split dataFiles
-		files := strings.Split(dataFiles, ",")
+		kind, err := cmd.Flags().GetString("kind")
 		if err != nil {
 			log.Fatalf("%s\n", err)
 			return
 		}
 
-		model, err := gafit.ReadModel(modelFile)
-		if err != nil {
-			log.Fatalf("Reading model: %s\n", err)
+		// Split dataFiles
+		files := strings.Split(dataFiles, ",")
+
+		if kind == "convergence" {
+			plots, err := convergencePlots(files)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+
+			if err := savePlots(plots, 4*vg.Inch, 4*vg.Inch, out); err != nil {
+				log.Fatalf("Error while saving plot %s\n", err)
+				return
+			}
+			log.Printf("Plot saved to %s\n", out)
 			return
 		}
 
-		// Initialize plot
-		plt := plot.New()
+		createPlot, ok := plotKinds[kind]
+		if !ok {
+			log.Fatalf("Unknown plot kind %s. Must be one of %s\n", kind, strings.Join(plotKindNames, ", "))
+			return
+		}
 
-		plt.X.Label.Text = model.TargetName + " predicted"
-		plt.Y.Label.Text = model.TargetName + " reference"
+		multi, err := gafit.ReadMultiTargetModel(modelFile)
+		if err != nil {
+			log.Fatalf("Reading model: %s\n", err)
+			return
+		}
 
-		minValue := 1e100
-		maxValue := -1e100
-		colors := JosephAndHisBrothers()
-		glyphs := NewDefaultGlyphCycle()
+		plots := make([]*plot.Plot, len(multi.Models))
+		for i, model := range multi.Models {
+			if model.IsClassifier() {
+				log.Fatalf("The plot command does not support classification models\n")
+				return
+			}
 
-		for i, fname := range files {
-			dataset, err := gafit.Read(fname, model.TargetName)
+			plots[i], err = createPlot(model, files)
 			if err != nil {
-				log.Fatalf("Dataset %d: %s\n", i, err)
+				log.Fatalf("%s\n", err)
 				return
 			}
-			pred := dataset.Dot(model.Coeffs)
+		}
+
+		if err := savePlots(plots, 4*vg.Inch, 4*vg.Inch, out); err != nil {
+			log.Fatalf("Error while saving plot %s\n", err)
+			return
+		}
+		log.Printf("Plot saved to %s\n", out)
+	},
+}
 
-			// Create points
-			pts := make(plotter.XYs, pred.Len())
-			for j := 0; j < pred.Len(); j++ {
-				pts[j].Y = dataset.Y.AtVec(j)
-				pts[j].X = pred.AtVec(j)
+// plotKindNames holds the plot kinds in the order listed in the help text
+var plotKindNames = []string{"parity", "residuals", "histogram", "qq", "leverage", "convergence"}
 
-				if pts[j].Y > maxValue {
-					maxValue = pts[j].Y
-				}
+// plotKinds maps the plot kinds to the functions creating the plot of a model. Convergence
+// plots do not depend on a model and are created by convergencePlots
+var plotKinds = map[string]func(gafit.Model, []string) (*plot.Plot, error){
+	"parity":    parityPlot,
+	"residuals": residualPlot,
+	"histogram": residualHistogram,
+	"qq":        qqPlot,
+	"leverage":  leveragePlot,
+}
 
-				if pts[j].Y < minValue {
-					minValue = pts[j].Y
-				}
+// parityPlot creates a scatter plot of the predicted values against the reference values for
+// each of the datafiles
+func parityPlot(model gafit.Model, files []string) (*plot.Plot, error) {
+	// Initialize plot
+	plt := plot.New()
+
+	plt.X.Label.Text = model.TargetName + " predicted"
+	plt.Y.Label.Text = model.TargetName + " reference"
+
+	minValue := 1e100
+	maxValue := -1e100
+	colors := JosephAndHisBrothers()
+	glyphs := NewDefaultGlyphCycle()
+
+	for i, fname := range files {
+		dataset, err := gafit.ReadForModel(fname, model.TargetName, model)
+		if err != nil {
+			return nil, fmt.Errorf("Dataset %d: %s", i, err)
+		}
+		pred := model.Predict(dataset)
+
+		// Create points
+		pts := make(plotter.XYs, pred.Len())
+		for j := 0; j < pred.Len(); j++ {
+			pts[j].Y = dataset.Y.AtVec(j)
+			pts[j].X = pred.AtVec(j)
+
+			if pts[j].Y > maxValue {
+				maxValue = pts[j].Y
 			}
 
-			s, err := plotter.NewScatter(pts)
-			s.GlyphStyle.Color = colors.Next()
-			s.GlyphStyle.Shape = glyphs.Next()
-			if err != nil {
-				log.Fatalf("%s\n", err)
-				return
+			if pts[j].Y < minValue {
+				minValue = pts[j].Y
 			}
-			plt.Add(s)
-			plt.Legend.Add(fname)
 		}
 
-		rng := maxValue - minValue
-		if rng < 1e-16 {
-			rng = 1.0
+		s, err := plotter.NewScatter(pts)
+		if err != nil {
+			return nil, err
 		}
+		s.GlyphStyle.Color = colors.Next()
+		s.GlyphStyle.Shape = glyphs.Next()
+		plt.Add(s)
+		plt.Legend.Add(fname)
+	}
+
+	rng := maxValue - minValue
+	if rng < 1e-16 {
+		rng = 1.0
+	}
+
+	straightLine := make(plotter.XYs, 2)
+	frac := 0.05
+	straightLine[0].X = minValue - frac*rng
+	straightLine[0].Y = minValue - frac*rng
+	straightLine[1].X = maxValue + frac*rng
+	straightLine[1].Y = maxValue + frac*rng
+	line, err := plotter.NewLine(straightLine)
+	if err != nil {
+		return nil, err
+	}
+	line.LineStyle.Color = colors.Get(2)
+	plt.Add(line)
+	return plt, nil
+}
 
-		straightLine := make(plotter.XYs, 2)
-		frac := 0.05
-		straightLine[0].X = minValue - frac*rng
-		straightLine[0].Y = minValue - frac*rng
-		straightLine[1].X = maxValue + frac*rng
-		straightLine[1].Y = maxValue + frac*rng
-		line, err := plotter.NewLine(straightLine)
-		line.LineStyle.Color = colors.Get(2)
+// residualDiagnostics calculates the residual diagnostics of the model for each of the
+// datafiles. The leverages are only calculated if leverage is true
+func residualDiagnostics(model gafit.Model, files []string, leverage bool) ([]gafit.ResidualDiagnostics, error) {
+	res := make([]gafit.ResidualDiagnostics, len(files))
+	for i, fname := range files {
+		dataset, err := gafit.ReadForModel(fname, model.TargetName, model)
 		if err != nil {
-			log.Fatalf("%s\n", err)
-			return
+			return nil, fmt.Errorf("Dataset %d: %s", i, err)
 		}
-		plt.Add(line)
+		res[i] = gafit.NewResidualDiagnostics(model, dataset, leverage)
+	}
+	return res, nil
+}
 
-		if err := plt.Save(4*vg.Inch, 4*vg.Inch, out); err != nil {
-			log.Fatalf("Error while saving plot %s\n", err)
-			return
+// scatterPlot creates a plot with one scatter series per datafile. The points of each series
+// are given by xy. Points with a NaN coordinate are skipped
+func scatterPlot(files []string, diags []gafit.ResidualDiagnostics, xy func(d gafit.ResidualDiagnostics) (x, y []float64)) (*plot.Plot, error) {
+	plt := plot.New()
+	colors := JosephAndHisBrothers()
+	glyphs := NewDefaultGlyphCycle()
+
+	for i, d := range diags {
+		x, y := xy(d)
+		pts := make(plotter.XYs, 0, len(x))
+		for j := range x {
+			if !math.IsNaN(x[j]) && !math.IsNaN(y[j]) {
+				pts = append(pts, plotter.XY{X: x[j], Y: y[j]})
+			}
 		}
-		log.Printf("Plot saved to %s\n", out)
-	},
+
+		s, err := plotter.NewScatter(pts)
+		if err != nil {
+			return nil, err
+		}
+		s.GlyphStyle.Color = colors.Next()
+		s.GlyphStyle.Shape = glyphs.Next()
+		plt.Add(s)
+		plt.Legend.Add(files[i])
+	}
+	return plt, nil
+}
+
+// addLine adds a straight line between two points to the plot
+func addLine(plt *plot.Plot, x0, y0, x1, y1 float64, c color.Color) error {
+	line, err := plotter.NewLine(plotter.XYs{{X: x0, Y: y0}, {X: x1, Y: y1}})
+	if err != nil {
+		return err
+	}
+	line.LineStyle.Color = c
+	plt.Add(line)
+	return nil
+}
+
+// residualPlot creates a scatter plot of the residuals against the predicted values
+func residualPlot(model gafit.Model, files []string) (*plot.Plot, error) {
+	diags, err := residualDiagnostics(model, files, false)
+	if err != nil {
+		return nil, err
+	}
+
+	plt, err := scatterPlot(files, diags, func(d gafit.ResidualDiagnostics) ([]float64, []float64) {
+		return d.Predicted, d.Residuals
+	})
+	if err != nil {
+		return nil, err
+	}
+	plt.X.Label.Text = model.TargetName + " predicted"
+	plt.Y.Label.Text = "Residual"
+
+	colors := JosephAndHisBrothers()
+	return plt, addLine(plt, plt.X.Min, 0.0, plt.X.Max, 0.0, colors.Get(2))
+}
+
+// residualHistogram creates a histogram of the residuals of each datafile, normalized to unit
+// area. A normal density with the mean and standard deviation of all residuals is added
+func residualHistogram(model gafit.Model, files []string) (*plot.Plot, error) {
+	diags, err := residualDiagnostics(model, files, false)
+	if err != nil {
+		return nil, err
+	}
+
+	plt := plot.New()
+	plt.X.Label.Text = "Residual"
+	plt.Y.Label.Text = "Density"
+	colors := JosephAndHisBrothers()
+
+	all := []float64{}
+	for i, d := range diags {
+		all = append(all, d.Residuals...)
+
+		// Number of bins from Sturges' rule
+		bins := int(math.Ceil(math.Log2(float64(len(d.Residuals))))) + 1
+		hist, err := plotter.NewHist(plotter.Values(d.Residuals), bins)
+		if err != nil {
+			return nil, err
+		}
+		hist.Normalize(1.0)
+		c := colors.Next()
+		c.A = 128
+		hist.FillColor = c
+		hist.LineStyle.Color = colors.Get(i)
+		plt.Add(hist)
+		plt.Legend.Add(files[i], hist)
+	}
+
+	mean, std := stat.MeanStdDev(all, nil)
+	if std > 0.0 {
+		normal := distuv.Normal{Mu: mean, Sigma: std}
+		density := plotter.NewFunction(normal.Prob)
+		density.LineStyle.Color = colors.Get(2)
+		density.LineStyle.Dashes = []vg.Length{vg.Points(4), vg.Points(2)}
+		plt.Add(density)
+		plt.Legend.Add("normal", density)
+	}
+	return plt, nil
+}
+
+// qqPlot creates a normal Q-Q plot of the studentized residuals of each datafile
+func qqPlot(model gafit.Model, files []string) (*plot.Plot, error) {
+	diags, err := residualDiagnostics(model, files, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkStudentized(diags); err != nil {
+		return nil, err
+	}
+
+	plt, err := scatterPlot(files, diags, func(d gafit.ResidualDiagnostics) ([]float64, []float64) {
+		_, sample := definedStudentized(d)
+		sort.Float64s(sample)
+		return gafit.NormalQuantiles(len(sample)), sample
+	})
+	if err != nil {
+		return nil, err
+	}
+	plt.X.Label.Text = "Theoretical quantiles"
+	plt.Y.Label.Text = "Studentized residual"
+
+	colors := JosephAndHisBrothers()
+	return plt, addLine(plt, plt.X.Min, plt.X.Min, plt.X.Max, plt.X.Max, colors.Get(2))
+}
+
+// definedStudentized returns the leverage and the studentized residuals of the points where
+// the studentized residual is defined. It is NaN for points with leverage 1
+func definedStudentized(d gafit.ResidualDiagnostics) ([]float64, []float64) {
+	leverage := []float64{}
+	studentized := []float64{}
+	for i, r := range d.Studentized {
+		if !math.IsNaN(r) && !math.IsInf(r, 0) {
+			leverage = append(leverage, d.Leverage[i])
+			studentized = append(studentized, r)
+		}
+	}
+	return leverage, studentized
+}
+
+// checkStudentized logs the number of points without a studentized residual, which are not
+// shown. It returns an error if no point has a studentized residual
+func checkStudentized(diags []gafit.ResidualDiagnostics) error {
+	total := 0
+	dropped := 0
+	for _, d := range diags {
+		_, r := definedStudentized(d)
+		total += len(d.Studentized)
+		dropped += len(d.Studentized) - len(r)
+	}
+
+	if dropped == total {
+		return fmt.Errorf("None of the %d points has a studentized residual, since all have leverage 1", total)
+	}
+	if dropped > 0 {
+		log.Printf("%d points with leverage 1 have no studentized residual and are not shown\n", dropped)
+	}
+	return nil
+}
+
+// leveragePlot creates a scatter plot of the studentized residuals against the leverage of
+// each datafile, with contours of Cook's distance 0.5 and 1
+func leveragePlot(model gafit.Model, files []string) (*plot.Plot, error) {
+	diags, err := residualDiagnostics(model, files, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkStudentized(diags); err != nil {
+		return nil, err
+	}
+
+	plt, err := scatterPlot(files, diags, definedStudentized)
+	if err != nil {
+		return nil, err
+	}
+	plt.X.Label.Text = "Leverage"
+	plt.Y.Label.Text = "Studentized residual"
+
+	// The contours are drawn within the leverage range of the data and up to the largest
+	// absolute studentized residual (at least 2), such that they do not dominate the axes
+	rmax := math.Max(math.Max(math.Abs(plt.Y.Min), math.Abs(plt.Y.Max)), 2.0)
+	hmax := math.Min(plt.X.Max, 0.999)
+	colors := JosephAndHisBrothers()
+	for i, cook := range []float64{0.5, 1.0} {
+		for _, d := range diags {
+			// Leverage where the contour reaches rmax
+			p := float64(d.NumParams)
+			hmin := cook * p / (rmax*rmax + cook*p)
+			if hmin >= hmax {
+				continue
+			}
+
+			numPts := 50
+			upper := make(plotter.XYs, numPts)
+			lower := make(plotter.XYs, numPts)
+			for j := range upper {
+				h := hmin + (hmax-hmin)*float64(j)/float64(numPts-1)
+				r := d.CooksDistanceContour(cook, h)
+				upper[j] = plotter.XY{X: h, Y: r}
+				lower[j] = plotter.XY{X: h, Y: -r}
+			}
+
+			for j, pts := range []plotter.XYs{upper, lower} {
+				line, err := plotter.NewLine(pts)
+				if err != nil {
+					return nil, err
+				}
+				line.LineStyle.Color = colors.Get(2 + i)
+				line.LineStyle.Dashes = []vg.Length{vg.Points(4), vg.Points(2)}
+				plt.Add(line)
+				if j == 0 {
+					plt.Legend.Add(fmt.Sprintf("Cook's distance %g", cook), line)
+				}
+			}
+
+			// All datafiles have the same number of parameters
+			break
+		}
+	}
+	return plt, nil
+}
+
+// convergencePlots creates plots of the fitness, the population diversity and the number of
+// features of the best genome against the generation for all GA runs in the history files
+func convergencePlots(files []string) ([]*plot.Plot, error) {
+	fitness := plot.New()
+	fitness.Y.Label.Text = "Cost"
+	diversity := plot.New()
+	diversity.Y.Label.Text = "Diversity"
+	numFeatures := plot.New()
+	numFeatures.Y.Label.Text = "Number of features"
+
+	plots := []*plot.Plot{fitness, diversity, numFeatures}
+	for _, plt := range plots {
+		plt.X.Label.Text = "Generation"
+	}
+
+	colors := JosephAndHisBrothers()
+	dashes := [][]vg.Length{nil, {vg.Points(4), vg.Points(2)}, {vg.Points(1), vg.Points(2)}}
+	for i, fname := range files {
+		history, err := gafit.ReadGAHistory(fname)
+		if err != nil {
+			return nil, fmt.Errorf("History %d: %s", i, err)
+		}
+
+		if history.Cost != "" {
+			fitness.Y.Label.Text = history.Cost
+		}
+
+		for _, target := range history.Targets() {
+			label := target
+			if len(files) > 1 {
+				label = fname + ": " + target
+			}
+
+			var series [5]plotter.XYs
+			for _, g := range history.Generations {
+				if g.Target != target {
+					continue
+				}
+				x := float64(g.Generation)
+				for j, v := range []*float64{g.Best, g.Mean, g.Worst} {
+					if v != nil {
+						series[j] = append(series[j], plotter.XY{X: x, Y: *v})
+					}
+				}
+				series[3] = append(series[3], plotter.XY{X: x, Y: g.Diversity})
+				series[4] = append(series[4], plotter.XY{X: x, Y: float64(g.NumFeatures)})
+			}
+
+			c := colors.Next()
+			for j, pts := range series {
+				if len(pts) == 0 {
+					continue
+				}
+				line, err := plotter.NewLine(pts)
+				if err != nil {
+					return nil, err
+				}
+				line.LineStyle.Color = c
+
+				switch {
+				case j < 3:
+					line.LineStyle.Dashes = dashes[j]
+					fitness.Add(line)
+					fitness.Legend.Add(label+" "+[]string{"best", "mean", "worst"}[j], line)
+				case j == 3:
+					diversity.Add(line)
+					diversity.Legend.Add(label, line)
+				default:
+					numFeatures.Add(line)
+					numFeatures.Legend.Add(label, line)
+				}
+			}
+		}
+	}
+	return plots, nil
+}
+
+// savePlots stores the plots side by side in a single image, where each plot has the given
+// width and height. The image format is deduced from the file extension
+func savePlots(plots []*plot.Plot, width vg.Length, height vg.Length, out string) error {
+	if len(plots) == 1 {
+		return plots[0].Save(width, height, out)
+	}
+
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(out), "."))
+	img, err := draw.NewFormattedCanvas(vg.Length(len(plots))*width, height, format)
+	if err != nil {
+		return err
+	}
+
+	tiles := draw.Tiles{
+		Rows:      1,
+		Cols:      len(plots),
+		PadX:      vg.Millimeter,
+		PadY:      vg.Millimeter,
+		PadTop:    vg.Millimeter,
+		PadBottom: vg.Millimeter,
+		PadLeft:   vg.Millimeter,
+		PadRight:  vg.Millimeter,
+	}
+	canvases := plot.Align([][]*plot.Plot{plots}, tiles, draw.New(img))
+	for i, plt := range plots {
+		plt.Draw(canvases[0][i])
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = img.WriteTo(f)
+	return err
 }
 
 func init() {
@@ -131,4 +572,5 @@ func init() {
 	plotCmd.Flags().StringP("data", "d", "", "Comma separated list of datasets (e.g. test, train")
 	plotCmd.Flags().StringP("model", "m", "", "JSON file with the model")
 	plotCmd.Flags().StringP("out", "o", "gogafitPlot.png", "Image file where the model will be stored")
+	plotCmd.Flags().String("kind", "parity", "Kind of plot ("+strings.Join(plotKindNames, ", ")+")")
 }
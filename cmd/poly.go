@@ -30,6 +30,10 @@ feat1,feat2,feat1p2targetQuantity
 1.0,2.0,1.0,3.0
 2.0,1.0,4.0,2.0
 
+together with data_poly_pipeline.json that records the transformation. Models fitted to
+data_poly.csv store this pipeline, such that predictions can be made directly from data with
+the original columns.
+
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		dataFile, err := cmd.Flags().GetString("data")
@@ -63,11 +67,24 @@ feat1,feat2,feat1p2targetQuantity
 		log.Printf("Using %s as target value\n", target)
 
 		data, err := gafit.Read(dataFile, target)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		pipeline, err := inputPipeline(dataFile, data)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
 		cols := data.Columns(pattern)
+		colNames := []string{}
 
 		log.Printf("Slected columns:\n")
 		for _, c := range cols {
 			log.Printf("No. %d name: %s\n", c, data.ColNames[c])
+			colNames = append(colNames, data.ColNames[c])
 		}
 
 		newData := gafit.AddPoly(cols, data, int(order))
@@ -80,6 +97,12 @@ feat1,feat2,feat1p2targetQuantity
 		}
 
 		log.Printf("New dataset written to %s\n", outfname)
+
+		step := gafit.PipelineStep{Poly: &gafit.PolyStep{Columns: colNames, Order: int(order)}}
+		if err = saveOutputPipeline(outfname, pipeline, step); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
 	},
 }
 
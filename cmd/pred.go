@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"fmt"
 	"log"
+	"math"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/davidkleiven/gogafit/gafit"
 	"github.com/spf13/cobra"
@@ -18,8 +22,39 @@ as well as the estimated prediction error.
 
 gogafit pred -m fitted_model.json -d dataToPredict.csv
 
+The model file holds the coefficient covariance and the residual variance, such that the
+training data is not needed. For model files without this information (written by older
+versions), the covariance is calculated from the training data given by the Datafile field.
+ELM models trained with --method ridge (the default) have regularized output weights and hold
+no covariance. Their prediction errors are written as NaN.
+
 note that dataToPredict.csv can also be the training data, in which case the computed values
 are the in-sample predictions and prediction errors.
+
+The output file holds the columns prediction, stddev, conf_lower, conf_upper, pred_lower and
+pred_upper. stddev is the standard deviation of a new observation. conf_lower and conf_upper
+bound the confidence interval of the mean response, while pred_lower and pred_upper bound the
+prediction interval of a new observation. The intervals are based on the Student-t distribution
+with n - p degrees of freedom (n: number of training data points, p: number of coefficients),
+and the level is set by --level.
+
+gogafit pred -m fitted_model.json -d dataToPredict.csv --level 0.9
+
+With --bootstrap n, the model is refitted to n bootstrap resamples of the training data (read
+from the Datafile field of the model), and the intervals are percentile intervals that do not
+rely on normally distributed residuals. The confidence interval is formed from the predictions
+of the refitted models, and the prediction interval and stddev from these predictions plus
+residuals of the fit drawn with replacement. The resampling method (case or residual), the
+reselection of the features on each resample and the number of parallel workers are set as for
+the summary command (see gogafit summary -h).
+
+gogafit pred -m fitted_model.json -d dataToPredict.csv --bootstrap 1000 --bootstrap-method residual
+
+For multi-target models (see gogafit fit -h) the output holds the columns <target> and
+<target>_stddev for each target.
+
+For classification models the output contains the predicted class and the probability of each
+class.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		modelFile, err := cmd.Flags().GetString("model")
@@ -28,22 +63,43 @@ are the in-sample predictions and prediction errors.
 			return
 		}
 
-		model, err := gafit.ReadModel(modelFile)
+		multi, err := gafit.ReadMultiTargetModel(modelFile)
 		if err != nil {
 			log.Fatalf("%s\n", err)
 			return
 		}
 
-		// Check if the datafile used by the model exists
-		if _, err := os.Stat(model.Datafile); os.IsNotExist(err) {
-			log.Fatalf("Looking for data at %s but can't find it\n", model.Datafile)
+		level, err := cmd.Flags().GetFloat64("level")
+		if err != nil {
+			log.Fatalf("%s\n", err)
 			return
-		} else if err != nil {
-			log.Fatalf("Error when checking file %s\n", err)
+		}
+
+		if level <= 0.0 || level >= 1.0 {
+			log.Fatalf("The level must be between 0 and 1. Got %f\n", level)
 			return
 		}
 
-		data, err := gafit.Read(model.Datafile, model.TargetName)
+		boot, err := readBootstrapSettings(cmd)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if len(multi.Models) > 1 {
+			predictMultiTarget(cmd, multi, level, boot)
+			return
+		}
+		model := multi.Models[0]
+
+		if model.IsClassifier() {
+			if boot.numResamples > 0 {
+				log.Fatalf("--bootstrap is not supported for classification models\n")
+				return
+			}
+			predictClasses(cmd, model)
+			return
+		}
 
 		predDataFile, err := cmd.Flags().GetString("data")
 		if err != nil {
@@ -51,14 +107,18 @@ are the in-sample predictions and prediction errors.
 			return
 		}
 
-		predData, err := gafit.Read(predDataFile, "")
+		predData, err := gafit.ReadForModel(predDataFile, "", model)
 		if err != nil {
 			log.Fatalf("%s\n", err)
 			return
 		}
 
-		pred := gafit.GetPredictions(data, model, &predData)
-		outfile := predDataFile[:len(predDataFile)-4] + "_predictions.csv"
+		pred, err := modelPredictions(model, predData, level, boot)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		outfile := predictionFile(predDataFile)
 		err = gafit.SavePredictions(outfile, pred)
 
 		if err != nil {
@@ -69,9 +129,128 @@ are the in-sample predictions and prediction errors.
 	},
 }
 
+// predictionFile returns the name of the file where the predictions for the data in dataFile
+// are written
+func predictionFile(dataFile string) string {
+	return strings.TrimSuffix(dataFile, filepath.Ext(dataFile)) + "_predictions.csv"
+}
+
+// readTrainingData reads the data the model was fitted to
+func readTrainingData(model gafit.Model) (gafit.Dataset, error) {
+	// Check if the datafile used by the model exists
+	if _, err := os.Stat(model.Datafile); os.IsNotExist(err) {
+		return gafit.Dataset{}, fmt.Errorf("Looking for data at %s but can't find it", model.Datafile)
+	} else if err != nil {
+		return gafit.Dataset{}, fmt.Errorf("Error when checking file %s", err)
+	}
+	return gafit.ReadForModel(model.Datafile, model.TargetName, model)
+}
+
+// modelPredictions returns the predictions together with the uncertainties. If bootstrap
+// resamples are requested, the intervals are bootstrap percentile intervals. Otherwise the
+// covariance embedded in the model is used if present, and if not (model files written by
+// older versions) it is calculated from the training data
+func modelPredictions(model gafit.Model, predData gafit.Dataset, level float64, boot bootstrapSettings) ([]gafit.Prediction, error) {
+	if boot.numResamples > 0 {
+		data, err := readTrainingData(model)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := boot.run(model, data)
+		if err != nil {
+			return nil, err
+		}
+		return b.PredictWithUncertainty(predData, level)
+	}
+
+	if model.Inference != nil {
+		return model.PredictWithUncertainty(predData, level)
+	}
+
+	if model.IsNetwork() {
+		log.Printf("The model holds no covariance information, since the output weights of the network are regularized. The prediction errors are written as NaN\n")
+		return predictionsWithoutUncertainty(model, predData), nil
+	}
+
+	log.Printf("The model holds no covariance information. Reading the training data from %s\n", model.Datafile)
+	data, err := readTrainingData(model)
+	if err != nil {
+		return nil, err
+	}
+	return gafit.GetPredictionsWithLevel(data, model, &predData, level)
+}
+
+// predictMultiTarget writes the predictions of all targets of a multi-target model
+func predictMultiTarget(cmd *cobra.Command, multi gafit.MultiTargetModel, level float64, boot bootstrapSettings) {
+	predDataFile, err := cmd.Flags().GetString("data")
+	if err != nil {
+		log.Fatalf("%s\n", err)
+		return
+	}
+
+	pred := make([][]gafit.Prediction, len(multi.Models))
+	for i, model := range multi.Models {
+		predData, err := gafit.ReadForModel(predDataFile, "", model)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		pred[i], err = modelPredictions(model, predData, level, boot)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+	}
+
+	outfile := predictionFile(predDataFile)
+	if err = gafit.SaveMultiTargetPredictions(outfile, multi.Targets, pred); err != nil {
+		log.Fatalf("%s\n", err)
+		return
+	}
+	log.Printf("Predictions of %d targets for the data in %s is written to %s\n", len(multi.Targets), predDataFile, outfile)
+}
+
+// predictClasses writes the class probabilities predicted by a classification model
+func predictClasses(cmd *cobra.Command, model gafit.Model) {
+	predDataFile, err := cmd.Flags().GetString("data")
+	if err != nil {
+		log.Fatalf("%s\n", err)
+		return
+	}
+
+	predData, err := gafit.ReadForModel(predDataFile, "", model)
+	if err != nil {
+		log.Fatalf("%s\n", err)
+		return
+	}
+
+	outfile := predictionFile(predDataFile)
+	if err = gafit.SaveClassPredictions(outfile, model.Classes, model.PredictProba(predData)); err != nil {
+		log.Fatalf("%s\n", err)
+		return
+	}
+	log.Printf("Class probabilities for the data in %s is written to %s\n", predDataFile, outfile)
+}
+
 func init() {
 	rootCmd.AddCommand(predCmd)
 
 	predCmd.Flags().StringP("model", "m", "", "JSON file holding the model")
 	predCmd.Flags().StringP("data", "d", "", "CSV file with data to predict")
+	predCmd.Flags().Float64("level", gafit.DefaultIntervalLevel, "Level of the confidence and prediction intervals")
+	addBootstrapFlags(predCmd)
+}
+
+// predictionsWithoutUncertainty returns the predictions of the model with NaN as the standard
+// deviation and the bounds of the intervals
+func predictionsWithoutUncertainty(model gafit.Model, data gafit.Dataset) []gafit.Prediction {
+	values := model.Predict(data)
+	nan := math.NaN()
+	res := make([]gafit.Prediction, values.Len())
+	for i := range res {
+		res[i] = gafit.Prediction{Value: values.AtVec(i), Std: nan, ConfLower: nan, ConfUpper: nan, PredLower: nan, PredUpper: nan}
+	}
+	return res
 }
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
 	"log"
-	"math"
+	"os"
 
 	"github.com/davidkleiven/gogafit/gafit"
 	"github.com/spf13/cobra"
@@ -11,7 +13,7 @@ import (
 // rmseCmd represents the rmse command
 var rmseCmd = &cobra.Command{
 	Use:   "rmse",
-	Short: "Calculate RMSE for a model",
+	Short: "Calculate RMSE and other regression metrics for a model",
 	Long: `Calcualte the root mean square error for a given model.
 
 The prediction is given by p = X.dot(c) where X is the design matrix and y is
@@ -48,6 +50,21 @@ data matrix that are not listed, is taken as zero.
 Minimal example:
 
 gogafit rmse -d mydata.csv -c mycoeff,csv
+
+In addition to RMSE, the following metrics of the model on the data are reported: the number of
+rows and parameters, mean absolute error (MAE), maximum absolute error, R^2, adjusted R^2, mean
+absolute percentage error (MAPE, rows where the target is zero are skipped), generalized CV (GCV),
+AICC, BIC and statistics of the residuals (mean, standard deviation, min, median, max, skewness
+and excess kurtosis). The report is written to stdout (or the file given by -o) as a text table,
+or in a machine readable format via --format csv (columns metric and value) or --format json.
+Metrics that are not defined (e.g. GCV when there are more parameters than data points) are
+left empty in the csv file, omitted in the json file and shown as - in the text table.
+
+gogafit rmse -d mydata.csv -m model.json --format json -o metrics.json
+
+For multi-target models (see gogafit fit -h) the metrics of all targets are reported. The text
+table and the csv file then have one column per target, and the json file holds a list with the
+metrics of each target.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		dataFile, err := cmd.Flags().GetString("data")
@@ -62,40 +79,96 @@ gogafit rmse -d mydata.csv -c mycoeff,csv
 			return
 		}
 
-		model, err := gafit.ReadModel(coeffFile)
+		multi, err := gafit.ReadMultiTargetModel(coeffFile)
 		if err != nil {
 			log.Fatalf("%s\n", err)
 			return
 		}
 
-		data, err := gafit.Read(dataFile, model.TargetName)
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
 
+		out, err := cmd.Flags().GetString("out")
 		if err != nil {
 			log.Fatalf("%s\n", err)
+			return
 		}
 
-		coeffs := model.Coeffs
+		metrics := make([]gafit.TargetMetrics, len(multi.Models))
+		for i, model := range multi.Models {
+			if model.IsClassifier() {
+				log.Fatalf("The rmse command does not support classification models\n")
+				return
+			}
+
+			data, err := gafit.ReadForModel(dataFile, model.TargetName, model)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			metrics[i] = gafit.TargetMetrics{
+				Target:            model.TargetName,
+				RegressionMetrics: gafit.NewRegressionMetrics(model, data),
+			}
+		}
 
-		pred := data.Dot(coeffs)
+		var w io.Writer = os.Stdout
+		if out != "" {
+			f, err := os.Create(out)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			defer f.Close()
+			w = f
+		}
 
-		rss := 0.0
-		for i := 0; i < pred.Len(); i++ {
-			rss += math.Pow(pred.AtVec(i)-data.Y.AtVec(i), 2)
+		if len(metrics) == 1 {
+			err = writeRegressionMetrics(w, format, metrics[0].RegressionMetrics)
+		} else {
+			err = writeTargetMetrics(w, format, metrics)
 		}
-		rmse := math.Sqrt(rss / float64(pred.Len()))
-		log.Printf("RMSE: %f\n", rmse)
 
-		// Calculate GCV
-		names := []string{}
-		for k := range coeffs {
-			names = append(names, k)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if out != "" {
+			log.Printf("Metrics written to %s\n", out)
 		}
-		X := data.Submatrix(names)
-		gcv := gafit.GeneralizedCV(rmse, X)
-		log.Printf("Generalized CV (GCV): %f\n", gcv)
 	},
 }
 
+// writeRegressionMetrics writes the metrics of a single target model in the given format
+func writeRegressionMetrics(w io.Writer, format string, metrics gafit.RegressionMetrics) error {
+	switch format {
+	case "text":
+		return metrics.WriteTable(w)
+	case "csv":
+		return metrics.WriteCSV(w)
+	case "json":
+		return metrics.WriteJSON(w)
+	}
+	return fmt.Errorf("Unknown format %s. Must be text, csv or json", format)
+}
+
+// writeTargetMetrics writes the metrics of a multi-target model in the given format
+func writeTargetMetrics(w io.Writer, format string, metrics []gafit.TargetMetrics) error {
+	switch format {
+	case "text":
+		return gafit.WriteTargetMetricsTable(w, metrics)
+	case "csv":
+		return gafit.WriteTargetMetricsCSV(w, metrics)
+	case "json":
+		return gafit.WriteTargetMetricsJSON(w, metrics)
+	}
+	return fmt.Errorf("Unknown format %s. Must be text, csv or json", format)
+}
+
 func init() {
 	rootCmd.AddCommand(rmseCmd)
 
@@ -109,4 +182,6 @@ func init() {
 	// is called directly, e.g.:
 	rmseCmd.Flags().StringP("data", "d", "", "Csv file with data")
 	rmseCmd.Flags().StringP("model", "m", "model.json", "JSON file with fitted model coefficients")
+	rmseCmd.Flags().String("format", "text", "Output format (text, csv or json)")
+	rmseCmd.Flags().StringP("out", "o", "", "File where the metrics are written (default stdout)")
 }
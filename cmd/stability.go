@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/davidkleiven/gogafit/gafit"
+	"github.com/spf13/cobra"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// stabilityCmd represents the stability command
+var stabilityCmd = &cobra.Command{
+	Use:   "stability",
+	Short: "Analyse how robust the selected features are",
+	Long: `Repeats the feature selection of the fit command on resamples of the data and reports how
+often each feature is selected. Features that are selected in most resamples are robust, while
+features that are only selected occasionally depend on the particular data points.
+
+Two resampling methods are supported (--method)
+
+bootstrap - n rows drawn with replacement, where n is the number of rows in the data
+subsample - a fraction (--fraction) of the rows drawn without replacement
+
+Features with a selection frequency of at least --threshold are marked as stable. For
+subsamples of half the data (the default), this is stability selection (Meinshausen and
+Bühlmann, 2010), and for thresholds above 0.5 an upper bound of the expected number of
+falsely selected stable features, q^2/((2*threshold - 1)p), is reported. Here q is the mean
+number of selected features and p is the number of candidate features.
+
+Example:
+
+gogafit stability -d data.csv -y target -n 100 --method subsample --threshold 0.7 --plot stability.png
+
+The genetic algorithm is run with the given settings (number of generations, population size,
+cost function, mutation rate and cross over splits, as in the fit command) for each resample,
+so the analysis takes about n times as long as a single fit. With --plot, a bar chart of the
+selection frequencies is written (png, svg or pdf). With --format json, the result is written in
+JSON format.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dataFile, err := cmd.Flags().GetString("data")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		target, err := cmd.Flags().GetString("target")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		cost, err := cmd.Flags().GetString("cost")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		numGen, err := cmd.Flags().GetUint("numgen")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		popSize, err := cmd.Flags().GetUint("popsize")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		fdratio, err := cmd.Flags().GetFloat64("fdratio")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		mutRate, err := cmd.Flags().GetFloat64("mutrate")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		numSplits, err := cmd.Flags().GetUint("csplits")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		dummy, err := cmd.Flags().GetBool("dummy")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		numResamples, err := cmd.Flags().GetInt("resamples")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		method, err := cmd.Flags().GetString("method")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		fraction, err := cmd.Flags().GetFloat64("fraction")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		threshold, err := cmd.Flags().GetFloat64("threshold")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		seed, err := cmd.Flags().GetInt64("seed")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		out, err := cmd.Flags().GetString("out")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		plotFile, err := cmd.Flags().GetString("plot")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if format != "text" && format != "json" {
+			log.Fatalf("Unknown format %s. Must be text or json\n", format)
+			return
+		}
+
+		target, err = ClosestHeaderName(dataFile, target)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		dataset, err := gafit.ReadWithOptions(dataFile, target, gafit.ReadOptions{DropFirst: dummy})
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+
+		settings := gaSettings{
+			popSize:   popSize,
+			numGen:    numGen,
+			mutRate:   mutRate,
+			numSplits: numSplits,
+			fdratio:   fdratio,
+			cost:      cost,
+		}
+
+		resample := 0
+		selector := func(data gafit.Dataset, rng *rand.Rand) ([]string, error) {
+			factory := newFactory(data, settings)
+			if factory.Config.LargestModel() < 1 {
+				return nil, fmt.Errorf("%d rows are too few to select features with fdratio %g", data.NumData(), fdratio)
+			}
+
+			best, err := minimize(factory, popSize, numGen, nil, rng)
+			if err != nil {
+				return nil, err
+			}
+
+			resample++
+			model := gafit.NewModel(best, data, cost, dataFile)
+			log.Printf("Resample %d of %d: %d features selected\n", resample, numResamples, len(model.Coeffs))
+			return model.Features(), nil
+		}
+
+		result, err := gafit.StabilityAnalysis(dataset, selector, gafit.StabilityConfig{
+			Method:       method,
+			NumResamples: numResamples,
+			Fraction:     fraction,
+			Threshold:    threshold,
+			Seed:         seed,
+		})
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		var w io.Writer = os.Stdout
+		if out != "" {
+			f, err := os.Create(out)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if format == "json" {
+			err = result.WriteJSON(w)
+		} else {
+			err = result.WriteTable(w)
+		}
+
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if plotFile != "" {
+			if err := stabilityPlot(result, plotFile); err != nil {
+				log.Fatalf("Error while saving plot %s\n", err)
+				return
+			}
+			log.Printf("Plot saved to %s\n", plotFile)
+		}
+	},
+}
+
+// stabilityPlot creates a bar chart of the selection frequencies with a line at the threshold
+func stabilityPlot(result gafit.StabilityResult, out string) error {
+	plt := plot.New()
+	plt.Y.Label.Text = "Selection frequency"
+	plt.Y.Min = 0.0
+	plt.Y.Max = 1.0
+
+	colors := JosephAndHisBrothers()
+	bars, err := plotter.NewBarChart(plotter.Values(result.Frequencies()), vg.Points(12))
+	if err != nil {
+		return err
+	}
+	bars.Color = colors.Get(0)
+	bars.LineStyle.Width = 0
+	plt.Add(bars)
+	plt.NominalX(result.Features...)
+	plt.X.Tick.Label.Rotation = 1.2
+	plt.X.Tick.Label.XAlign = -1.0
+
+	threshold, err := plotter.NewLine(plotter.XYs{{X: -0.5, Y: result.Threshold}, {X: float64(len(result.Features)) - 0.5, Y: result.Threshold}})
+	if err != nil {
+		return err
+	}
+	threshold.LineStyle.Color = colors.Get(2)
+	threshold.LineStyle.Dashes = []vg.Length{vg.Points(4), vg.Points(2)}
+	plt.Add(threshold)
+
+	width := vg.Length(len(result.Features)) * vg.Points(18)
+	if width < 4*vg.Inch {
+		width = 4 * vg.Inch
+	}
+	return plt.Save(width, 4*vg.Inch, out)
+}
+
+func init() {
+	rootCmd.AddCommand(stabilityCmd)
+
+	stabilityCmd.Flags().StringP("data", "d", "", "Datafile. Should be stored in CSV format")
+	stabilityCmd.Flags().StringP("target", "y", "lastCol", "Name of the column used as target")
+	stabilityCmd.Flags().StringP("cost", "c", "aicc", "Cost function (aic|aicc|bic|ebic)")
+	stabilityCmd.Flags().UintP("numgen", "g", 50, "Number of generations of each GA run")
+	stabilityCmd.Flags().UintP("popsize", "p", 30, "Population size")
+	stabilityCmd.Flags().Float64P("fdratio", "f", 0.8, "Maximum ratio between number of selected features and number of data points")
+	stabilityCmd.Flags().Float64P("mutrate", "m", 0.5, "Mutation rate in genetic algorithm")
+	stabilityCmd.Flags().UintP("csplits", "s", 2, "Number of splits used for cross over operations")
+	stabilityCmd.Flags().Bool("dummy", false, "Use dummy encoding with a reference level for categorical columns instead of one-hot encoding")
+	stabilityCmd.Flags().IntP("resamples", "n", 50, "Number of resamples")
+	stabilityCmd.Flags().String("method", gafit.SubsampleResampling, "Resampling method (bootstrap or subsample)")
+	stabilityCmd.Flags().Float64("fraction", 0.5, "Fraction of the rows in each subsample")
+	stabilityCmd.Flags().Float64("threshold", 0.6, "Selection frequency above which a feature is stable")
+	stabilityCmd.Flags().Int64("seed", 0, "Seed of the resampling and the GA runs (default based on the current time)")
+	stabilityCmd.Flags().String("format", "text", "Output format (text or json)")
+	stabilityCmd.Flags().StringP("out", "o", "", "File where the result is written (default stdout)")
+	stabilityCmd.Flags().String("plot", "", "Image file with a bar chart of the selection frequencies")
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/davidkleiven/gogafit/gafit"
+	"github.com/spf13/cobra"
+)
+
+// summaryCmd represents the summary command
+var summaryCmd = &cobra.Command{
+	Use:   "summary",
+	Short: "Print a regression table with coefficient statistics",
+	Long: `Prints a regression table of a fitted model in the style of R. For each coefficient, the
+table holds
+
+Estimate   - the value of the coefficient
+Std. Error - the standard error (square root of the diagonal of the coefficient covariance)
+t value    - the estimate divided by the standard error
+Pr(>|t|)   - the two-sided p-value of the t-test of the coefficient being zero, followed by
+             its significance code
+CI         - the confidence interval at the level given by --level
+VIF        - the variance inflation factor 1/(1 - R^2), where R^2 is found by regressing the
+             feature on the other features. Values above 5-10 indicate that the feature is
+             strongly correlated with the other features
+
+Below the table, the residual standard error, R^2, adjusted R^2 and the F-statistic testing if
+any coefficient other than the constant term is non-zero are shown. If the model has no
+intercept or constant feature, R^2 and the F-statistic are calculated relative to zero.
+
+The statistics are calculated from the training data of the model, which by default is read
+from the datafile stored in the model (override with -d). If the training data is not
+available, the covariance embedded in the model is used, and VIF, R^2 and the F-statistic are
+omitted.
+
+Example:
+
+gogafit summary -m model.json
+gogafit summary -m model.json -d data.csv --level 0.9
+
+For multi-target models (see gogafit fit -h), the target is selected with -y.
+
+With --bootstrap n, the model is refitted to n bootstrap resamples of the training data, and
+the bootstrap standard error and percentile interval of each coefficient are shown below the
+table. The bootstrap does not rely on normally distributed residuals. Two resampling methods
+are supported (--bootstrap-method)
+
+case     - rows of the training data drawn with replacement
+residual - the fitted values plus residuals of the fit drawn with replacement
+
+By default, the selected features are kept fixed and only the coefficients are refitted. With
+--reselect, the genetic algorithm of the fit command is run on each resample (with the cost
+function and standardization of the model, and the GA settings --numgen, --popsize, --mutrate,
+--csplits and --fdratio), such that the intervals also account for the uncertainty in the
+feature selection. A coefficient is then zero in the resamples where the
+feature is not selected, and the fraction of resamples where it is selected is shown. The
+resamples are fitted in parallel (--workers). For a given --seed, the result is reproducible
+and does not depend on the number of workers, also with --reselect.
+
+gogafit summary -m model.json --bootstrap 1000
+gogafit summary -m model.json --bootstrap 100 --reselect --numgen 30
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		modelFile, err := cmd.Flags().GetString("model")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		dataFile, err := cmd.Flags().GetString("data")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		target, err := cmd.Flags().GetString("target")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		level, err := cmd.Flags().GetFloat64("level")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		boot, err := readBootstrapSettings(cmd)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		multi, err := gafit.ReadMultiTargetModel(modelFile)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		model := multi.Models[0]
+		if target != "" {
+			model, err = multi.Model(target)
+		} else if len(multi.Models) > 1 {
+			err = fmt.Errorf("%s holds a multi-target model. Select the target with -y (one of %s)", modelFile, strings.Join(multi.Targets, ", "))
+		}
+
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if model.IsNetwork() && model.Inference == nil {
+			log.Fatalf("The model holds no covariance information, since the output weights of the network are regularized\n")
+			return
+		}
+
+		var data *gafit.Dataset
+		if dataFile != "" {
+			d, err := gafit.ReadForModel(dataFile, model.TargetName, model)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			data = &d
+		} else if d, err := readTrainingData(model); err == nil {
+			data = &d
+		} else if model.Inference != nil && boot.numResamples == 0 {
+			log.Printf("%s. Using the covariance stored in the model\n", err)
+		} else {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		summary, err := gafit.NewModelSummary(model, data, level)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if boot.numResamples > 0 {
+			b, err := boot.run(model, *data)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			summary.AddBootstrap(b)
+		}
+
+		if err := summary.WriteTable(os.Stdout); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(summaryCmd)
+
+	summaryCmd.Flags().StringP("model", "m", "model.json", "JSON file with the model")
+	summaryCmd.Flags().StringP("data", "d", "", "Csv file with the training data (default the datafile of the model)")
+	summaryCmd.Flags().StringP("target", "y", "", "Target to summarize from a multi-target model")
+	summaryCmd.Flags().Float64("level", gafit.DefaultIntervalLevel, "Level of the confidence intervals")
+	addBootstrapFlags(summaryCmd)
+}
@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"image/color"
 	"io"
+	"log"
 	"os"
 	"strconv"
 	"strings"
 
+	"github.com/davidkleiven/gogafit/gafit"
 	"gonum.org/v1/plot/vg/draw"
 )
 
@@ -38,6 +40,30 @@ func ClosestHeaderName(fname string, name string) (string, error) {
 	return "", errors.New(msg)
 }
 
+// inputPipeline returns the pipeline that produced datafile. If there is none, a new pipeline
+// starting from the raw data is returned
+func inputPipeline(datafile string, data gafit.Dataset) (gafit.Pipeline, error) {
+	p, exists, err := gafit.ReadPipelineIfExists(datafile)
+	if err != nil {
+		return p, err
+	}
+
+	if !exists {
+		p.Categories = data.Categories
+	}
+	return p, nil
+}
+
+// saveOutputPipeline appends step to the pipeline and stores it alongside outfile
+func saveOutputPipeline(outfile string, p gafit.Pipeline, step gafit.PipelineStep) error {
+	fname := gafit.PipelineFile(outfile)
+	if err := gafit.SavePipeline(fname, p.Append(step)); err != nil {
+		return err
+	}
+	log.Printf("Transformation pipeline written to %s\n", fname)
+	return nil
+}
+
 // ReadCoeffs return a map with the coefficients
 func ReadCoeffs(fname string) (map[string]float64, error) {
 	f, err := os.Open(fname)
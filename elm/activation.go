@@ -1,6 +1,10 @@
 package elm
 
-import "math"
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
 
 // ActivationFunc function is a function that takes a value as input and returns a float
 // value corresponding to the output of a neuron
@@ -18,3 +22,77 @@ func Relu(x float64) float64 {
 	}
 	return x
 }
+
+// Tanh is the hyperbolic tangent
+func Tanh(x float64) float64 {
+	return math.Tanh(x)
+}
+
+// Gaussian is the radial basis function e^{-x^2}
+func Gaussian(x float64) float64 {
+	return math.Exp(-x * x)
+}
+
+// Sine is sin(x)
+func Sine(x float64) float64 {
+	return math.Sin(x)
+}
+
+// Softplus is a smooth version of the rectifier given by log(1 + e^x)
+func Softplus(x float64) float64 {
+	if x > 0.0 {
+		return x + math.Log1p(math.Exp(-x))
+	}
+	return math.Log1p(math.Exp(x))
+}
+
+// LeakyReluSlope is the slope of LeakyRelu for negative arguments
+const LeakyReluSlope = 0.01
+
+// LeakyRelu is a rectifier that returns LeakyReluSlope*x for negative x
+func LeakyRelu(x float64) float64 {
+	if x < 0.0 {
+		return LeakyReluSlope * x
+	}
+	return x
+}
+
+// HardLimit is a step function returning 1 if x >= 0 and 0 otherwise
+func HardLimit(x float64) float64 {
+	if x < 0.0 {
+		return 0.0
+	}
+	return 1.0
+}
+
+// Activations maps names to the known activation functions. The names are used when
+// neurons are serialized
+var Activations = map[string]ActivationFunc{
+	"sigmoid":   Sigmoid,
+	"relu":      Relu,
+	"tanh":      Tanh,
+	"gaussian":  Gaussian,
+	"sine":      Sine,
+	"softplus":  Softplus,
+	"leakyrelu": LeakyRelu,
+	"hardlimit": HardLimit,
+}
+
+// ActivationName returns the name of a known activation function
+func ActivationName(a ActivationFunc) (string, error) {
+	ptr := reflect.ValueOf(a).Pointer()
+	for name, f := range Activations {
+		if reflect.ValueOf(f).Pointer() == ptr {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("Unknown activation function. Only functions listed in Activations can be serialized")
+}
+
+// GetActivation returns the activation function with the given name
+func GetActivation(name string) (ActivationFunc, error) {
+	if f, ok := Activations[name]; ok {
+		return f, nil
+	}
+	return nil, fmt.Errorf("Unknown activation function %s", name)
+}
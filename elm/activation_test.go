@@ -0,0 +1,55 @@
+package elm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestActivations(t *testing.T) {
+	tol := 1e-10
+	for i, test := range []struct {
+		f    ActivationFunc
+		x    float64
+		want float64
+	}{
+		{f: Tanh, x: 0.5, want: math.Tanh(0.5)},
+		{f: Gaussian, x: 2.0, want: math.Exp(-4.0)},
+		{f: Sine, x: 1.0, want: math.Sin(1.0)},
+		{f: Softplus, x: 0.0, want: math.Log(2.0)},
+		{f: Softplus, x: 800.0, want: 800.0},
+		{f: Softplus, x: -800.0, want: 0.0},
+		{f: LeakyRelu, x: -2.0, want: -2.0 * LeakyReluSlope},
+		{f: LeakyRelu, x: 2.0, want: 2.0},
+		{f: HardLimit, x: -0.1, want: 0.0},
+		{f: HardLimit, x: 0.0, want: 1.0},
+	} {
+		if got := test.f(test.x); math.Abs(got-test.want) > tol {
+			t.Errorf("Test #%d: Want %f got %f\n", i, test.want, got)
+		}
+	}
+}
+
+func TestAllActivationsHaveNames(t *testing.T) {
+	for name, f := range Activations {
+		got, err := ActivationName(f)
+		if err != nil {
+			t.Errorf("%s: %s\n", name, err)
+			continue
+		}
+		if got != name {
+			t.Errorf("Want %s got %s\n", name, got)
+		}
+	}
+}
+
+func TestNeuronBias(t *testing.T) {
+	neuron := Neuron{
+		Weights:        []float64{1.0, -1.0},
+		Bias:           0.5,
+		ActivationFunc: Relu,
+	}
+
+	if got := neuron.Activation([]float64{1.0, 1.0}); math.Abs(got-0.5) > 1e-10 {
+		t.Errorf("Want 0.5 got %f\n", got)
+	}
+}
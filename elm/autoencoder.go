@@ -0,0 +1,38 @@
+package elm
+
+import (
+	"gonum.org/v1/gonum/mat"
+)
+
+// TrainAutoencoder turns a randomly initialized layer into an ELM autoencoder layer. The
+// output weights B of the random layer are fitted by ridge regression such that the hidden
+// layer reconstructs its (scaled) input, G*B = X. The returned layer uses the rows of B as
+// input weights, such that its output g(X*B^T) is a learned representation of X. Stacking
+// several such layers gives a multi-layer ELM.
+func TrainAutoencoder(X *mat.Dense, random Layer, lambda float64) (Layer, error) {
+	target := X
+	if random.Scaling != nil {
+		target = random.Scaling.Apply(X)
+	}
+
+	G := random.Output(X)
+	B, err := RidgeRegressionMatrix(G, target, lambda)
+	if err != nil {
+		return random, err
+	}
+
+	ae := Layer{
+		Inputs:  random.Inputs,
+		Names:   random.Names,
+		Neurons: make([]Neuron, len(random.Neurons)),
+		Scaling: random.Scaling,
+		Init:    "autoencoder",
+	}
+	for i := range ae.Neurons {
+		ae.Neurons[i] = Neuron{
+			Weights:        mat.Row(nil, i, B),
+			ActivationFunc: random.Neurons[i].ActivationFunc,
+		}
+	}
+	return ae, nil
+}
@@ -0,0 +1,57 @@
+package elm
+
+import (
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestTrainAutoencoder(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	X := mat.NewDense(50, 2, nil)
+	for i := 0; i < 50; i++ {
+		X.Set(i, 0, 100.0*rng.NormFloat64())
+		X.Set(i, 1, rng.Float64())
+	}
+
+	scaling, err := NewInputScaling(X, "standard")
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	random := Layer{Inputs: []string{"x1", "x2"}, Scaling: &scaling}
+	for i := 0; i < 30; i++ {
+		random.Neurons = append(random.Neurons, RandomNeuronFactory(2, rng, Sigmoid))
+		random.Names = append(random.Names, "sigmoid")
+	}
+
+	ae, err := TrainAutoencoder(X, random, 1e-8)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	if ae.Init != "autoencoder" || ae.Scaling != random.Scaling || len(ae.Neurons) != 30 {
+		t.Errorf("Unexpected autoencoder layer %v\n", ae)
+	}
+
+	// The input weights of the autoencoder are the output weights B of the random layer,
+	// and G*B should reconstruct the scaled input
+	B := mat.NewDense(30, 2, nil)
+	for i, n := range ae.Neurons {
+		B.SetRow(i, n.Weights)
+	}
+
+	var reconstructed mat.Dense
+	reconstructed.Mul(random.Output(X), B)
+	if !mat.EqualApprox(&reconstructed, scaling.Apply(X), 1e-3) {
+		t.Errorf("The autoencoder does not reconstruct its input\n")
+	}
+
+	r, c := ae.Output(X).Dims()
+	if r != 50 || c != 30 {
+		t.Errorf("Expected output of size 50x30, got %dx%d\n", r, c)
+	}
+}
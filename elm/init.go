@@ -0,0 +1,110 @@
+package elm
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// InitSchemes holds the names of the supported weight initialization schemes
+//
+// normal: weights and bias drawn from a standard normal distribution
+//
+// uniform: weights and bias drawn uniformly from [-1, 1]
+//
+// xavier: weights drawn uniformly from [-a, a] with a = sqrt(6/(n+1)), where n is the number of inputs
+//
+// he: weights drawn from a normal distribution with standard deviation sqrt(2/n)
+//
+// data: the bias is chosen such that the transition of the activation function passes through
+// a randomly sampled input row, and the weights are scaled such that the pre-activation has unit
+// standard deviation over the sampled rows
+var InitSchemes = []string{"normal", "uniform", "xavier", "he", "data"}
+
+// dataInitSamples is the maximum number of rows used to calculate the spread of the
+// pre-activation in the data-driven initialization
+const dataInitSamples = 100
+
+// InitNeuron creates a neuron initialized by the given scheme (see InitSchemes). X holds the
+// (scaled) input rows. It is only used by the data-driven scheme, where it must hold at least
+// one row. The xavier and he schemes only initialize the weights and leave the bias at 0.
+func InitNeuron(scheme string, n int, X *mat.Dense, rng *rand.Rand, a ActivationFunc) (Neuron, error) {
+	neuron := Neuron{
+		Weights:        make([]float64, n),
+		ActivationFunc: a,
+	}
+
+	switch scheme {
+	case "normal":
+		return RandomNeuronFactory(n, rng, a), nil
+	case "uniform":
+		for i := range neuron.Weights {
+			neuron.Weights[i] = 2.0*rng.Float64() - 1.0
+		}
+		neuron.Bias = 2.0*rng.Float64() - 1.0
+	case "xavier":
+		limit := math.Sqrt(6.0 / float64(n+1))
+		for i := range neuron.Weights {
+			neuron.Weights[i] = limit * (2.0*rng.Float64() - 1.0)
+		}
+	case "he":
+		if n < 1 {
+			return neuron, fmt.Errorf("The he initialization requires at least one input")
+		}
+		std := math.Sqrt(2.0 / float64(n))
+		for i := range neuron.Weights {
+			neuron.Weights[i] = std * rng.NormFloat64()
+		}
+	case "data":
+		if X == nil {
+			return neuron, fmt.Errorf("The data-driven initialization requires input data")
+		}
+		if r, _ := X.Dims(); r == 0 {
+			return neuron, fmt.Errorf("The data-driven initialization requires at least one input row")
+		}
+		dataDrivenInit(&neuron, X, rng)
+	default:
+		return neuron, fmt.Errorf("Unknown initialization scheme %s. Must be one of %v", scheme, InitSchemes)
+	}
+	return neuron, nil
+}
+
+// dataDrivenInit draws a random direction for the weights and places the transition of the
+// activation at a randomly selected row in X. The weights and bias are then rescaled such that
+// the pre-activation w.x + b has unit standard deviation over (a sample of) the rows in X
+func dataDrivenInit(neuron *Neuron, X *mat.Dense, rng *rand.Rand) {
+	r, c := X.Dims()
+	if c != len(neuron.Weights) {
+		panic("The number of columns in X does not match the number of weights")
+	}
+
+	for i := range neuron.Weights {
+		neuron.Weights[i] = rng.NormFloat64()
+	}
+	neuron.Bias = -Dot(neuron.Weights, X.RawRowView(rng.Intn(r)))
+
+	num := r
+	if num > dataInitSamples {
+		num = dataInitSamples
+	}
+	preActivation := make([]float64, num)
+	for i := range preActivation {
+		row := i
+		if r > dataInitSamples {
+			row = rng.Intn(r)
+		}
+		preActivation[i] = Dot(neuron.Weights, X.RawRowView(row)) + neuron.Bias
+	}
+
+	std := stat.StdDev(preActivation, nil)
+	if !(std > 0.0) || math.IsInf(std, 0) {
+		return
+	}
+	for i := range neuron.Weights {
+		neuron.Weights[i] /= std
+	}
+	neuron.Bias /= std
+}
@@ -0,0 +1,73 @@
+package elm
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+func TestInitNeuron(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	X := mat.NewDense(200, 2, nil)
+	for i := 0; i < 200; i++ {
+		X.Set(i, 0, 1000.0+100.0*rng.NormFloat64())
+		X.Set(i, 1, rng.Float64())
+	}
+
+	for i, test := range []struct {
+		scheme string
+		limit  float64
+	}{
+		{scheme: "uniform", limit: 1.0},
+		{scheme: "xavier", limit: math.Sqrt(2.0)},
+	} {
+		for j := 0; j < 20; j++ {
+			n, err := InitNeuron(test.scheme, 2, nil, rng, Sigmoid)
+			if err != nil {
+				t.Errorf("Test #%d: %s\n", i, err)
+				break
+			}
+			for _, w := range append(n.Weights, n.Bias) {
+				if math.Abs(w) > test.limit {
+					t.Errorf("Test #%d: weight %f outside [-%f, %f]\n", i, w, test.limit, test.limit)
+				}
+			}
+		}
+	}
+
+	// The pre-activation of data-driven neurons should have unit spread over the data
+	for j := 0; j < 10; j++ {
+		n, err := InitNeuron("data", 2, X, rng, Sigmoid)
+		if err != nil {
+			t.Errorf("%s\n", err)
+			return
+		}
+
+		pre := make([]float64, 200)
+		for i := range pre {
+			pre[i] = Dot(n.Weights, X.RawRowView(i)) + n.Bias
+		}
+		if std := stat.StdDev(pre, nil); math.Abs(std-1.0) > 0.3 {
+			t.Errorf("Expected standard deviation of the pre-activation close to 1. Got %f\n", std)
+		}
+	}
+
+	if _, err := InitNeuron("data", 2, nil, rng, Sigmoid); err == nil {
+		t.Errorf("Expected error when data-driven initialization has no data\n")
+	}
+
+	if _, err := InitNeuron("data", 2, &mat.Dense{}, rng, Sigmoid); err == nil {
+		t.Errorf("Expected error when data-driven initialization has no rows\n")
+	}
+
+	if _, err := InitNeuron("he", 0, nil, rng, Sigmoid); err == nil {
+		t.Errorf("Expected error for he initialization without inputs\n")
+	}
+
+	if _, err := InitNeuron("unknown", 2, nil, rng, Sigmoid); err == nil {
+		t.Errorf("Expected error for unknown scheme\n")
+	}
+}
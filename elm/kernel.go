@@ -0,0 +1,184 @@
+package elm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// KernelTypes holds the names of the supported kernels
+//
+// rbf: exp(-gamma*|x - c|^2)
+//
+// poly: (gamma*x.c + coef0)^degree
+var KernelTypes = []string{"rbf", "poly"}
+
+// Kernel represents the hidden layer of a kernel ELM. Instead of random neurons, the hidden
+// layer has one output per training row (center), given by the kernel function evaluated
+// between the input and the center.
+type Kernel struct {
+	Type   string
+	Gamma  float64
+	Degree int     `json:",omitempty"`
+	Coef0  float64 `json:",omitempty"`
+
+	// Inputs holds the names of the input columns and Names the name of each kernel column
+	Inputs []string
+	Names  []string
+
+	// Centers holds the (scaled) training rows
+	Centers [][]float64
+
+	// Scaling is applied to the inputs before the kernel is evaluated
+	Scaling *InputScaling `json:",omitempty"`
+}
+
+// NewKernel creates a kernel where the rows of X are used as centers. The columns of the
+// kernel are named k0, k1, ... If gamma is zero, 1/(number of inputs) is used.
+func NewKernel(kernelType string, inputs []string, X *mat.Dense, scaling *InputScaling, gamma float64, degree int, coef0 float64) (Kernel, error) {
+	k := Kernel{
+		Type:    kernelType,
+		Gamma:   gamma,
+		Inputs:  inputs,
+		Scaling: scaling,
+	}
+
+	switch kernelType {
+	case "rbf":
+	case "poly":
+		if degree < 1 {
+			return k, errors.New("The degree of the polynomial kernel must be at least 1")
+		}
+		k.Degree = degree
+		k.Coef0 = coef0
+	default:
+		return k, fmt.Errorf("Unknown kernel %s. Must be one of %v", kernelType, KernelTypes)
+	}
+
+	r, c := X.Dims()
+	if k.Gamma == 0.0 {
+		k.Gamma = 1.0 / float64(c)
+	}
+
+	if scaling != nil {
+		X = scaling.Apply(X)
+	}
+	k.Centers = make([][]float64, r)
+	k.Names = make([]string, r)
+	for i := range k.Centers {
+		k.Centers[i] = mat.Row(nil, i, X)
+		k.Names[i] = fmt.Sprintf("k%d", i)
+	}
+	return k, nil
+}
+
+// Eval evaluates the kernel between two (scaled) rows
+func (k Kernel) Eval(a, b []float64) float64 {
+	if k.Type == "poly" {
+		return math.Pow(k.Gamma*Dot(a, b)+k.Coef0, float64(k.Degree))
+	}
+
+	dist := 0.0
+	for i := range a {
+		dist += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return math.Exp(-k.Gamma * dist)
+}
+
+// Output calculates the kernel between each row in X and each of the centers
+func (k Kernel) Output(X *mat.Dense) *mat.Dense {
+	if k.Scaling != nil {
+		X = k.Scaling.Apply(X)
+	}
+
+	r, c := X.Dims()
+	for _, center := range k.Centers {
+		if len(center) != c {
+			panic("The number of columns in X does not match the dimension of the kernel centers")
+		}
+	}
+
+	K := mat.NewDense(r, len(k.Centers), nil)
+	for i := 0; i < r; i++ {
+		row := K.RawRowView(i)
+		x := X.RawRowView(i)
+		for j, center := range k.Centers {
+			row[j] = k.Eval(x, center)
+		}
+	}
+	return K
+}
+
+// Subset returns a new kernel holding only the centers with the given names
+func (k Kernel) Subset(names []string) (Kernel, error) {
+	idx := make(map[string]int)
+	for i, n := range k.Names {
+		idx[n] = i
+	}
+
+	res := k
+	res.Names = nil
+	res.Centers = nil
+	for _, n := range names {
+		i, ok := idx[n]
+		if !ok {
+			return res, fmt.Errorf("No kernel center named %s", n)
+		}
+		res.Names = append(res.Names, n)
+		res.Centers = append(res.Centers, k.Centers[i])
+	}
+	return res, nil
+}
+
+// KernelModel is a kernel ELM. The prediction is given by K(x)*w, where K(x) holds the kernel
+// between x and each of the centers.
+type KernelModel struct {
+	Kernel  Kernel
+	Weights []float64
+
+	// Regularization is added to the diagonal of the kernel matrix in Train
+	Regularization float64
+}
+
+// Train solves (K + lambda*I)w = y, where K is the kernel matrix of the training data. X must
+// hold the rows used as centers in the kernel
+func (m *KernelModel) Train(X *mat.Dense, y *mat.VecDense) error {
+	r, _ := X.Dims()
+	if r != y.Len() || r != len(m.Kernel.Centers) {
+		return errors.New("The number of rows in X must match the length of y and the number of kernel centers")
+	}
+
+	if m.Regularization < 0.0 {
+		return errors.New("Regularization must be non-negative")
+	}
+
+	K := m.Kernel.Output(X)
+	for i := 0; i < r; i++ {
+		K.Set(i, i, K.At(i, i)+m.Regularization)
+	}
+
+	// An ill-conditioned kernel matrix is reported as mat.Condition, but the solution is still
+	// usable. Increase the regularization to improve the conditioning
+	var w mat.VecDense
+	if err := w.SolveVec(K, y); err != nil {
+		if _, illConditioned := err.(mat.Condition); !illConditioned {
+			return err
+		}
+	}
+	m.Weights = w.RawVector().Data
+	return nil
+}
+
+// Predict returns the output of the kernel ELM for each row in X
+func (m KernelModel) Predict(X *mat.Dense) *mat.VecDense {
+	if len(m.Weights) != len(m.Kernel.Centers) {
+		panic("The number of weights does not match the number of kernel centers. Has the model been trained?")
+	}
+	K := m.Kernel.Output(X)
+	r, _ := K.Dims()
+	pred := mat.NewVecDense(r, nil)
+	pred.MulVec(K, mat.NewVecDense(len(m.Weights), m.Weights))
+	return pred
+}
@@ -0,0 +1,89 @@
+package elm
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestKernelEval(t *testing.T) {
+	for i, test := range []struct {
+		kernel Kernel
+		a, b   []float64
+		want   float64
+	}{
+		{
+			kernel: Kernel{Type: "rbf", Gamma: 0.5},
+			a:      []float64{1.0, 2.0},
+			b:      []float64{0.0, 0.0},
+			want:   math.Exp(-2.5),
+		},
+		{
+			kernel: Kernel{Type: "poly", Gamma: 2.0, Degree: 2, Coef0: 1.0},
+			a:      []float64{1.0, 2.0},
+			b:      []float64{3.0, -1.0},
+			want:   9.0,
+		},
+	} {
+		got := test.kernel.Eval(test.a, test.b)
+		if math.Abs(got-test.want) > 1e-10 {
+			t.Errorf("Test #%d: Want %f got %f\n", i, test.want, got)
+		}
+	}
+}
+
+func TestKernelModelInterpolates(t *testing.T) {
+	X := mat.NewDense(4, 1, []float64{0.0, 1.0, 2.0, 3.0})
+	y := mat.NewVecDense(4, []float64{1.0, -1.0, 2.0, 0.5})
+
+	for i, kernelType := range KernelTypes {
+		kernel, err := NewKernel(kernelType, []string{"x"}, X, nil, 0.0, 3, 1.0)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+
+		if kernel.Gamma != 1.0 {
+			t.Errorf("Test #%d: Expected default gamma 1, got %f\n", i, kernel.Gamma)
+		}
+
+		model := KernelModel{Kernel: kernel}
+		if err := model.Train(X, y); err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+
+		pred := model.Predict(X)
+		if !mat.EqualApprox(pred, y, 1e-6) {
+			t.Errorf("Test #%d: Want\n%v\ngot\n%v\n", i, mat.Formatted(y), mat.Formatted(pred))
+		}
+	}
+
+	if _, err := NewKernel("unknown", []string{"x"}, X, nil, 0.0, 3, 1.0); err == nil {
+		t.Errorf("Expected error for unknown kernel\n")
+	}
+}
+
+func TestKernelSubset(t *testing.T) {
+	X := mat.NewDense(3, 1, []float64{0.0, 1.0, 2.0})
+	kernel, err := NewKernel("rbf", []string{"x"}, X, nil, 1.0, 0, 0.0)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	sub, err := kernel.Subset([]string{"k2"})
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	if len(sub.Centers) != 1 || sub.Centers[0][0] != 2.0 || len(kernel.Centers) != 3 {
+		t.Errorf("Unexpected subset %v\n", sub)
+	}
+
+	if _, err := kernel.Subset([]string{"k3"}); err == nil {
+		t.Errorf("Expected error for unknown center\n")
+	}
+}
@@ -0,0 +1,89 @@
+package elm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Layer is a hidden layer in an extreme learning machine. Inputs holds the names of the
+// input columns (in the order expected by the weights) and Names holds the name of the
+// output of each neuron
+type Layer struct {
+	Inputs  []string
+	Names   []string
+	Neurons []Neuron
+
+	// Scaling is applied to the inputs before they are passed to the neurons
+	Scaling *InputScaling `json:",omitempty"`
+
+	// Init is the name of the scheme used to initialize the weights (see InitSchemes)
+	Init string `json:",omitempty"`
+}
+
+// Output calculates the output of the layer for each row in X (see HiddenLayerMatrix)
+func (l Layer) Output(X *mat.Dense) *mat.Dense {
+	if l.Scaling != nil {
+		X = l.Scaling.Apply(X)
+	}
+	return HiddenLayerMatrix(X, l.Neurons)
+}
+
+// Subset returns a new layer holding only the neurons with the given names
+func (l Layer) Subset(names []string) (Layer, error) {
+	idx := make(map[string]int)
+	for i, n := range l.Names {
+		idx[n] = i
+	}
+
+	res := Layer{Inputs: l.Inputs, Scaling: l.Scaling, Init: l.Init}
+	for _, n := range names {
+		i, ok := idx[n]
+		if !ok {
+			return res, fmt.Errorf("No neuron named %s in the layer", n)
+		}
+		res.Names = append(res.Names, n)
+		res.Neurons = append(res.Neurons, l.Neurons[i])
+	}
+	return res, nil
+}
+
+// SaveLayer writes the layer to a JSON file
+func SaveLayer(fname string, l Layer) error {
+	serialized, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, serialized, 0644)
+}
+
+// ReadLayer reads a layer from a JSON file
+func ReadLayer(fname string) (Layer, error) {
+	var l Layer
+	f, err := os.Open(fname)
+	if err != nil {
+		return l, err
+	}
+	defer f.Close()
+
+	bytes, err := ioutil.ReadAll(f)
+	if err != nil {
+		return l, err
+	}
+
+	if err = json.Unmarshal(bytes, &l); err != nil {
+		return l, err
+	}
+
+	if len(l.Names) != len(l.Neurons) {
+		return l, fmt.Errorf("The layer has %d neurons but %d names", len(l.Neurons), len(l.Names))
+	}
+
+	if l.Scaling != nil && (len(l.Scaling.Offsets) != len(l.Inputs) || len(l.Scaling.Scales) != len(l.Inputs)) {
+		return l, fmt.Errorf("The input scaling does not match the %d inputs of the layer", len(l.Inputs))
+	}
+	return l, nil
+}
@@ -0,0 +1,62 @@
+package elm
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestLayerSaveRead(t *testing.T) {
+	layer := Layer{
+		Inputs: []string{"x1", "x2"},
+		Names:  []string{"relu0", "sigmoid0"},
+		Neurons: []Neuron{
+			{Weights: []float64{1.0, -1.0}, ActivationFunc: Relu},
+			{Weights: []float64{0.0, -0.2}, ActivationFunc: Sigmoid},
+		},
+		Scaling: &InputScaling{Method: "standard", Offsets: []float64{1.0, 2.0}, Scales: []float64{2.0, 0.5}},
+		Init:    "he",
+	}
+
+	fname := "layerRoundTrip.json"
+	defer os.Remove(fname)
+	if err := SaveLayer(fname, layer); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	read, err := ReadLayer(fname)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	if !reflect.DeepEqual(read.Inputs, layer.Inputs) || !reflect.DeepEqual(read.Names, layer.Names) ||
+		!reflect.DeepEqual(read.Scaling, layer.Scaling) || read.Init != layer.Init {
+		t.Errorf("Want\n%v\ngot\n%v\n", layer, read)
+	}
+
+	X := mat.NewDense(2, 2, []float64{1.0, 2.0, 4.0, 3.0})
+	want := layer.Output(X)
+	got := read.Output(X)
+	if !mat.EqualApprox(want, got, 1e-10) {
+		t.Errorf("Want\n%v\ngot\n%v\n", mat.Formatted(want), mat.Formatted(got))
+	}
+}
+
+func TestReadLayerNameMismatch(t *testing.T) {
+	fname := "layerMismatch.json"
+	defer os.Remove(fname)
+	content := `{"Inputs": ["x1"], "Names": [], "Neurons": [{"Weights": [1.0], "Activation": "relu"}]}`
+	if err := ioutil.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	if _, err := ReadLayer(fname); err == nil {
+		t.Errorf("Expected error when the number of names does not match the number of neurons\n")
+	}
+}
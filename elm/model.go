@@ -0,0 +1,99 @@
+package elm
+
+import (
+	"errors"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Model is an extreme learning machine consisting of a hidden layer and a linear output
+// layer. The prediction is given by G*w, where G is the output of the hidden layer and w
+// are the output weights.
+type Model struct {
+	Layer         Layer
+	OutputWeights []float64
+
+	// Regularization is the strength of the L2 penalty on the output weights used in Train
+	Regularization float64
+}
+
+// Train solves for the output weights by regularized least squares
+// (G^TG + lambda*I)w = G^Ty, where lambda is the regularization
+func (m *Model) Train(X *mat.Dense, y *mat.VecDense) error {
+	r, _ := X.Dims()
+	if r != y.Len() {
+		return errors.New("The number of rows in X must match the length of y")
+	}
+
+	if m.Regularization < 0.0 {
+		return errors.New("Regularization must be non-negative")
+	}
+
+	G := m.Layer.Output(X)
+	weights, err := RidgeRegression(G, y, m.Regularization)
+	if err != nil {
+		return err
+	}
+	m.OutputWeights = weights.RawVector().Data
+	return nil
+}
+
+// Predict returns the output of the network for each row in X
+func (m Model) Predict(X *mat.Dense) *mat.VecDense {
+	if len(m.OutputWeights) != len(m.Layer.Neurons) {
+		panic("The number of output weights does not match the number of neurons. Has the model been trained?")
+	}
+	G := m.Layer.Output(X)
+	r, _ := G.Dims()
+	pred := mat.NewVecDense(r, nil)
+	pred.MulVec(G, mat.NewVecDense(len(m.OutputWeights), m.OutputWeights))
+	return pred
+}
+
+// RidgeRegression solves (G^TG + lambda*I)w = G^Ty. If lambda is zero, the minimum norm
+// least squares solution is returned
+func RidgeRegression(G *mat.Dense, y *mat.VecDense, lambda float64) (*mat.VecDense, error) {
+	Y := mat.NewDense(y.Len(), 1, nil)
+	Y.SetCol(0, y.RawVector().Data)
+	W, err := RidgeRegressionMatrix(G, Y, lambda)
+	if err != nil {
+		return nil, err
+	}
+	return mat.VecDenseCopyOf(W.ColView(0)), nil
+}
+
+// RidgeRegressionMatrix solves (G^TG + lambda*I)W = G^TY for all columns in Y simultaneously
+func RidgeRegressionMatrix(G *mat.Dense, Y *mat.Dense, lambda float64) (*mat.Dense, error) {
+	var svd mat.SVD
+	if ok := svd.Factorize(G, mat.SVDThin); !ok {
+		return nil, errors.New("SVD factorization of the hidden layer matrix failed")
+	}
+
+	s := svd.Values(nil)
+	var u, v mat.Dense
+	svd.UTo(&u)
+	svd.VTo(&v)
+
+	var uTY mat.Dense
+	uTY.Mul(u.T(), Y)
+
+	tol := 1e-12
+	if len(s) > 0 {
+		tol *= s[0]
+	}
+
+	_, numTargets := Y.Dims()
+	for i := range s {
+		factor := 0.0
+		if s[i]*s[i]+lambda > tol*tol {
+			factor = s[i] / (s[i]*s[i] + lambda)
+		}
+		for j := 0; j < numTargets; j++ {
+			uTY.Set(i, j, uTY.At(i, j)*factor)
+		}
+	}
+
+	var W mat.Dense
+	W.Mul(&v, &uTY)
+	return &W, nil
+}
@@ -0,0 +1,99 @@
+package elm
+
+import (
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestModelTrainPredict(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	layer := Layer{
+		Inputs: []string{"x1", "x2"},
+		Names:  []string{"n0", "n1", "n2"},
+		Neurons: []Neuron{
+			RandomNeuronFactory(2, rng, Sigmoid),
+			RandomNeuronFactory(2, rng, Tanh),
+			RandomNeuronFactory(2, rng, Relu),
+		},
+	}
+
+	X := mat.NewDense(20, 2, nil)
+	for i := 0; i < 20; i++ {
+		X.Set(i, 0, rng.Float64())
+		X.Set(i, 1, rng.NormFloat64())
+	}
+
+	// Construct a target that is exactly representable by the network
+	wantWeights := mat.NewVecDense(3, []float64{1.0, -2.0, 0.5})
+	y := mat.NewVecDense(20, nil)
+	y.MulVec(layer.Output(X), wantWeights)
+
+	model := Model{Layer: layer}
+	if err := model.Train(X, y); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	got := mat.NewVecDense(3, model.OutputWeights)
+	if !mat.EqualApprox(got, wantWeights, 1e-6) {
+		t.Errorf("Want\n%v\ngot\n%v\n", mat.Formatted(wantWeights), mat.Formatted(got))
+	}
+
+	pred := model.Predict(X)
+	if !mat.EqualApprox(pred, y, 1e-6) {
+		t.Errorf("Predictions does not match the target\n")
+	}
+
+	// Regularization shrinks the weights
+	regularized := Model{Layer: layer, Regularization: 10.0}
+	if err := regularized.Train(X, y); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	if mat.Norm(mat.NewVecDense(3, regularized.OutputWeights), 2) >= mat.Norm(wantWeights, 2) {
+		t.Errorf("Expected regularization to reduce the norm of the weights\n")
+	}
+}
+
+func TestRidgeRegressionUnderdetermined(t *testing.T) {
+	G := mat.NewDense(1, 2, []float64{1.0, 1.0})
+	y := mat.NewVecDense(1, []float64{1.0})
+	w, err := RidgeRegression(G, y, 0.0)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	want := mat.NewVecDense(2, []float64{0.5, 0.5})
+	if !mat.EqualApprox(w, want, 1e-8) {
+		t.Errorf("Want\n%v\ngot\n%v\n", mat.Formatted(want), mat.Formatted(w))
+	}
+}
+
+func TestLayerSubset(t *testing.T) {
+	layer := Layer{
+		Inputs: []string{"x1"},
+		Names:  []string{"a", "b", "c"},
+		Neurons: []Neuron{
+			{Weights: []float64{1.0}, ActivationFunc: Relu},
+			{Weights: []float64{2.0}, ActivationFunc: Relu},
+			{Weights: []float64{3.0}, ActivationFunc: Relu},
+		},
+	}
+
+	sub, err := layer.Subset([]string{"c", "a"})
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	if len(sub.Neurons) != 2 || sub.Neurons[0].Weights[0] != 3.0 || sub.Names[1] != "a" {
+		t.Errorf("Unexpected subset %v\n", sub)
+	}
+
+	if _, err := layer.Subset([]string{"d"}); err == nil {
+		t.Errorf("Expected error for unknown neuron\n")
+	}
+}
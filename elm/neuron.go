@@ -1,19 +1,58 @@
 package elm
 
-import "math/rand"
+import (
+	"encoding/json"
+	"math/rand"
+)
 
-// Neuron represents a neuron
+// Neuron represents a neuron. The output of the neuron is given by
+// ActivationFunc(w.x + Bias), where w are the weights
 type Neuron struct {
 	Weights        []float64
+	Bias           float64
 	ActivationFunc ActivationFunc
 }
 
+// neuronJSON is the serialized version of a neuron, where the activation function is
+// represented by its name
+type neuronJSON struct {
+	Weights    []float64
+	Bias       float64
+	Activation string
+}
+
+// MarshalJSON serializes the neuron. The activation function is stored by its name
+func (n Neuron) MarshalJSON() ([]byte, error) {
+	name, err := ActivationName(n.ActivationFunc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(neuronJSON{Weights: n.Weights, Bias: n.Bias, Activation: name})
+}
+
+// UnmarshalJSON initializes the neuron from its serialized version
+func (n *Neuron) UnmarshalJSON(data []byte) error {
+	var nj neuronJSON
+	if err := json.Unmarshal(data, &nj); err != nil {
+		return err
+	}
+
+	activation, err := GetActivation(nj.Activation)
+	if err != nil {
+		return err
+	}
+	n.Weights = nj.Weights
+	n.Bias = nj.Bias
+	n.ActivationFunc = activation
+	return nil
+}
+
 // Activation calculates the actuvation of the current neuron
 func (n Neuron) Activation(x []float64) float64 {
-	return n.ActivationFunc(Dot(x, n.Weights))
+	return n.ActivationFunc(Dot(x, n.Weights) + n.Bias)
 }
 
-// RandomNeuronFactory creates a neuron with randomly initialized weights
+// RandomNeuronFactory creates a neuron with randomly initialized weights and bias
 func RandomNeuronFactory(n int, rng *rand.Rand, a ActivationFunc) Neuron {
 	neuron := Neuron{
 		Weights:        make([]float64, n),
@@ -23,6 +62,7 @@ func RandomNeuronFactory(n int, rng *rand.Rand, a ActivationFunc) Neuron {
 	for i := range neuron.Weights {
 		neuron.Weights[i] = rng.NormFloat64()
 	}
+	neuron.Bias = rng.NormFloat64()
 	return neuron
 }
 
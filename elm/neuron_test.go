@@ -0,0 +1,60 @@
+package elm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+)
+
+func TestNeuronJSONRoundTrip(t *testing.T) {
+	neurons := []Neuron{
+		{
+			Weights:        []float64{1.0, -1.0},
+			ActivationFunc: Relu,
+		},
+		{
+			Weights:        []float64{0.0, -0.2},
+			Bias:           0.3,
+			ActivationFunc: Sigmoid,
+		},
+	}
+
+	data, err := json.Marshal(neurons)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	var res []Neuron
+	if err := json.Unmarshal(data, &res); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	x := []float64{-0.5, 2.0}
+	for i := range neurons {
+		if !floats.EqualApprox(neurons[i].Weights, res[i].Weights, 1e-10) {
+			t.Errorf("Neuron #%d: Want %v got %v\n", i, neurons[i].Weights, res[i].Weights)
+		}
+
+		if neurons[i].Activation(x) != res[i].Activation(x) {
+			t.Errorf("Neuron #%d: Activation function differ\n", i)
+		}
+	}
+}
+
+func TestUnknownActivation(t *testing.T) {
+	neuron := Neuron{
+		Weights:        []float64{1.0},
+		ActivationFunc: func(x float64) float64 { return x },
+	}
+	if _, err := json.Marshal(neuron); err == nil {
+		t.Errorf("Expected error for unknown activation function\n")
+	}
+
+	var n Neuron
+	if err := json.Unmarshal([]byte(`{"Weights": [1.0], "Activation": "unknown"}`), &n); err == nil {
+		t.Errorf("Expected error for unknown activation name\n")
+	}
+}
@@ -0,0 +1,75 @@
+package elm
+
+import (
+	"fmt"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// InputScaling is an affine transformation (x - Offset)/Scale applied to each input
+// column before it is passed to the neurons. Without scaling, inputs on the scale of
+// 1e3 saturates sigmoid-like activation functions for weights of order unity.
+type InputScaling struct {
+	// Method is the name of the method used to construct the scaling (standard or minmax)
+	Method  string
+	Offsets []float64
+	Scales  []float64
+}
+
+// ScalingMethods holds the names of the supported scaling methods
+var ScalingMethods = []string{"none", "standard", "minmax"}
+
+// NewInputScaling calculates the scaling of each column in X. If method is standard,
+// the columns are transformed to zero mean and unit standard deviation. If method is
+// minmax, the columns are mapped to the interval [-1, 1]. Constant columns are only
+// shifted.
+func NewInputScaling(X *mat.Dense, method string) (InputScaling, error) {
+	_, c := X.Dims()
+	s := InputScaling{
+		Method:  method,
+		Offsets: make([]float64, c),
+		Scales:  make([]float64, c),
+	}
+
+	for j := 0; j < c; j++ {
+		col := mat.Col(nil, j, X)
+		switch method {
+		case "standard":
+			s.Offsets[j], s.Scales[j] = stat.MeanStdDev(col, nil)
+		case "minmax":
+			min, max := col[0], col[0]
+			for _, v := range col {
+				if v < min {
+					min = v
+				}
+				if v > max {
+					max = v
+				}
+			}
+			s.Offsets[j] = 0.5 * (min + max)
+			s.Scales[j] = 0.5 * (max - min)
+		default:
+			return s, fmt.Errorf("Unknown scaling method %s. Must be one of %v", method, ScalingMethods)
+		}
+
+		if !(s.Scales[j] > 0.0) {
+			s.Scales[j] = 1.0
+		}
+	}
+	return s, nil
+}
+
+// Apply returns a scaled copy of X
+func (s InputScaling) Apply(X *mat.Dense) *mat.Dense {
+	r, c := X.Dims()
+	if c != len(s.Offsets) || c != len(s.Scales) {
+		panic("The number of columns in X does not match the scaling")
+	}
+
+	res := mat.NewDense(r, c, nil)
+	res.Apply(func(i, j int, v float64) float64 {
+		return (v - s.Offsets[j]) / s.Scales[j]
+	}, X)
+	return res
+}
@@ -0,0 +1,55 @@
+package elm
+
+import (
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestInputScaling(t *testing.T) {
+	X := mat.NewDense(3, 3, []float64{
+		1000.0, 1.0, 5.0,
+		2000.0, 2.0, 5.0,
+		3000.0, 6.0, 5.0,
+	})
+
+	for i, test := range []struct {
+		method  string
+		offsets []float64
+		scales  []float64
+		first   []float64
+	}{
+		{
+			method:  "standard",
+			offsets: []float64{2000.0, 3.0, 5.0},
+			scales:  []float64{1000.0, 2.6457513110645907, 1.0},
+			first:   []float64{-1.0, -0.7559289460184544, 0.0},
+		},
+		{
+			method:  "minmax",
+			offsets: []float64{2000.0, 3.5, 5.0},
+			scales:  []float64{1000.0, 2.5, 1.0},
+			first:   []float64{-1.0, -1.0, 0.0},
+		},
+	} {
+		s, err := NewInputScaling(X, test.method)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+
+		if !floats.EqualApprox(s.Offsets, test.offsets, 1e-10) || !floats.EqualApprox(s.Scales, test.scales, 1e-10) {
+			t.Errorf("Test #%d: Want\n%v %v\ngot\n%v %v\n", i, test.offsets, test.scales, s.Offsets, s.Scales)
+		}
+
+		scaled := s.Apply(X)
+		if !floats.EqualApprox(scaled.RawRowView(0), test.first, 1e-10) {
+			t.Errorf("Test #%d: Want\n%v\ngot\n%v\n", i, test.first, scaled.RawRowView(0))
+		}
+	}
+
+	if _, err := NewInputScaling(X, "unknown"); err == nil {
+		t.Errorf("Expected error for unknown scaling method\n")
+	}
+}
@@ -1,6 +1,11 @@
 package elm
 
-import "gonum.org/v1/gonum/mat"
+import (
+	"runtime"
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+)
 
 // Dot calculates the dot product between a and n
 func Dot(a []float64, b []float64) float64 {
@@ -33,8 +38,19 @@ func checkNeurons(numCols int, neurons []Neuron) bool {
 	return true
 }
 
+// Block sizes used when calculating the hidden layer matrix. Each goroutine processes
+// hiddenRowBlock rows at the time, and the weights of hiddenNeuronBlock neurons are
+// kept hot in the cache while iterating over the rows in the block
+const (
+	hiddenRowBlock    = 64
+	hiddenNeuronBlock = 64
+)
+
 // HiddenLayerMatrix calculates the matrix of a hidden layer given a matrix of input
-// Each row of X corresponds to an input vector to the layer.
+// Each row of X corresponds to an input vector to the layer. The pre-activations XW + b
+// are calculated as a blocked matrix product, followed by element-wise application of
+// the activation functions. The row blocks are processed in parallel. The result is
+// identical to evaluating each neuron via EvaluateLayer.
 func HiddenLayerMatrix(X *mat.Dense, neurons []Neuron) *mat.Dense {
 	r, c := X.Dims()
 
@@ -43,12 +59,79 @@ func HiddenLayerMatrix(X *mat.Dense, neurons []Neuron) *mat.Dense {
 	}
 
 	G := mat.NewDense(r, len(neurons), nil)
+	numBlocks := (r + hiddenRowBlock - 1) / hiddenRowBlock
+	workers := runtime.GOMAXPROCS(0)
+	if workers > numBlocks {
+		workers = numBlocks
+	}
 
-	for j := 0; j < r; j++ {
-		res := EvaluateLayer(neurons, X.RawRowView(j))
-		for i := range res {
-			G.Set(j, i, res[i])
-		}
+	blocks := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for start := range blocks {
+				end := start + hiddenRowBlock
+				if end > r {
+					end = r
+				}
+				hiddenLayerBlock(X, neurons, G, start, end)
+			}
+		}()
 	}
+
+	for b := 0; b < numBlocks; b++ {
+		blocks <- b * hiddenRowBlock
+	}
+	close(blocks)
+	wg.Wait()
 	return G
 }
+
+// hiddenLayerBlock calculates the rows start to end (exclusive) of the hidden layer matrix.
+// The sums over the inputs are accumulated in the same order as in Dot, such that the
+// result is bitwise identical to Neuron.Activation
+func hiddenLayerBlock(X *mat.Dense, neurons []Neuron, G *mat.Dense, start, end int) {
+	for n0 := 0; n0 < len(neurons); n0 += hiddenNeuronBlock {
+		n1 := n0 + hiddenNeuronBlock
+		if n1 > len(neurons) {
+			n1 = len(neurons)
+		}
+
+		// Matrix product. Four neurons are handled per pass over a row
+		for i := start; i < end; i++ {
+			x := X.RawRowView(i)
+			out := G.RawRowView(i)
+			j := n0
+			for ; j+4 <= n1; j += 4 {
+				// Re-slicing to len(x) lets the compiler drop the bounds checks in the loop
+				w0 := neurons[j].Weights[:len(x)]
+				w1 := neurons[j+1].Weights[:len(x)]
+				w2 := neurons[j+2].Weights[:len(x)]
+				w3 := neurons[j+3].Weights[:len(x)]
+				s0, s1, s2, s3 := 0.0, 0.0, 0.0, 0.0
+				for k, xk := range x {
+					s0 += xk * w0[k]
+					s1 += xk * w1[k]
+					s2 += xk * w2[k]
+					s3 += xk * w3[k]
+				}
+				out[j], out[j+1], out[j+2], out[j+3] = s0, s1, s2, s3
+			}
+			for ; j < n1; j++ {
+				out[j] = Dot(x, neurons[j].Weights)
+			}
+		}
+
+		// Element-wise bias and activation
+		for j := n0; j < n1; j++ {
+			f := neurons[j].ActivationFunc
+			b := neurons[j].Bias
+			for i := start; i < end; i++ {
+				out := G.RawRowView(i)
+				out[j] = f(out[j] + b)
+			}
+		}
+	}
+}
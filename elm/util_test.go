@@ -2,6 +2,7 @@ package elm
 
 import "gonum.org/v1/gonum/mat"
 import "testing"
+import "math/rand"
 
 func TestHiddenLayerMatrix(t *testing.T) {
 	neurons := []Neuron{
@@ -23,3 +24,64 @@ func TestHiddenLayerMatrix(t *testing.T) {
 		t.Errorf("Expected\n%v\ngot\n%v\n", mat.Formatted(want), mat.Formatted(G))
 	}
 }
+
+// hiddenLayerMatrixRowWise is the reference implementation evaluating each row via EvaluateLayer
+func hiddenLayerMatrixRowWise(X *mat.Dense, neurons []Neuron) *mat.Dense {
+	r, _ := X.Dims()
+	G := mat.NewDense(r, len(neurons), nil)
+	for j := 0; j < r; j++ {
+		G.SetRow(j, EvaluateLayer(neurons, X.RawRowView(j)))
+	}
+	return G
+}
+
+func randomHiddenLayerProblem(rows, cols, numNeurons int) (*mat.Dense, []Neuron) {
+	rng := rand.New(rand.NewSource(42))
+	X := mat.NewDense(rows, cols, nil)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			X.Set(i, j, rng.NormFloat64())
+		}
+	}
+
+	activations := []ActivationFunc{Relu, Sigmoid, Tanh, Gaussian, Sine, Softplus, LeakyRelu, HardLimit}
+	neurons := make([]Neuron, numNeurons)
+	for i := range neurons {
+		neurons[i] = RandomNeuronFactory(cols, rng, activations[i%len(activations)])
+	}
+	return X, neurons
+}
+
+func TestHiddenLayerMatrixIdentical(t *testing.T) {
+	for i, test := range []struct {
+		rows, cols, neurons int
+	}{
+		{rows: 1, cols: 1, neurons: 1},
+		{rows: 63, cols: 5, neurons: 7},
+		{rows: 300, cols: 7, neurons: 131},
+		{rows: 129, cols: 13, neurons: 64},
+	} {
+		X, neurons := randomHiddenLayerProblem(test.rows, test.cols, test.neurons)
+		want := hiddenLayerMatrixRowWise(X, neurons)
+		got := HiddenLayerMatrix(X, neurons)
+		if !mat.Equal(want, got) {
+			t.Errorf("Test #%d: Vectorized hidden layer matrix differs from the row-wise calculation\n", i)
+		}
+	}
+}
+
+func BenchmarkHiddenLayerMatrix(b *testing.B) {
+	X, neurons := randomHiddenLayerProblem(5000, 20, 500)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		HiddenLayerMatrix(X, neurons)
+	}
+}
+
+func BenchmarkHiddenLayerMatrixRowWise(b *testing.B) {
+	X, neurons := randomHiddenLayerProblem(5000, 20, 500)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hiddenLayerMatrixRowWise(X, neurons)
+	}
+}
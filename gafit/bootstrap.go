@@ -0,0 +1,278 @@
+package gafit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// Bootstrap resampling methods
+const (
+	// CaseResampling draws rows of the training data with replacement
+	CaseResampling = "case"
+
+	// ResidualResampling keeps the rows of the training data and adds residuals of the fit,
+	// drawn with replacement, to the fitted values
+	ResidualResampling = "residual"
+)
+
+// ModelSelector fits a model including feature selection to data (e.g. by running the GA).
+// Random choices must be drawn from rng to make the bootstrap reproducible
+type ModelSelector func(data Dataset, rng *rand.Rand) (Model, error)
+
+// BootstrapConfig holds the settings of a bootstrap of a fitted model
+type BootstrapConfig struct {
+	// Method is CaseResampling or ResidualResampling
+	Method       string
+	NumResamples int
+
+	// Reselect selects the features on each resample. If nil, the features of the model are
+	// kept fixed and only the coefficients are refitted
+	Reselect ModelSelector
+
+	// NumWorkers is the number of resamples fitted in parallel. If zero, GOMAXPROCS is used
+	NumWorkers int
+
+	// Seed is used to draw the resamples. Resample i uses a random number generator seeded
+	// with Seed + i, which is also passed to Reselect, such that the result does not depend
+	// on the number of workers
+	Seed int64
+}
+
+// Bootstrap holds the models fitted to bootstrap resamples of the training data
+type Bootstrap struct {
+	Method     string
+	Reselected bool
+	Seed       int64
+
+	// Model is the model fitted to the training data
+	Model Model
+
+	// Models holds the model fitted to each resample
+	Models []Model
+
+	// Residuals holds the residuals of Model on the training data, scaled by sqrt(n/(n - p))
+	// and centered. They are used for residual resampling and prediction intervals
+	Residuals []float64
+}
+
+// NewBootstrap refits the model to bootstrap resamples of the training data. The resamples
+// are fitted in parallel. Only regression models are supported
+func NewBootstrap(model Model, data Dataset, conf BootstrapConfig) (Bootstrap, error) {
+	if model.IsClassifier() {
+		return Bootstrap{}, errors.New("Bootstrap is only supported for regression models")
+	}
+
+	if conf.NumResamples < 1 {
+		return Bootstrap{}, fmt.Errorf("The number of resamples must be positive. Got %d", conf.NumResamples)
+	}
+
+	if conf.Method != CaseResampling && conf.Method != ResidualResampling {
+		return Bootstrap{}, fmt.Errorf("Unknown bootstrap method %s. Must be %s or %s", conf.Method, CaseResampling, ResidualResampling)
+	}
+
+	b := Bootstrap{
+		Method:     conf.Method,
+		Reselected: conf.Reselect != nil,
+		Seed:       conf.Seed,
+		Model:      model,
+		Models:     make([]Model, conf.NumResamples),
+		Residuals:  bootstrapResiduals(model, data),
+	}
+	fitted := model.Predict(data).RawVector().Data
+
+	workers := conf.NumWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > conf.NumResamples {
+		workers = conf.NumResamples
+	}
+
+	errs := make([]error, conf.NumResamples)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				rng := rand.New(rand.NewSource(conf.Seed + int64(i)))
+				resample := b.resample(data, fitted, rng)
+				if conf.Reselect != nil {
+					b.Models[i], errs[i] = conf.Reselect(resample, rng)
+				} else {
+					b.Models[i] = refit(model, resample)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < conf.NumResamples; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return Bootstrap{}, fmt.Errorf("Resample %d: %s", i, err)
+		}
+	}
+	return b, nil
+}
+
+// bootstrapResiduals returns the residuals of the model scaled by sqrt(n/(n - p)) to account
+// for the fitted parameters, and centered
+func bootstrapResiduals(model Model, data Dataset) []float64 {
+	pred := model.Predict(data)
+	n := data.NumData()
+	p := model.CoeffVector().Len()
+	scale := 1.0
+	if n > p {
+		scale = math.Sqrt(float64(n) / float64(n-p))
+	}
+
+	resid := make([]float64, n)
+	for i := range resid {
+		resid[i] = scale * (data.Y.AtVec(i) - pred.AtVec(i))
+	}
+
+	mean := stat.Mean(resid, nil)
+	for i := range resid {
+		resid[i] -= mean
+	}
+	return resid
+}
+
+// resample draws a bootstrap resample of the training data
+func (b Bootstrap) resample(data Dataset, fitted []float64, rng *rand.Rand) Dataset {
+	n := data.NumData()
+	if b.Method == CaseResampling {
+		idx := make([]int, n)
+		for i := range idx {
+			idx[i] = rng.Intn(n)
+		}
+		return data.Rows(idx)
+	}
+
+	res := data.Copy()
+	for i := 0; i < n; i++ {
+		res.Y.SetVec(i, fitted[i]+b.Residuals[rng.Intn(n)])
+	}
+	return res
+}
+
+// refit returns a copy of the model where the coefficients (and the intercept) are fitted to
+// data by least squares
+func refit(model Model, data Dataset) Model {
+	coeff := Fit(model.DesignMatrix(data), data.Y)
+	res := model
+	res.Coeffs = make(map[string]float64)
+	res.Inference = nil
+	for i, name := range model.Features() {
+		res.Coeffs[name] = coeff.AtVec(i)
+	}
+	if model.HasIntercept() {
+		res.Intercept = coeff.AtVec(coeff.Len() - 1)
+	}
+	return res
+}
+
+// percentileInterval returns the lower and upper percentiles enclosing the fraction level of
+// the values. The values are sorted in place
+func percentileInterval(values []float64, level float64) (float64, float64) {
+	sort.Float64s(values)
+	alpha := 0.5 * (1.0 - level)
+	return stat.Quantile(alpha, stat.Empirical, values, nil), stat.Quantile(1.0-alpha, stat.Empirical, values, nil)
+}
+
+// BootstrapInterval holds the bootstrap standard error and the percentile interval of a
+// coefficient
+type BootstrapInterval struct {
+	Name   string
+	StdErr float64
+	Lower  float64
+	Upper  float64
+
+	// Frequency is the fraction of the resamples where the feature was selected. It is 1
+	// unless the features are reselected on each resample
+	Frequency float64
+}
+
+// CoefficientIntervals returns the bootstrap standard errors and percentile intervals at the
+// given level of the coefficients of the model. If the features are reselected, a coefficient
+// is zero in the resamples where the feature is not selected. If the model has an intercept,
+// it is the last element
+func (b Bootstrap) CoefficientIntervals(level float64) []BootstrapInterval {
+	names := b.Model.Features()
+	if b.Model.HasIntercept() {
+		names = append(names, InterceptName)
+	}
+
+	res := make([]BootstrapInterval, len(names))
+	values := make([]float64, len(b.Models))
+	for i, name := range names {
+		selected := 0
+		for k, m := range b.Models {
+			v, ok := m.Coeffs[name]
+			if name == InterceptName {
+				v, ok = m.Intercept, true
+			}
+			values[k] = v
+			if ok {
+				selected++
+			}
+		}
+
+		res[i] = BootstrapInterval{
+			Name:      name,
+			StdErr:    stat.StdDev(values, nil),
+			Frequency: float64(selected) / float64(len(b.Models)),
+		}
+		res[i].Lower, res[i].Upper = percentileInterval(values, level)
+	}
+	return res
+}
+
+// PredictWithUncertainty returns the predictions of the model for all rows in data together
+// with bootstrap intervals at the given level. The confidence interval of the mean response
+// is the percentile interval of the predictions of the resampled models. For the prediction
+// interval and the standard deviation, a residual drawn with replacement is added to each of
+// these predictions
+func (b Bootstrap) PredictWithUncertainty(data Dataset, level float64) ([]Prediction, error) {
+	for _, m := range b.Models {
+		if _, err := columnIndices(data, m.Features()); err != nil {
+			return nil, err
+		}
+	}
+
+	rng := rand.New(rand.NewSource(b.Seed))
+	value := b.Model.Predict(data)
+	means := make([][]float64, len(b.Models))
+	for k, m := range b.Models {
+		means[k] = m.Predict(data).RawVector().Data
+	}
+
+	predictions := make([]Prediction, data.NumData())
+	mean := make([]float64, len(b.Models))
+	draws := make([]float64, len(b.Models))
+	for i := range predictions {
+		for k := range b.Models {
+			mean[k] = means[k][i]
+			draws[k] = mean[k] + b.Residuals[rng.Intn(len(b.Residuals))]
+		}
+
+		p := Prediction{Value: value.AtVec(i), Std: stat.StdDev(draws, nil)}
+		p.ConfLower, p.ConfUpper = percentileInterval(mean, level)
+		p.PredLower, p.PredUpper = percentileInterval(draws, level)
+		predictions[i] = p
+	}
+	return predictions, nil
+}
@@ -0,0 +1,228 @@
+package gafit
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// bootstrapTestProblem returns data from y = 1 + 2x + noise and the least squares fit
+func bootstrapTestProblem() (Model, Dataset) {
+	rng := rand.New(rand.NewSource(1))
+	n := 200
+	data := Dataset{
+		X:          mat.NewDense(n, 2, nil),
+		Y:          mat.NewVecDense(n, nil),
+		ColNames:   []string{"x", "z"},
+		TargetName: "y",
+	}
+	for i := 0; i < n; i++ {
+		x := rng.Float64()
+		data.X.Set(i, 0, x)
+		data.X.Set(i, 1, rng.NormFloat64())
+		data.Y.SetVec(i, 1.0+2.0*x+0.5*rng.NormFloat64())
+	}
+
+	model := Model{
+		TargetName:      "y",
+		Coeffs:          map[string]float64{"x": 0.0},
+		Standardization: &Standardization{Means: map[string]float64{"x": 0.0}, Scales: map[string]float64{"x": 1.0}},
+	}
+	return refit(model, data), data
+}
+
+func TestBootstrapCoefficients(t *testing.T) {
+	model, data := bootstrapTestProblem()
+	summary, err := NewModelSummary(model, &data, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, method := range []string{CaseResampling, ResidualResampling} {
+		b, err := NewBootstrap(model, data, BootstrapConfig{Method: method, NumResamples: 400, Seed: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// For homoscedastic normal errors, the bootstrap agrees with the analytic intervals.
+		// The intercept is the first coefficient in the summary and the last in the bootstrap
+		intervals := b.CoefficientIntervals(0.9)
+		for i, interval := range intervals {
+			c := summary.Coefficients[(i+1)%2]
+			if interval.Name != c.Name || interval.Frequency != 1.0 {
+				t.Errorf("%s: Unexpected name %s or frequency %f\n", method, interval.Name, interval.Frequency)
+			}
+
+			if math.Abs(interval.StdErr / *c.StdErr - 1.0) > 0.15 {
+				t.Errorf("%s: Expected standard error of %s close to %f got %f\n", method, c.Name, *c.StdErr, interval.StdErr)
+			}
+
+			width := *c.ConfUpper - *c.ConfLower
+			if math.Abs(interval.Lower-*c.ConfLower) > 0.15*width || math.Abs(interval.Upper-*c.ConfUpper) > 0.15*width {
+				t.Errorf("%s: Expected interval of %s close to [%f, %f] got [%f, %f]\n", method, c.Name, *c.ConfLower, *c.ConfUpper, interval.Lower, interval.Upper)
+			}
+		}
+
+		// The result does not depend on the number of workers
+		serial, err := NewBootstrap(model, data, BootstrapConfig{Method: method, NumResamples: 400, Seed: 2, NumWorkers: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k := range b.Models {
+			if b.Models[k].Coeffs["x"] != serial.Models[k].Coeffs["x"] || b.Models[k].Intercept != serial.Models[k].Intercept {
+				t.Errorf("%s: Resample %d differs between parallel and serial runs\n", method, k)
+				break
+			}
+		}
+	}
+}
+
+func TestBootstrapReselect(t *testing.T) {
+	model, data := bootstrapTestProblem()
+
+	// Select z in about every fourth resample, using the random number generator of the
+	// resample
+	selector := func(d Dataset, rng *rand.Rand) (Model, error) {
+		m := model
+		m.Coeffs = map[string]float64{"x": 2.0}
+		if rng.Float64() < 0.25 {
+			m.Coeffs["z"] = 0.1
+		}
+		return refit(m, d), nil
+	}
+
+	selected := make([][]bool, 2)
+	var b Bootstrap
+	for i, workers := range []int{1, 4} {
+		var err error
+		b, err = NewBootstrap(model, data, BootstrapConfig{Method: ResidualResampling, NumResamples: 100, Reselect: selector, NumWorkers: workers, Seed: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, m := range b.Models {
+			_, ok := m.Coeffs["z"]
+			selected[i] = append(selected[i], ok)
+		}
+	}
+
+	numZ := 0
+	for _, ok := range selected[0] {
+		if ok {
+			numZ++
+		}
+	}
+	if numZ == 0 || numZ == 100 {
+		t.Fatalf("Expected z to be selected in some resamples. Got %d\n", numZ)
+	}
+
+	// The selections do not depend on the number of workers
+	if !reflect.DeepEqual(selected[0], selected[1]) {
+		t.Errorf("Expected the same selections with 1 and 4 workers\n")
+	}
+
+	// The coefficients of the original model are reported
+	intervals := b.CoefficientIntervals(0.9)
+	if len(intervals) != 2 || intervals[0].Name != "x" || intervals[0].Frequency != 1.0 {
+		t.Errorf("Unexpected intervals %v\n", intervals)
+	}
+
+	// The data for predictions must hold the features selected in all resamples
+	predData := Dataset{X: mat.NewDense(2, 1, []float64{0.0, 1.0}), ColNames: []string{"x"}}
+	if _, err := b.PredictWithUncertainty(predData, 0.9); err == nil {
+		t.Errorf("Expected error when z is missing from the data\n")
+	}
+}
+
+func TestBootstrapPredictions(t *testing.T) {
+	model, data := bootstrapTestProblem()
+	b, err := NewBootstrap(model, data, BootstrapConfig{Method: CaseResampling, NumResamples: 400, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	predData := Dataset{X: mat.NewDense(3, 2, []float64{0.0, 0.0, 0.5, 0.0, 1.0, 0.0}), ColNames: []string{"x", "z"}}
+	got, err := b.PredictWithUncertainty(predData, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := model.WithInference(data).PredictWithUncertainty(predData, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range got {
+		p := got[i]
+		if p.Value != want[i].Value {
+			t.Errorf("Row %d: Expected prediction %f got %f\n", i, want[i].Value, p.Value)
+		}
+		if !(p.PredLower < p.ConfLower && p.ConfLower < p.Value && p.Value < p.ConfUpper && p.ConfUpper < p.PredUpper) {
+			t.Errorf("Row %d: Expected nested intervals around the prediction. Got %v\n", i, p)
+		}
+
+		confWidth, wantConf := p.ConfUpper-p.ConfLower, want[i].ConfUpper-want[i].ConfLower
+		predWidth, wantPred := p.PredUpper-p.PredLower, want[i].PredUpper-want[i].PredLower
+		if math.Abs(confWidth/wantConf-1.0) > 0.2 || math.Abs(predWidth/wantPred-1.0) > 0.2 || math.Abs(p.Std/want[i].Std-1.0) > 0.2 {
+			t.Errorf("Row %d: Expected interval widths close to %f and %f got %f and %f\n", i, wantConf, wantPred, confWidth, predWidth)
+		}
+	}
+}
+
+func TestSummaryBootstrap(t *testing.T) {
+	model, data := bootstrapTestProblem()
+	summary, err := NewModelSummary(model, &data, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewBootstrap(model, data, BootstrapConfig{Method: CaseResampling, NumResamples: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary.AddBootstrap(b)
+
+	// The intercept is shown first as in the coefficient table
+	if len(summary.Bootstrap) != 2 || summary.Bootstrap[0].Name != InterceptName || summary.Bootstrap[1].Name != "x" {
+		t.Errorf("Unexpected bootstrap intervals %v\n", summary.Bootstrap)
+	}
+
+	var buf bytes.Buffer
+	if err := summary.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"Bootstrap (case resampling, 50 resamples, fixed features):",
+		"             Std. Error  5 %",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Expected the table to contain\n%s\ngot\n%s\n", line, buf.String())
+		}
+	}
+
+	if strings.Contains(buf.String(), "Selected") {
+		t.Errorf("Expected no selection frequency for fixed features\n")
+	}
+}
+
+func TestBootstrapErrors(t *testing.T) {
+	model, data := bootstrapTestProblem()
+	for i, conf := range []BootstrapConfig{
+		{Method: "jackknife", NumResamples: 10},
+		{Method: CaseResampling, NumResamples: 0},
+	} {
+		if _, err := NewBootstrap(model, data, conf); err == nil {
+			t.Errorf("Test #%d: Expected error\n", i)
+		}
+	}
+
+	classifier := Model{TargetName: "y", Classes: []string{"a", "b"}, ClassCoeffs: []map[string]float64{{"x": 1.0}}}
+	if _, err := NewBootstrap(classifier, data, BootstrapConfig{Method: CaseResampling, NumResamples: 10}); err == nil {
+		t.Errorf("Expected error for classification model\n")
+	}
+}
@@ -0,0 +1,101 @@
+package gafit
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CategoricalEncoding describes how a column holding string values (e.g. material names)
+// is expanded into numerical columns. Each level gets its own indicator column named
+// <column>_<level>. If DropFirst is true, the first level is used as reference level
+// (dummy encoding) and no column is created for it.
+type CategoricalEncoding struct {
+	Column    string
+	Levels    []string
+	DropFirst bool
+}
+
+// NewCategoricalEncoding creates an encoding from the values of a column. The levels are
+// the unique values sorted alphabetically
+func NewCategoricalEncoding(column string, values []string, dropFirst bool) CategoricalEncoding {
+	unique := make(map[string]bool)
+	for _, v := range values {
+		unique[strings.TrimSpace(v)] = true
+	}
+
+	levels := make([]string, 0, len(unique))
+	for v := range unique {
+		levels = append(levels, v)
+	}
+	sort.Strings(levels)
+	return CategoricalEncoding{
+		Column:    column,
+		Levels:    levels,
+		DropFirst: dropFirst,
+	}
+}
+
+func (c CategoricalEncoding) firstEncoded() int {
+	if c.DropFirst {
+		return 1
+	}
+	return 0
+}
+
+// Names returns the names of the columns created by the encoding
+func (c CategoricalEncoding) Names() []string {
+	names := []string{}
+	for _, level := range c.Levels[c.firstEncoded():] {
+		names = append(names, fmt.Sprintf("%s_%s", c.Column, level))
+	}
+	return names
+}
+
+// Encode returns the indicator values corresponding to value. There is one value for each
+// name returned by Names. An error is returned if value is not one of the known levels.
+func (c CategoricalEncoding) Encode(value string) ([]float64, error) {
+	value = strings.TrimSpace(value)
+	res := make([]float64, len(c.Levels)-c.firstEncoded())
+	for i, level := range c.Levels {
+		if level == value {
+			if i >= c.firstEncoded() {
+				res[i-c.firstEncoded()] = 1.0
+			}
+			return res, nil
+		}
+	}
+	return res, fmt.Errorf("Unknown level %s in categorical column %s. Known levels: %s", value, c.Column, strings.Join(c.Levels, ", "))
+}
+
+// IsEqual returns true if the two encodings are equal
+func (c CategoricalEncoding) IsEqual(other CategoricalEncoding) bool {
+	return c.Column == other.Column && c.DropFirst == other.DropFirst && allEqualString(c.Levels, other.Levels)
+}
+
+// isNumeric returns true if all values can be parsed as floating point numbers
+func isNumeric(values []string) bool {
+	for _, v := range values {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// mostlyNumeric returns the index of the first value that can not be parsed as a floating
+// point number and true if more than half of the values are numbers, but not all. Such
+// columns are most likely numeric columns with missing or malformed values
+func mostlyNumeric(values []string) (int, bool) {
+	first := -1
+	numeric := 0
+	for i, v := range values {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			numeric++
+		} else if first == -1 {
+			first = i
+		}
+	}
+	return first, first != -1 && 2*numeric > len(values)
+}
@@ -0,0 +1,64 @@
+package gafit
+
+import (
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+)
+
+func TestNewCategoricalEncoding(t *testing.T) {
+	enc := NewCategoricalEncoding("material", []string{"steel", " copper", "steel", "aluminium "}, false)
+	want := []string{"aluminium", "copper", "steel"}
+	if !allEqualString(enc.Levels, want) {
+		t.Errorf("Want\n%v\ngot\n%v\n", want, enc.Levels)
+	}
+}
+
+func TestCategoricalEncode(t *testing.T) {
+	for i, test := range []struct {
+		enc   CategoricalEncoding
+		value string
+		names []string
+		want  []float64
+	}{
+		{
+			enc:   CategoricalEncoding{Column: "mat", Levels: []string{"a", "b", "c"}},
+			value: "b",
+			names: []string{"mat_a", "mat_b", "mat_c"},
+			want:  []float64{0.0, 1.0, 0.0},
+		},
+		{
+			enc:   CategoricalEncoding{Column: "mat", Levels: []string{"a", "b", "c"}, DropFirst: true},
+			value: "c",
+			names: []string{"mat_b", "mat_c"},
+			want:  []float64{0.0, 1.0},
+		},
+		{
+			enc:   CategoricalEncoding{Column: "mat", Levels: []string{"a", "b", "c"}, DropFirst: true},
+			value: "a",
+			names: []string{"mat_b", "mat_c"},
+			want:  []float64{0.0, 0.0},
+		},
+	} {
+		names := test.enc.Names()
+		if !allEqualString(names, test.names) {
+			t.Errorf("Test #%d: Want\n%v\ngot\n%v\n", i, test.names, names)
+		}
+
+		v, err := test.enc.Encode(test.value)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+		if !floats.EqualApprox(v, test.want, 1e-10) {
+			t.Errorf("Test #%d: Want\n%v\ngot\n%v\n", i, test.want, v)
+		}
+	}
+}
+
+func TestCategoricalEncodeUnknownLevel(t *testing.T) {
+	enc := CategoricalEncoding{Column: "mat", Levels: []string{"a", "b"}}
+	if _, err := enc.Encode("c"); err == nil {
+		t.Errorf("Expected error for unknown level\n")
+	}
+}
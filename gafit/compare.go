@@ -0,0 +1,223 @@
+package gafit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// ModelComparison compares the selected features, the coefficients and the performance of
+// several models of the same target (e.g. fitted with different cost functions or seeds)
+type ModelComparison struct {
+	Names  []string
+	Models []Model
+
+	// Metrics holds the performance of each model on test data. It is nil if the models have
+	// not been evaluated
+	Metrics []RegressionMetrics
+}
+
+// comparedTestMetrics holds the names of the test metrics included in the comparison table
+var comparedTestMetrics = map[string]bool{
+	"n": true, "rmse": true, "mae": true, "max_error": true, "r2": true, "adj_r2": true, "mape": true,
+}
+
+// NewModelComparison creates a comparison of the passed models. Names are used as the column
+// headers (e.g. the model files). All models must be regression models of the same target
+func NewModelComparison(names []string, models []Model) (ModelComparison, error) {
+	if len(names) != len(models) {
+		return ModelComparison{}, fmt.Errorf("Got %d names but %d models", len(names), len(models))
+	}
+
+	for i, m := range models {
+		if m.IsClassifier() {
+			return ModelComparison{}, fmt.Errorf("%s is a classification model. Only regression models can be compared", names[i])
+		}
+		if m.TargetName != models[0].TargetName {
+			return ModelComparison{}, fmt.Errorf("%s has target %s, but %s has target %s", names[i], m.TargetName, names[0], models[0].TargetName)
+		}
+	}
+	return ModelComparison{Names: names, Models: models}, nil
+}
+
+// Features returns the union of the features of all models in alphabetical order
+func (c ModelComparison) Features() []string {
+	unique := make(map[string]bool)
+	for _, m := range c.Models {
+		for _, name := range m.Features() {
+			unique[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(unique))
+	for name := range unique {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// JaccardSimilarity returns the number of elements in the intersection divided by the number
+// of elements in the union of the two sets. The similarity of two empty sets is 1
+func JaccardSimilarity(a []string, b []string) float64 {
+	inA := make(map[string]bool)
+	for _, v := range a {
+		inA[v] = true
+	}
+
+	union := len(inA)
+	intersection := 0
+	seen := make(map[string]bool)
+	for _, v := range b {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		if inA[v] {
+			intersection++
+		} else {
+			union++
+		}
+	}
+
+	if union == 0 {
+		return 1.0
+	}
+	return float64(intersection) / float64(union)
+}
+
+// JaccardMatrix returns the Jaccard similarity between the feature sets of all pairs of models
+func (c ModelComparison) JaccardMatrix() [][]float64 {
+	features := make([][]string, len(c.Models))
+	for i, m := range c.Models {
+		features[i] = m.Features()
+	}
+
+	res := make([][]float64, len(c.Models))
+	for i := range res {
+		res[i] = make([]float64, len(c.Models))
+		for j := range res[i] {
+			res[i][j] = JaccardSimilarity(features[i], features[j])
+		}
+	}
+	return res
+}
+
+// WriteTable writes the comparison as human readable tables. The first table holds the
+// coefficient of each feature in each model (- if the feature is not selected) and the number
+// of models that selected it. The second table holds the number of features, the in-sample score
+// and the test metrics. The last table is the Jaccard similarity matrix of the feature sets
+func (c ModelComparison) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := func(first string) {
+		fmt.Fprintf(tw, "%s", first)
+		for _, name := range c.Names {
+			fmt.Fprintf(tw, "\t%s", name)
+		}
+	}
+
+	header("feature")
+	fmt.Fprintf(tw, "\tselected by\n")
+	for _, feature := range c.Features() {
+		fmt.Fprintf(tw, "%s", feature)
+		count := 0
+		for _, m := range c.Models {
+			if coeff, ok := m.Coeffs[feature]; ok {
+				fmt.Fprintf(tw, "\t%.6g", coeff)
+				count++
+			} else {
+				fmt.Fprintf(tw, "\t-")
+			}
+		}
+		fmt.Fprintf(tw, "\t%d/%d\n", count, len(c.Models))
+	}
+
+	fmt.Fprintf(tw, "\n")
+	header("metric")
+	fmt.Fprintf(tw, "\nnum_features")
+	for _, m := range c.Models {
+		fmt.Fprintf(tw, "\t%d", len(m.Features()))
+	}
+	fmt.Fprintf(tw, "\ncost")
+	for _, m := range c.Models {
+		fmt.Fprintf(tw, "\t%s", m.Score.Name)
+	}
+	fmt.Fprintf(tw, "\nscore")
+	for _, m := range c.Models {
+		fmt.Fprintf(tw, "\t%.6g", m.Score.Value)
+	}
+	fmt.Fprintf(tw, "\n")
+
+	metrics := make([]TargetMetrics, len(c.Metrics))
+	for i, rm := range c.Metrics {
+		metrics[i] = TargetMetrics{Target: c.Names[i], RegressionMetrics: rm}
+	}
+
+	for _, row := range targetMetricRows(metrics) {
+		if !comparedTestMetrics[row[0].Name] {
+			continue
+		}
+		fmt.Fprintf(tw, "test_%s", row[0].Name)
+		for _, mv := range row {
+			value := "-"
+			if mv.Value != nil {
+				value = fmt.Sprintf("%.6g", *mv.Value)
+			}
+			fmt.Fprintf(tw, "\t%s", value)
+		}
+		fmt.Fprintf(tw, "\n")
+	}
+
+	fmt.Fprintf(tw, "\n")
+	header("jaccard")
+	fmt.Fprintf(tw, "\n")
+	for i, row := range c.JaccardMatrix() {
+		fmt.Fprintf(tw, "%s", c.Names[i])
+		for _, v := range row {
+			fmt.Fprintf(tw, "\t%.3f", v)
+		}
+		fmt.Fprintf(tw, "\n")
+	}
+	return tw.Flush()
+}
+
+// comparedModel is the JSON representation of a model in a comparison
+type comparedModel struct {
+	Name        string
+	Features    []string
+	Coeffs      map[string]float64
+	Score       Score
+	TestMetrics *RegressionMetrics `json:",omitempty"`
+}
+
+// WriteJSON writes the comparison in JSON format. It holds the features, coefficients, score
+// and test metrics of each model, the union of the features and the Jaccard similarity matrix
+func (c ModelComparison) WriteJSON(w io.Writer) error {
+	doc := struct {
+		Target   string
+		Features []string
+		Models   []comparedModel
+		Jaccard  [][]float64
+	}{
+		Features: c.Features(),
+		Jaccard:  c.JaccardMatrix(),
+	}
+
+	for i, m := range c.Models {
+		doc.Target = m.TargetName
+		cm := comparedModel{Name: c.Names[i], Features: m.Features(), Coeffs: m.Coeffs, Score: m.Score}
+		if len(c.Metrics) > 0 {
+			cm.TestMetrics = &c.Metrics[i]
+		}
+		doc.Models = append(doc.Models, cm)
+	}
+
+	serialized, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", serialized)
+	return err
+}
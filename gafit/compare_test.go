@@ -0,0 +1,98 @@
+package gafit
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestJaccardSimilarity(t *testing.T) {
+	for i, test := range []struct {
+		a, b []string
+		want float64
+	}{
+		{[]string{"x1", "x2"}, []string{"x1", "x2"}, 1.0},
+		{[]string{"x1", "x2"}, []string{"x2", "x3"}, 1.0 / 3.0},
+		{[]string{"x1"}, []string{"x2"}, 0.0},
+		{[]string{"x1", "x2", "x3", "x4"}, []string{"x1", "x1"}, 0.25},
+		{[]string{}, []string{}, 1.0},
+		{[]string{}, []string{"x1"}, 0.0},
+	} {
+		if got := JaccardSimilarity(test.a, test.b); math.Abs(got-test.want) > 1e-12 {
+			t.Errorf("Test #%d: Expected %f got %f\n", i, test.want, got)
+		}
+	}
+}
+
+func TestModelComparison(t *testing.T) {
+	models := []Model{
+		{TargetName: "y", Coeffs: map[string]float64{"x1": 1.0, "x2": 2.0}, Score: Score{Name: "aicc", Value: -10.0}},
+		{TargetName: "y", Coeffs: map[string]float64{"x2": 2.5, "x3": -1.0}, Score: Score{Name: "bic", Value: -8.0}},
+		{TargetName: "y", Coeffs: map[string]float64{"x1": 0.5, "x2": 1.5, "x3": 0.1}, Score: Score{Name: "aicc", Value: -9.0}},
+	}
+
+	comp, err := NewModelComparison([]string{"a.json", "b.json", "c.json"}, models)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(comp.Features(), []string{"x1", "x2", "x3"}) {
+		t.Errorf("Unexpected union of features %v\n", comp.Features())
+	}
+
+	want := [][]float64{
+		{1.0, 1.0 / 3.0, 2.0 / 3.0},
+		{1.0 / 3.0, 1.0, 2.0 / 3.0},
+		{2.0 / 3.0, 2.0 / 3.0, 1.0},
+	}
+	got := comp.JaccardMatrix()
+	for i := range want {
+		for j := range want[i] {
+			if math.Abs(got[i][j]-want[i][j]) > 1e-12 {
+				t.Errorf("Jaccard (%d, %d): Expected %f got %f\n", i, j, want[i][j], got[i][j])
+			}
+		}
+	}
+
+	data := Dataset{
+		X:        mat.NewDense(3, 3, []float64{1.0, 0.0, 1.0, 0.0, 1.0, 2.0, 1.0, 1.0, 0.0}),
+		Y:        mat.NewVecDense(3, []float64{1.0, 2.0, 3.0}),
+		ColNames: []string{"x1", "x2", "x3"},
+	}
+	for _, m := range models {
+		comp.Metrics = append(comp.Metrics, NewRegressionMetrics(m, data))
+	}
+
+	var buf bytes.Buffer
+	if err := comp.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	table := buf.String()
+	for _, line := range []string{
+		"x1       1       -       0.5     2/3",
+		"x3       -       -1      0.1     2/3",
+		"num_features    2       2        3",
+		"cost            aicc    bic      aicc",
+		"test_rmse       0       1.47196  0.645497",
+		"test_adj_r2     1       -5.5     -",
+		"b.json   0.333   1.000   0.667",
+	} {
+		if !strings.Contains(table, line) {
+			t.Errorf("Expected the table to contain\n%s\ngot\n%s\n", line, table)
+		}
+	}
+
+	if err := comp.WriteJSON(&buf); err != nil {
+		t.Errorf("%s\n", err)
+	}
+
+	models[1].TargetName = "z"
+	if _, err := NewModelComparison([]string{"a.json", "b.json", "c.json"}, models); err == nil {
+		t.Errorf("Expected error for models of different targets\n")
+	}
+}
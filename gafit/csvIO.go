@@ -15,68 +15,202 @@ import (
 
 // Read dataset from the a file
 func Read(fname string, targetName string) (Dataset, error) {
+	return ReadWithOptions(fname, targetName, ReadOptions{})
+}
+
+// ReadOptions controls how columns with non-numeric values are converted
+type ReadOptions struct {
+	// DropFirst selects dummy encoding (the first level is used as a reference level)
+	// instead of one-hot encoding for categorical columns that are detected automatically
+	DropFirst bool
+
+	// Encodings holds known categorical encodings (e.g. the ones stored in a model). Columns
+	// listed here are always treated as categorical, and values that are not among the
+	// known levels result in an error
+	Encodings []CategoricalEncoding
+
+	// ClassTarget marks the target column as holding class labels (classification). The
+	// target values are converted into the index of the label in the list of classes
+	ClassTarget bool
+
+	// Classes holds known class labels (e.g. the ones stored in a model). If empty, the
+	// sorted unique values of the target column are used
+	Classes []string
+}
+
+// ReadWithOptions reads a dataset from a file using the passed options
+func ReadWithOptions(fname string, targetName string, opts ReadOptions) (Dataset, error) {
 	f, err := os.Open(fname)
 	if err != nil {
-		panic(err)
+		return Dataset{}, err
 	}
 	defer f.Close()
-	return ReadFile(f, targetName)
+	return ReadFileWithOptions(f, targetName, opts)
 }
 
 // ReadFile creates a dataset from the passed file, If targetName is an empty
 // string, the entire file will be added to the X matrix. If targetName is not empty string
-// and is not found in the header, the function will return with an error
+// and is not found in the header, the function will return with an error. Columns that
+// contain non-numeric values are one-hot encoded.
 func ReadFile(csvfile *os.File, targetName string) (Dataset, error) {
+	return ReadFileWithOptions(csvfile, targetName, ReadOptions{})
+}
+
+// ReadFileWithOptions creates a dataset from the passed reader. Columns that contain
+// non-numeric values, or that are listed in opts.Encodings, are expanded into indicator
+// columns. The encodings used are stored in the Categories field of the returned dataset.
+func ReadFileWithOptions(csvfile io.Reader, targetName string, opts ReadOptions) (Dataset, error) {
 	targetName = strings.TrimSpace(targetName)
-	r := csv.NewReader(csvfile)
 	data := Dataset{}
 
-	isHeader := true
-	targetCol := -1
-	X := []float64{}
-	y := []float64{}
-	for {
-		record, err := r.Read()
-		if err == io.EOF {
-			break
-		}
+	records, err := csv.NewReader(csvfile).ReadAll()
+	if err != nil {
+		return data, err
+	}
+
+	if len(records) < 2 {
+		return data, errors.New("The file must contain a header and at least one row of data")
+	}
 
-		if isHeader {
-			isHeader = false
-			parseHeader(record)
-			record, targetCol = remove(record, targetName)
+	header := records[0]
+	parseHeader(header)
+	names, targetCol := remove(header, targetName)
 
-			if targetCol == -1 && targetName != "" {
-				return data, errors.New("Target column not found in the header")
-			}
-			data.ColNames = record
-			data.TargetName = targetName
+	if targetCol == -1 && targetName != "" {
+		return data, errors.New("Target column not found in the header")
+	}
+	data.TargetName = targetName
+
+	rows := records[1:]
+	y := make([]float64, len(rows))
+	labels := make([]string, len(rows))
+	columns := make([][]string, len(names))
+	for i := range columns {
+		columns[i] = make([]string, len(rows))
+	}
+
+	for i, record := range rows {
+		if targetCol == -1 {
+			y[i] = math.NaN()
+		} else if opts.ClassTarget {
+			labels[i] = record[targetCol]
 		} else {
-			values, err := parseValues(record)
+			values, err := parseValues(record[targetCol : targetCol+1])
 			if err != nil {
 				return data, err
 			}
+			y[i] = values[0]
+		}
 
-			values, targ := removeIndex(values, targetCol)
-			y = append(y, targ)
-			for _, v := range values {
-				X = append(X, v)
+		col := 0
+		for j := range record {
+			if j == targetCol {
+				continue
 			}
+			columns[col][i] = record[j]
+			col++
 		}
 	}
 
-	// Populate the dataset
-	nr, nc := len(y), len(X)/len(y)
+	if opts.ClassTarget {
+		data.Classes = opts.Classes
+		if targetCol != -1 {
+			if len(data.Classes) == 0 {
+				data.Classes = NewCategoricalEncoding(targetName, labels, false).Levels
+			}
+			if err := classIndices(data.Classes, targetName, labels, y); err != nil {
+				return data, err
+			}
+		}
+	}
+
+	known := make(map[string]CategoricalEncoding)
+	for _, enc := range opts.Encodings {
+		known[enc.Column] = enc
+	}
+
+	// Convert each column into one or more numerical columns
+	numerical := [][]float64{}
+	for i, name := range names {
+		enc, isKnown := known[name]
+
+		// One-hot encoding a numeric column with a few bad values would silently add one
+		// column per distinct value
+		if row, ok := mostlyNumeric(columns[i]); !isKnown && ok {
+			return data, fmt.Errorf("Column %s: value \"%s\" on line %d is not a number, while most values in the column are. Fix or remove the value", name, columns[i][row], row+2)
+		}
 
-	if len(X) != nr*nc {
-		msg := fmt.Sprintf("Dimension mismatch. Expected %d elements got %d\n", nr*nc, len(X))
-		return data, errors.New(msg)
+		if !isKnown && isNumeric(columns[i]) {
+			values, err := parseValues(columns[i])
+			if err != nil {
+				return data, err
+			}
+			numerical = append(numerical, values)
+			data.ColNames = append(data.ColNames, name)
+			continue
+		}
+
+		if !isKnown {
+			enc = NewCategoricalEncoding(name, columns[i], opts.DropFirst)
+		}
+		encoded, err := encodeColumn(enc, columns[i])
+		if err != nil {
+			return data, err
+		}
+		numerical = append(numerical, encoded...)
+		data.ColNames = append(data.ColNames, enc.Names()...)
+		data.Categories = append(data.Categories, enc)
+	}
+
+	// Populate the dataset
+	nr, nc := len(y), len(numerical)
+	if nc == 0 {
+		return data, errors.New("No feature columns found in the file")
+	}
+	data.X = mat.NewDense(nr, nc, nil)
+	for j, values := range numerical {
+		data.X.SetCol(j, values)
 	}
-	data.X = mat.NewDense(nr, nc, X)
 	data.Y = mat.NewVecDense(nr, y)
 	return data, nil
 }
 
+// classIndices stores the index of each label in classes in y
+func classIndices(classes []string, targetName string, labels []string, y []float64) error {
+	idx := make(map[string]int)
+	for i, c := range classes {
+		idx[c] = i
+	}
+
+	for i, label := range labels {
+		c, ok := idx[strings.TrimSpace(label)]
+		if !ok {
+			return fmt.Errorf("Unknown class %s in target column %s. Known classes: %s", label, targetName, strings.Join(classes, ", "))
+		}
+		y[i] = float64(c)
+	}
+	return nil
+}
+
+// encodeColumn returns one column of indicator values for each name in the encoding
+func encodeColumn(enc CategoricalEncoding, values []string) ([][]float64, error) {
+	res := make([][]float64, len(enc.Names()))
+	for i := range res {
+		res[i] = make([]float64, len(values))
+	}
+
+	for row, v := range values {
+		encoded, err := enc.Encode(v)
+		if err != nil {
+			return res, err
+		}
+		for i := range encoded {
+			res[i][row] = encoded[i]
+		}
+	}
+	return res, nil
+}
+
 func parseHeader(record []string) {
 	for i := range record {
 		record[i] = strings.Trim(record[i], "#/ \n\t\r\v")
@@ -138,10 +272,10 @@ func Write(fname string, X *mat.Dense, y *mat.VecDense, featNames []string, targ
 	return WriteFile(f, X, y, featNames, targetName)
 }
 
-// WriteFile writes dataset to file
+// WriteFile writes dataset to file. If y is nil, only the columns of X are written
 func WriteFile(f *os.File, X *mat.Dense, y *mat.VecDense, featNames []string, targetName string) error {
 	r, c := X.Dims()
-	if r != y.Len() {
+	if y != nil && r != y.Len() {
 		return errors.New("Length of y must be equal to the number of rows in X")
 	}
 
@@ -153,22 +287,30 @@ func WriteFile(f *os.File, X *mat.Dense, y *mat.VecDense, featNames []string, ta
 	defer writer.Flush()
 
 	// Write the header, where target name is appended to the end
-	record := make([]string, len(featNames)+1)
+	numCols := len(featNames)
+	if y != nil {
+		numCols++
+	}
+	record := make([]string, numCols)
 	copy(record, featNames)
-	record[len(record)-1] = targetName
+	if y != nil {
+		record[len(record)-1] = targetName
+	}
 
 	err := writer.Write(record)
 	if err != nil {
 		return err
 	}
 
-	for i := 0; i < y.Len(); i++ {
+	for i := 0; i < r; i++ {
 		for j := 0; j < c; j++ {
 			v := strconv.FormatFloat(X.At(i, j), 'f', 8, 64)
 			record[j] = v
 		}
-		v := strconv.FormatFloat(y.AtVec(i), 'f', 8, 64)
-		record[len(record)-1] = v
+		if y != nil {
+			v := strconv.FormatFloat(y.AtVec(i), 'f', 8, 64)
+			record[len(record)-1] = v
+		}
 		err = writer.Write(record)
 		if err != nil {
 			return err
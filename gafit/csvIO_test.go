@@ -1,6 +1,7 @@
 package gafit
 
 import (
+	"strings"
 	"testing"
 
 	"gonum.org/v1/gonum/floats"
@@ -111,4 +112,130 @@ func TestReadFile(t *testing.T) {
 	if !data.IsEqual(want) {
 		t.Errorf("Wanted\n%+v\ngot\n%+v\n", want, data)
 	}
+
+	if _, err := Read("_testdata/missing.csv", "Var4"); err == nil {
+		t.Errorf("Expected error for a missing file\n")
+	}
+}
+
+func TestReadMalformedNumeric(t *testing.T) {
+	// A missing value in a numeric column is reported instead of one-hot encoding the column
+	csv := "x,y\n1.0,0.5\n,0.7\n3.0,0.9\n"
+	_, err := ReadFileWithOptions(strings.NewReader(csv), "y", ReadOptions{})
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected error naming line 3. Got %v\n", err)
+	}
+
+	// Columns where most values are not numbers are categorical
+	csv = "x,y\n1.0,0.5\na,0.7\nb,0.9\n"
+	data, err := ReadFileWithOptions(strings.NewReader(csv), "y", ReadOptions{})
+	if err != nil || len(data.Categories) != 1 {
+		t.Errorf("Expected a categorical column. Got %v (%v)\n", data.Categories, err)
+	}
+}
+
+func TestReadCategorical(t *testing.T) {
+	data, err := Read("_testdata/categorical.csv", "energy")
+	if err != nil {
+		t.Errorf("Error during read %s\n", err)
+		return
+	}
+
+	want := Dataset{
+		ColNames:   []string{"temperature", "material_aluminium", "material_copper", "material_steel", "pressure"},
+		TargetName: "energy",
+		Y:          mat.NewVecDense(4, []float64{0.5, 0.7, 0.9, 1.1}),
+		X: mat.NewDense(4, 5, []float64{300.0, 0.0, 0.0, 1.0, 1.0,
+			400.0, 0.0, 1.0, 0.0, 2.0,
+			500.0, 0.0, 0.0, 1.0, 1.5,
+			600.0, 1.0, 0.0, 0.0, 1.0}),
+	}
+
+	if !data.IsEqual(want) {
+		t.Errorf("Wanted\n%+v\ngot\n%+v\n", want, data)
+	}
+
+	if len(data.Categories) != 1 || data.Categories[0].Column != "material" {
+		t.Errorf("Expected one categorical column named material. Got %v\n", data.Categories)
+	}
+}
+
+func TestReadWithKnownEncoding(t *testing.T) {
+	for i, test := range []struct {
+		enc       CategoricalEncoding
+		expectErr bool
+		names     []string
+	}{
+		{
+			enc:       CategoricalEncoding{Column: "material", Levels: []string{"aluminium", "copper", "steel"}, DropFirst: true},
+			expectErr: false,
+			names:     []string{"temperature", "material_copper", "material_steel", "pressure"},
+		},
+		{
+			enc:       CategoricalEncoding{Column: "material", Levels: []string{"copper", "steel"}},
+			expectErr: true,
+		},
+	} {
+		data, err := ReadWithOptions("_testdata/categorical.csv", "energy", ReadOptions{Encodings: []CategoricalEncoding{test.enc}})
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Test #%d: Expected error\n", i)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+
+		if !allEqualString(data.ColNames, test.names) {
+			t.Errorf("Test #%d: Want\n%v\ngot\n%v\n", i, test.names, data.ColNames)
+		}
+	}
+}
+
+func TestReadClassTarget(t *testing.T) {
+	for i, test := range []struct {
+		classes   []string
+		expectErr bool
+		want      []string
+		y         []float64
+	}{
+		{
+			want: []string{"aluminium", "copper", "steel"},
+			y:    []float64{2.0, 1.0, 2.0, 0.0},
+		},
+		{
+			classes: []string{"steel", "copper", "aluminium"},
+			want:    []string{"steel", "copper", "aluminium"},
+			y:       []float64{0.0, 1.0, 0.0, 2.0},
+		},
+		{
+			classes:   []string{"copper", "steel"},
+			expectErr: true,
+		},
+	} {
+		opts := ReadOptions{ClassTarget: true, Classes: test.classes}
+		data, err := ReadWithOptions("_testdata/categorical.csv", "material", opts)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Test #%d: Expected error\n", i)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+
+		if !allEqualString(data.Classes, test.want) {
+			t.Errorf("Test #%d: Want\n%v\ngot\n%v\n", i, test.want, data.Classes)
+		}
+
+		if !floats.Equal(data.Y.RawVector().Data, test.y) {
+			t.Errorf("Test #%d: Want\n%v\ngot\n%v\n", i, test.y, data.Y.RawVector().Data)
+		}
+	}
 }
@@ -16,6 +16,13 @@ type Dataset struct {
 	// ColNames gives the name of the "feature" stored in each column of X
 	ColNames   []string
 	TargetName string
+
+	// Categories holds the encodings of columns that originally contained string values
+	Categories []CategoricalEncoding
+
+	// Classes holds the class labels for classification data. Y then holds the index of the
+	// class of each row
+	Classes []string
 }
 
 // Copy returns a copy of the dataset
@@ -33,14 +40,58 @@ func (data Dataset) Copy() Dataset {
 	names := make([]string, len(data.ColNames))
 	copy(names, data.ColNames)
 
+	var categories []CategoricalEncoding
+	for _, c := range data.Categories {
+		levels := make([]string, len(c.Levels))
+		copy(levels, c.Levels)
+		categories = append(categories, CategoricalEncoding{
+			Column:    c.Column,
+			Levels:    levels,
+			DropFirst: c.DropFirst,
+		})
+	}
+
+	var classes []string
+	if data.Classes != nil {
+		classes = make([]string, len(data.Classes))
+		copy(classes, data.Classes)
+	}
+
 	return Dataset{
 		X:          X,
 		Y:          Y,
 		TargetName: data.TargetName,
 		ColNames:   names,
+		Categories: categories,
+		Classes:    classes,
 	}
 }
 
+// Rows returns a dataset holding the given rows of data. Rows may be repeated (e.g. for
+// bootstrap resamples). The column names, categories and classes are shared with data
+func (data Dataset) Rows(idx []int) Dataset {
+	_, cols := data.X.Dims()
+	res := Dataset{
+		X:          mat.NewDense(len(idx), cols, nil),
+		ColNames:   data.ColNames,
+		TargetName: data.TargetName,
+		Categories: data.Categories,
+		Classes:    data.Classes,
+	}
+
+	if data.Y != nil {
+		res.Y = mat.NewVecDense(len(idx), nil)
+	}
+
+	for i, row := range idx {
+		res.X.SetRow(i, data.X.RawRowView(row))
+		if data.Y != nil {
+			res.Y.SetVec(i, data.Y.AtVec(row))
+		}
+	}
+	return res
+}
+
 // IsEqual returns true if the two dataseta are equal
 func (data Dataset) IsEqual(other Dataset) bool {
 	tol := 1e-6
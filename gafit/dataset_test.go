@@ -20,6 +20,24 @@ func TestSubmatrix(t *testing.T) {
 	}
 }
 
+func TestRows(t *testing.T) {
+	data := Dataset{
+		X:        mat.NewDense(3, 2, []float64{1.0, 2.0, 3.0, 4.0, 5.0, 6.0}),
+		Y:        mat.NewVecDense(3, []float64{-1.0, -2.0, -3.0}),
+		ColNames: []string{"feat1", "feat2"},
+	}
+
+	want := Dataset{
+		X:        mat.NewDense(3, 2, []float64{5.0, 6.0, 1.0, 2.0, 5.0, 6.0}),
+		Y:        mat.NewVecDense(3, []float64{-3.0, -1.0, -3.0}),
+		ColNames: []string{"feat1", "feat2"},
+	}
+
+	if got := data.Rows([]int{2, 0, 2}); !got.IsEqual(want) {
+		t.Errorf("Want\n%v\ngot\n%v\n", want, got)
+	}
+}
+
 func TestColumns(t *testing.T) {
 	data := Dataset{
 		X:        mat.NewDense(3, 3, []float64{1.0, 2.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0, 9.0}),
@@ -0,0 +1,98 @@
+package gafit
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// ResidualDiagnostics holds the quantities used in residual plots. All slices are ordered as
+// the rows of the data
+type ResidualDiagnostics struct {
+	Predicted []float64
+	Residuals []float64
+
+	// Leverage is the diagonal of the hat matrix (see Leverages). Leverage, Studentized and
+	// CooksDistance are nil unless the leverages are requested
+	Leverage []float64
+
+	// Studentized holds the internally studentized residuals e_i/(s sqrt(1 - h_i)), where s
+	// is the residual standard error and h_i is the leverage. It is NaN if the leverage is 1
+	Studentized []float64
+
+	// CooksDistance is r_i^2 h_i/(p(1 - h_i)), where r_i is the studentized residual and p
+	// is the number of coefficients
+	CooksDistance []float64
+
+	// NumParams is the number of coefficients, including the intercept
+	NumParams int
+}
+
+// NewResidualDiagnostics calculates the residuals of a regression model on data. If leverage
+// is true, the leverages, studentized residuals and Cook's distances are calculated as well.
+// The leverages refer to data, which should be the training data
+func NewResidualDiagnostics(model Model, data Dataset, leverage bool) ResidualDiagnostics {
+	pred := model.Predict(data)
+	n := data.NumData()
+	p := model.CoeffVector().Len()
+
+	diag := ResidualDiagnostics{
+		Predicted: make([]float64, n),
+		Residuals: make([]float64, n),
+		NumParams: p,
+	}
+
+	rss := 0.0
+	for i := 0; i < n; i++ {
+		diag.Predicted[i] = pred.AtVec(i)
+		diag.Residuals[i] = data.Y.AtVec(i) - diag.Predicted[i]
+		rss += diag.Residuals[i] * diag.Residuals[i]
+	}
+
+	if !leverage {
+		return diag
+	}
+
+	dof := n - p
+	if dof <= 0 {
+		dof = 1
+	}
+	s := math.Sqrt(rss / float64(dof))
+
+	diag.Leverage = Leverages(model.DesignMatrix(data))
+	diag.Studentized = make([]float64, n)
+	diag.CooksDistance = make([]float64, n)
+	for i, h := range diag.Leverage {
+		if h >= 1.0 {
+			diag.Studentized[i] = math.NaN()
+			diag.CooksDistance[i] = math.NaN()
+			continue
+		}
+		r := diag.Residuals[i] / (s * math.Sqrt(1.0-h))
+		diag.Studentized[i] = r
+		diag.CooksDistance[i] = r * r * h / (float64(p) * (1.0 - h))
+	}
+	return diag
+}
+
+// CooksDistanceContour returns the absolute value of the studentized residual at which an
+// observation with leverage h has Cook's distance d
+func (rd ResidualDiagnostics) CooksDistanceContour(d float64, h float64) float64 {
+	return math.Sqrt(d * float64(rd.NumParams) * (1.0 - h) / h)
+}
+
+// NormalQuantiles returns the quantiles of the standard normal distribution at the plotting
+// positions (i - a)/(n + 1 - 2a), i = 1...n, where a = 3/8 for n <= 10 and 1/2 otherwise. They
+// are used as the theoretical quantiles of normal Q-Q plots
+func NormalQuantiles(n int) []float64 {
+	a := 0.5
+	if n <= 10 {
+		a = 3.0 / 8.0
+	}
+
+	q := make([]float64, n)
+	for i := range q {
+		q[i] = distuv.UnitNormal.Quantile((float64(i+1) - a) / (float64(n) + 1.0 - 2.0*a))
+	}
+	return q
+}
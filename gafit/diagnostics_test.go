@@ -0,0 +1,85 @@
+package gafit
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestResidualDiagnostics(t *testing.T) {
+	// Same regression problem as in TestInferenceRoundTrip. The leverage of a simple
+	// regression is 1/n + (x - mean)^2/sum (x - mean)^2
+	data := Dataset{
+		X:          mat.NewDense(4, 1, []float64{0.0, 1.0, 2.0, 3.0}),
+		Y:          mat.NewVecDense(4, []float64{0.1, 0.9, 2.1, 2.9}),
+		ColNames:   []string{"x"},
+		TargetName: "y",
+	}
+	model := Model{
+		TargetName:      "y",
+		Coeffs:          map[string]float64{"x": 0.96},
+		Intercept:       0.06,
+		Standardization: &Standardization{Means: map[string]float64{"x": 0.0}, Scales: map[string]float64{"x": 1.0}},
+	}
+
+	diag := NewResidualDiagnostics(model, data, true)
+	if diag.NumParams != 2 {
+		t.Errorf("Expected 2 parameters got %d\n", diag.NumParams)
+	}
+
+	// Without leverages only the residuals are calculated
+	resid := NewResidualDiagnostics(model, data, false)
+	if resid.Leverage != nil || resid.Studentized != nil || resid.CooksDistance != nil || !reflect.DeepEqual(resid.Residuals, diag.Residuals) {
+		t.Errorf("Expected only residuals. Got %+v\n", resid)
+	}
+
+	s := math.Sqrt(0.016)
+	wantResid := []float64{0.04, -0.12, 0.12, -0.04}
+	wantLeverage := []float64{0.7, 0.3, 0.3, 0.7}
+	tol := 1e-10
+	for i := range wantResid {
+		r := wantResid[i] / (s * math.Sqrt(1.0-wantLeverage[i]))
+		cook := r * r * wantLeverage[i] / (2.0 * (1.0 - wantLeverage[i]))
+		for _, test := range []struct {
+			name      string
+			got, want float64
+		}{
+			{"residual", diag.Residuals[i], wantResid[i]},
+			{"predicted", diag.Predicted[i], data.Y.AtVec(i) - wantResid[i]},
+			{"leverage", diag.Leverage[i], wantLeverage[i]},
+			{"studentized", diag.Studentized[i], r},
+			{"cook", diag.CooksDistance[i], cook},
+		} {
+			if math.Abs(test.got-test.want) > tol {
+				t.Errorf("Row %d %s: Expected %f got %f\n", i, test.name, test.want, test.got)
+			}
+		}
+
+		// The contour at the Cook's distance of the point passes through the point
+		if c := diag.CooksDistanceContour(cook, wantLeverage[i]); math.Abs(c-math.Abs(r)) > tol {
+			t.Errorf("Row %d: Expected contour at %f got %f\n", i, math.Abs(r), c)
+		}
+	}
+}
+
+func TestNormalQuantiles(t *testing.T) {
+	for i, test := range []struct {
+		n    int
+		want []float64
+	}{
+		{1, []float64{0.0}},
+		{3, []float64{-0.8694238, 0.0, 0.8694238}},
+		{12, []float64{-1.7316644, -1.1503494, -0.8122178, -0.5485223, -0.3186394, -0.1046335,
+			0.1046335, 0.3186394, 0.5485223, 0.8122178, 1.1503494, 1.7316644}},
+	} {
+		got := NormalQuantiles(test.n)
+		for j := range test.want {
+			if math.Abs(got[j]-test.want[j]) > 1e-6 {
+				t.Errorf("Test #%d: Expected %v got %v\n", i, test.want, got)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,830 @@
+package gafit
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidkleiven/gogafit/elm"
+)
+
+// ExportLanguages holds the languages supported by ExportModel
+var ExportLanguages = []string{"go", "python", "c", "sql"}
+
+// ExportOptions controls the code generated by ExportModel
+type ExportOptions struct {
+	// Lang is one of ExportLanguages
+	Lang string
+
+	// FuncName is the name of the generated function. If empty, Predict is used for Go
+	// and predict for Python and C. It is not used for SQL
+	FuncName string
+
+	// Package is the package of generated Go code. If empty, model is used
+	Package string
+
+	// Table is the table queried by generated SQL. If empty, data is used
+	Table string
+}
+
+// ExportedModel holds the code generated by ExportModel. Inputs holds the names of the input
+// columns in the order they are passed to the generated function
+type ExportedModel struct {
+	Inputs []string
+	Code   string
+}
+
+// ExportModel generates a self-contained function in the requested language that calculates
+// the prediction of a regression model for a single row. The function takes the raw input
+// columns (i.e. before the pipeline of the model is applied) and evaluates the pipeline steps,
+// the coefficients and the intercept. Categorical columns are passed as their indicator
+// columns (e.g. material_steel). Only the pipeline outputs used by the model are calculated.
+// For SQL, a query that adds a prediction column to all rows of a table is generated.
+func ExportModel(model Model, opts ExportOptions) (ExportedModel, error) {
+	lang, ok := codeLanguages[opts.Lang]
+	if !ok {
+		return ExportedModel{}, fmt.Errorf("Unknown language %s. Must be one of %v", opts.Lang, ExportLanguages)
+	}
+
+	if model.IsClassifier() {
+		return ExportedModel{}, errors.New("Export of classification models is not supported")
+	}
+
+	if opts.FuncName == "" {
+		opts.FuncName = lang.defaultFunc
+	}
+	if opts.Package == "" {
+		opts.Package = "model"
+	}
+	if opts.Table == "" {
+		opts.Table = "data"
+	}
+
+	prog, err := newCodeProgram(model)
+	if err != nil {
+		return ExportedModel{}, err
+	}
+	prog.assignIdentifiers(lang, opts.FuncName)
+
+	r := codeRenderer{lang: lang, funcName: opts.FuncName, helpers: make(map[string]bool)}
+	exported := ExportedModel{Inputs: make([]string, len(prog.inputs))}
+	for i, v := range prog.inputs {
+		exported.Inputs[i] = v.name
+	}
+	exported.Code = lang.write(&r, prog, model, opts)
+	return exported, nil
+}
+
+// codeVar is a named value in the generated code. Inputs have no expression
+type codeVar struct {
+	name  string
+	expr  codeExpr
+	ident string
+}
+
+// codeExpr is a node in the expression tree of the generated code
+type codeExpr interface {
+	// vars adds the variables referenced by the expression to used
+	vars(used map[*codeVar]bool)
+}
+
+type numExpr float64
+
+type refExpr struct {
+	v *codeVar
+}
+
+// binExpr is a binary operation, where op is one of + - * / ^
+type binExpr struct {
+	op          byte
+	left, right codeExpr
+}
+
+// callExpr calls a function in ExpressionFunctions, an activation function (see
+// elm.Activations) or negates its argument (fn is -)
+type callExpr struct {
+	fn  string
+	arg codeExpr
+}
+
+// sumExpr is the linear combination constant + sum_i coeffs[i]*terms[i]
+type sumExpr struct {
+	constant float64
+	coeffs   []float64
+	terms    []codeExpr
+}
+
+func (e numExpr) vars(used map[*codeVar]bool)  {}
+func (e refExpr) vars(used map[*codeVar]bool)  { used[e.v] = true }
+func (e binExpr) vars(used map[*codeVar]bool)  { e.left.vars(used); e.right.vars(used) }
+func (e callExpr) vars(used map[*codeVar]bool) { e.arg.vars(used) }
+func (e sumExpr) vars(used map[*codeVar]bool) {
+	// Terms with zero coefficients are omitted from the generated code
+	for i, t := range e.terms {
+		if e.coeffs[i] != 0.0 {
+			t.vars(used)
+		}
+	}
+}
+
+// codeProgram holds the inputs, the intermediate variables (in evaluation order) and the
+// expression for the prediction
+type codeProgram struct {
+	inputs []*codeVar
+	body   []*codeVar
+	result codeExpr
+}
+
+// codeBuilder translates the pipeline of a model into variables. scope maps column names to
+// the variables holding them. As long as open is true, unknown columns are raw inputs. After
+// a step that replaces all columns (ELM and kernel), only the outputs of the step are known
+type codeBuilder struct {
+	vars  []*codeVar
+	scope map[string]*codeVar
+	open  bool
+}
+
+func (b *codeBuilder) ref(name string) (codeExpr, error) {
+	if v, ok := b.scope[name]; ok {
+		return refExpr{v}, nil
+	}
+
+	if !b.open {
+		return nil, fmt.Errorf("Column %s is not available after the previous pipeline step", name)
+	}
+
+	v := &codeVar{name: name}
+	b.vars = append(b.vars, v)
+	b.scope[name] = v
+	return refExpr{v}, nil
+}
+
+func (b *codeBuilder) refs(names []string) ([]codeExpr, error) {
+	res := make([]codeExpr, len(names))
+	for i, name := range names {
+		var err error
+		if res[i], err = b.ref(name); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// temp adds a variable that is not visible as a column
+func (b *codeBuilder) temp(name string, expr codeExpr) codeExpr {
+	v := &codeVar{name: name, expr: expr}
+	b.vars = append(b.vars, v)
+	return refExpr{v}
+}
+
+func (b *codeBuilder) define(name string, expr codeExpr) {
+	v := &codeVar{name: name, expr: expr}
+	b.vars = append(b.vars, v)
+	b.scope[name] = v
+}
+
+// scaled applies the input scaling of an ELM layer or a kernel
+func (b *codeBuilder) scaled(names []string, inputs []codeExpr, scaling *elm.InputScaling) []codeExpr {
+	if scaling == nil {
+		return inputs
+	}
+
+	res := make([]codeExpr, len(inputs))
+	for i, x := range inputs {
+		expr := binExpr{'/', binExpr{'-', x, numExpr(scaling.Offsets[i])}, numExpr(scaling.Scales[i])}
+		res[i] = b.temp(names[i]+"_scaled", expr)
+	}
+	return res
+}
+
+func (b *codeBuilder) addStep(step PipelineStep) error {
+	switch {
+	case step.Poly != nil:
+		if step.Poly.Order < 2 {
+			return nil
+		}
+		for _, col := range step.Poly.Columns {
+			x, err := b.ref(col)
+			if err != nil {
+				return err
+			}
+			for power := 2; power < step.Poly.Order+1; power++ {
+				b.define(fmt.Sprintf("%sp%d", col, power), binExpr{'^', x, numExpr(power)})
+			}
+		}
+	case step.Features != nil:
+		// All features are calculated from the columns prior to the step
+		exprs := make([]codeExpr, len(step.Features.Features))
+		for i, spec := range step.Features.Features {
+			parsed, err := ParseExpression(spec.Expr)
+			if err != nil {
+				return err
+			}
+			if exprs[i], err = b.expression(parsed.root); err != nil {
+				return err
+			}
+		}
+		for i, spec := range step.Features.Features {
+			b.define(spec.Name, exprs[i])
+		}
+	case step.ELM != nil:
+		layer := step.ELM.Layer
+		inputs, err := b.refs(layer.Inputs)
+		if err != nil {
+			return err
+		}
+		inputs = b.scaled(layer.Inputs, inputs, layer.Scaling)
+
+		b.scope = make(map[string]*codeVar)
+		b.open = false
+		for i, neuron := range layer.Neurons {
+			activation, err := elm.ActivationName(neuron.ActivationFunc)
+			if err != nil {
+				return err
+			}
+			sum := sumExpr{constant: neuron.Bias, coeffs: neuron.Weights, terms: inputs}
+			b.define(layer.Names[i], activationExpr(activation, sum))
+		}
+	case step.Kernel != nil:
+		k := step.Kernel.Kernel
+		inputs, err := b.refs(k.Inputs)
+		if err != nil {
+			return err
+		}
+		inputs = b.scaled(k.Inputs, inputs, k.Scaling)
+
+		b.scope = make(map[string]*codeVar)
+		b.open = false
+		for i, center := range k.Centers {
+			b.define(k.Names[i], kernelExpr(k, inputs, center))
+		}
+	default:
+		return errors.New("Empty pipeline step")
+	}
+	return nil
+}
+
+// expression converts a parsed feature expression
+func (b *codeBuilder) expression(node exprNode) (codeExpr, error) {
+	switch n := node.(type) {
+	case numberNode:
+		return numExpr(n.value), nil
+	case variableNode:
+		return b.ref(n.name)
+	case unaryNode:
+		arg, err := b.expression(n.arg)
+		if err != nil {
+			return nil, err
+		}
+		return callExpr{n.name, arg}, nil
+	case binaryNode:
+		left, err := b.expression(n.left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := b.expression(n.right)
+		if err != nil {
+			return nil, err
+		}
+		return binExpr{n.op, left, right}, nil
+	}
+	return nil, fmt.Errorf("Unknown expression node %T", node)
+}
+
+// activationExpr applies an activation function. Activations that correspond to an
+// expression function are translated to that function
+func activationExpr(activation string, arg codeExpr) codeExpr {
+	switch activation {
+	case "tanh":
+		return callExpr{"tanh", arg}
+	case "sine":
+		return callExpr{"sin", arg}
+	}
+	return callExpr{activation, arg}
+}
+
+// kernelExpr returns the kernel between the inputs and a center (see elm.Kernel.Eval)
+func kernelExpr(k elm.Kernel, inputs []codeExpr, center []float64) codeExpr {
+	if k.Type == "poly" {
+		dot := sumExpr{coeffs: center, terms: inputs}
+		base := sumExpr{constant: k.Coef0, coeffs: []float64{k.Gamma}, terms: []codeExpr{dot}}
+		return binExpr{'^', base, numExpr(k.Degree)}
+	}
+
+	dist := sumExpr{coeffs: make([]float64, len(inputs)), terms: make([]codeExpr, len(inputs))}
+	for i, x := range inputs {
+		diff := binExpr{'-', x, numExpr(center[i])}
+		dist.coeffs[i] = 1.0
+		dist.terms[i] = binExpr{'*', diff, diff}
+	}
+	return callExpr{"exp", binExpr{'*', numExpr(-k.Gamma), dist}}
+}
+
+// newCodeProgram translates the pipeline and the coefficients of a model. Variables that
+// are not needed for the prediction are removed
+func newCodeProgram(model Model) (codeProgram, error) {
+	b := codeBuilder{scope: make(map[string]*codeVar), open: true}
+	if model.Pipeline != nil {
+		for i, step := range model.Pipeline.Steps {
+			if err := b.addStep(step); err != nil {
+				return codeProgram{}, fmt.Errorf("Pipeline step %d: %s", i, err)
+			}
+		}
+	}
+
+	features := model.Features()
+	result := sumExpr{constant: model.Intercept, coeffs: make([]float64, len(features))}
+	for i, name := range features {
+		x, err := b.ref(name)
+		if err != nil {
+			return codeProgram{}, err
+		}
+		result.coeffs[i] = model.Coeffs[name]
+		result.terms = append(result.terms, x)
+	}
+
+	// Variables only depend on variables created before them
+	used := make(map[*codeVar]bool)
+	result.vars(used)
+	for i := len(b.vars) - 1; i >= 0; i-- {
+		if v := b.vars[i]; used[v] && v.expr != nil {
+			v.expr.vars(used)
+		}
+	}
+
+	prog := codeProgram{result: result}
+	for _, v := range b.vars {
+		if !used[v] {
+			continue
+		}
+		if v.expr == nil {
+			prog.inputs = append(prog.inputs, v)
+		} else {
+			prog.body = append(prog.body, v)
+		}
+	}
+	sort.Slice(prog.inputs, func(i, j int) bool { return prog.inputs[i].name < prog.inputs[j].name })
+	return prog, nil
+}
+
+// assignIdentifiers gives all variables a unique identifier that is valid in the language
+func (p codeProgram) assignIdentifiers(lang codeLanguage, funcName string) {
+	taken := map[string]bool{funcName: true}
+	for _, name := range lang.reserved {
+		taken[name] = true
+	}
+	for name := range elm.Activations {
+		taken[lang.helperName(funcName, name)] = true
+	}
+
+	for _, v := range append(append([]*codeVar{}, p.inputs...), p.body...) {
+		ident := identifier(v.name)
+		for i := 1; taken[ident]; i++ {
+			ident = fmt.Sprintf("%s_%d", identifier(v.name), i)
+		}
+		taken[ident] = true
+		v.ident = ident
+	}
+}
+
+// identifier converts a column name into an identifier, by replacing all characters except
+// ASCII letters, digits and underscores by underscores
+func identifier(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+
+	ident := sb.String()
+	if ident == "" || (ident[0] >= '0' && ident[0] <= '9') {
+		ident = "v_" + ident
+	}
+	return ident
+}
+
+// formatNumber formats a number such that it is a floating point literal in all languages
+func formatNumber(v float64) string {
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// codeLanguage describes how expressions and functions are written in a language
+type codeLanguage struct {
+	defaultFunc string
+	reserved    []string
+
+	// functions maps the functions in ExpressionFunctions to format strings taking the argument
+	functions map[string]string
+	pow       string
+
+	// inline is true if intermediate variables are substituted into the expressions (SQL)
+	inline bool
+
+	// input formats a reference to an input when inline is true
+	input func(name string) string
+
+	// activation returns the format string used to call an activation function. The
+	// argument can be used several times (%[1]s)
+	activation func(funcName, name string) string
+
+	// helper returns the definition of the function implementing an activation function
+	helper func(funcName, name string) string
+
+	helperName func(funcName, name string) string
+
+	write func(r *codeRenderer, prog codeProgram, model Model, opts ExportOptions) string
+}
+
+// codeRenderer renders expressions. Helpers collects the activation functions used
+type codeRenderer struct {
+	lang     codeLanguage
+	funcName string
+	helpers  map[string]bool
+}
+
+// expr renders an expression. Composite expressions are enclosed in parentheses unless top is true
+func (r *codeRenderer) expr(e codeExpr, top bool) string {
+	wrap := func(s string) string {
+		if top {
+			return s
+		}
+		return "(" + s + ")"
+	}
+
+	switch n := e.(type) {
+	case numExpr:
+		s := formatNumber(float64(n))
+		if n < 0 && !top {
+			return "(" + s + ")"
+		}
+		return s
+	case refExpr:
+		if !r.lang.inline {
+			return n.v.ident
+		}
+		if n.v.expr == nil {
+			return r.lang.input(n.v.name)
+		}
+		return r.expr(n.v.expr, top)
+	case binExpr:
+		if n.op == '^' {
+			return fmt.Sprintf(r.lang.pow, r.expr(n.left, true), r.expr(n.right, true))
+		}
+		return wrap(fmt.Sprintf("%s %c %s", r.expr(n.left, false), n.op, r.expr(n.right, false)))
+	case callExpr:
+		if n.fn == "-" {
+			return wrap("-" + r.expr(n.arg, false))
+		}
+		if format, ok := r.lang.functions[n.fn]; ok {
+			return fmt.Sprintf(format, r.expr(n.arg, true))
+		}
+		// Inlined activations use the argument as an operand, while the other languages
+		// pass it to a helper function
+		r.helpers[n.fn] = true
+		return fmt.Sprintf(r.lang.activation(r.funcName, n.fn), r.expr(n.arg, !r.lang.inline))
+	case sumExpr:
+		return r.sum(n, top)
+	}
+	panic(fmt.Sprintf("Unknown expression %T", e))
+}
+
+// sum renders a linear combination, where terms with zero coefficients are omitted
+func (r *codeRenderer) sum(s sumExpr, top bool) string {
+	parts := []string{}
+	if s.constant != 0.0 {
+		parts = append(parts, formatNumber(s.constant))
+	}
+
+	for i, term := range s.terms {
+		c := s.coeffs[i]
+		if c == 0.0 {
+			continue
+		}
+
+		sign := "+"
+		if c < 0.0 {
+			sign = "-"
+			c = -c
+		}
+
+		str := r.expr(term, false)
+		if c != 1.0 {
+			str = formatNumber(c) + "*" + str
+		}
+
+		if len(parts) == 0 && sign == "+" {
+			parts = append(parts, str)
+		} else if len(parts) == 0 {
+			parts = append(parts, "-"+str)
+		} else {
+			parts = append(parts, sign+" "+str)
+		}
+	}
+
+	switch len(parts) {
+	case 0:
+		return "0.0"
+	case 1:
+		if top || !strings.HasPrefix(parts[0], "-") {
+			return parts[0]
+		}
+	}
+
+	if top {
+		return strings.Join(parts, " ")
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// sortedHelpers returns the names of the activation functions used
+func (r *codeRenderer) sortedHelpers() []string {
+	names := []string{}
+	for name := range r.helpers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// modelDescription describes the model in the comment of the generated code
+func modelDescription(model Model) string {
+	desc := "the model of " + model.TargetName
+	if model.Datafile != "" {
+		desc += " (fitted to " + model.Datafile + ")"
+	}
+	return desc
+}
+
+// inputDescription lists the input columns. The identifier of the argument is shown if
+// it differs from the column name
+func inputDescription(prog codeProgram, prefix string) string {
+	var sb strings.Builder
+	for _, v := range prog.inputs {
+		sb.WriteString(prefix + "  " + v.name)
+		if v.ident != v.name {
+			sb.WriteString(" (" + v.ident + ")")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+var codeLanguages = map[string]codeLanguage{
+	"go": {
+		defaultFunc: "Predict",
+		reserved: []string{
+			"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough",
+			"for", "func", "go", "goto", "if", "import", "interface", "map", "package", "range",
+			"return", "select", "struct", "switch", "type", "var", "math", "float64", "int", "bool",
+			"string", "true", "false", "nil",
+		},
+		functions: map[string]string{
+			"sin": "math.Sin(%s)", "cos": "math.Cos(%s)", "tan": "math.Tan(%s)", "tanh": "math.Tanh(%s)",
+			"exp": "math.Exp(%s)", "log": "math.Log(%s)", "log10": "math.Log10(%s)",
+			"sqrt": "math.Sqrt(%s)", "abs": "math.Abs(%s)",
+		},
+		pow:        "math.Pow(%s, %s)",
+		activation: func(funcName, name string) string { return goHelperName(funcName, name) + "(%[1]s)" },
+		helper:     goHelper,
+		helperName: goHelperName,
+		write:      writeGo,
+	},
+	"python": {
+		defaultFunc: "predict",
+		reserved: []string{
+			"False", "None", "True", "and", "as", "assert", "async", "await", "break", "class",
+			"continue", "def", "del", "elif", "else", "except", "finally", "for", "from", "global",
+			"if", "import", "in", "is", "lambda", "nonlocal", "not", "or", "pass", "raise", "return",
+			"try", "while", "with", "yield", "math", "abs", "max",
+		},
+		functions: map[string]string{
+			"sin": "math.sin(%s)", "cos": "math.cos(%s)", "tan": "math.tan(%s)", "tanh": "math.tanh(%s)",
+			"exp": "math.exp(%s)", "log": "math.log(%s)", "log10": "math.log10(%s)",
+			"sqrt": "math.sqrt(%s)", "abs": "abs(%s)",
+		},
+		pow:        "math.pow(%s, %s)",
+		activation: func(funcName, name string) string { return snakeHelperName(funcName, name) + "(%[1]s)" },
+		helper:     pythonHelper,
+		helperName: snakeHelperName,
+		write:      writePython,
+	},
+	"c": {
+		defaultFunc: "predict",
+		reserved: []string{
+			"auto", "break", "case", "char", "const", "continue", "default", "do", "double", "else",
+			"enum", "extern", "float", "for", "goto", "if", "inline", "int", "long", "register",
+			"restrict", "return", "short", "signed", "sizeof", "static", "struct", "switch",
+			"typedef", "union", "unsigned", "void", "volatile", "while", "sin", "cos", "tan", "tanh",
+			"exp", "log", "log10", "log1p", "sqrt", "fabs", "fmax", "pow",
+		},
+		functions: map[string]string{
+			"sin": "sin(%s)", "cos": "cos(%s)", "tan": "tan(%s)", "tanh": "tanh(%s)",
+			"exp": "exp(%s)", "log": "log(%s)", "log10": "log10(%s)",
+			"sqrt": "sqrt(%s)", "abs": "fabs(%s)",
+		},
+		pow:        "pow(%s, %s)",
+		activation: func(funcName, name string) string { return snakeHelperName(funcName, name) + "(%[1]s)" },
+		helper:     cHelper,
+		helperName: snakeHelperName,
+		write:      writeC,
+	},
+	"sql": {
+		functions: map[string]string{
+			"sin": "SIN(%s)", "cos": "COS(%s)", "tan": "TAN(%s)", "tanh": "TANH(%s)",
+			"exp": "EXP(%s)", "log": "LN(%s)", "log10": "LOG10(%s)",
+			"sqrt": "SQRT(%s)", "abs": "ABS(%s)",
+		},
+		pow:        "POWER(%s, %s)",
+		inline:     true,
+		input:      func(name string) string { return "CAST(" + quoteSQL(name) + " AS DOUBLE PRECISION)" },
+		activation: sqlActivation,
+		helperName: func(funcName, name string) string { return name },
+		write:      writeSQL,
+	},
+}
+
+func goHelperName(funcName, name string) string {
+	return strings.ToLower(funcName[:1]) + funcName[1:] + strings.ToUpper(name[:1]) + name[1:]
+}
+
+func snakeHelperName(funcName, name string) string {
+	return funcName + "_" + name
+}
+
+func goHelper(funcName, name string) string {
+	body := ""
+	switch name {
+	case "sigmoid":
+		body = "\treturn 1.0 / (1.0 + math.Exp(-x))\n"
+	case "gaussian":
+		body = "\treturn math.Exp(-x * x)\n"
+	case "softplus":
+		body = "\tif x > 0.0 {\n\t\treturn x + math.Log1p(math.Exp(-x))\n\t}\n\treturn math.Log1p(math.Exp(x))\n"
+	case "relu":
+		body = "\tif x < 0.0 {\n\t\treturn 0.0\n\t}\n\treturn x\n"
+	case "leakyrelu":
+		body = "\tif x < 0.0 {\n\t\treturn " + formatNumber(elm.LeakyReluSlope) + " * x\n\t}\n\treturn x\n"
+	case "hardlimit":
+		body = "\tif x < 0.0 {\n\t\treturn 0.0\n\t}\n\treturn 1.0\n"
+	}
+	return fmt.Sprintf("func %s(x float64) float64 {\n%s}\n", goHelperName(funcName, name), body)
+}
+
+func cHelper(funcName, name string) string {
+	body := ""
+	switch name {
+	case "sigmoid":
+		body = "    return 1.0 / (1.0 + exp(-x));\n"
+	case "gaussian":
+		body = "    return exp(-x * x);\n"
+	case "softplus":
+		body = "    if (x > 0.0) return x + log1p(exp(-x));\n    return log1p(exp(x));\n"
+	case "relu":
+		body = "    return x < 0.0 ? 0.0 : x;\n"
+	case "leakyrelu":
+		body = "    return x < 0.0 ? " + formatNumber(elm.LeakyReluSlope) + " * x : x;\n"
+	case "hardlimit":
+		body = "    return x < 0.0 ? 0.0 : 1.0;\n"
+	}
+	return fmt.Sprintf("static double %s(double x)\n{\n%s}\n", snakeHelperName(funcName, name), body)
+}
+
+func pythonHelper(funcName, name string) string {
+	body := ""
+	switch name {
+	case "sigmoid":
+		// Equal to 1/(1 + exp(-x)), but does not overflow for large negative x
+		body = "    return 0.5 * (1.0 + math.tanh(0.5 * x))\n"
+	case "gaussian":
+		body = "    return math.exp(-x * x)\n"
+	case "softplus":
+		body = "    return max(x, 0.0) + math.log1p(math.exp(-abs(x)))\n"
+	case "relu":
+		body = "    return x if x >= 0.0 else 0.0\n"
+	case "leakyrelu":
+		body = "    return x if x >= 0.0 else " + formatNumber(elm.LeakyReluSlope) + " * x\n"
+	case "hardlimit":
+		body = "    return 1.0 if x >= 0.0 else 0.0\n"
+	}
+	return fmt.Sprintf("def %s(x):\n%s", snakeHelperName(funcName, name), body)
+}
+
+func sqlActivation(funcName, name string) string {
+	switch name {
+	case "sigmoid":
+		return "(1.0 / (1.0 + EXP(-%[1]s)))"
+	case "gaussian":
+		return "EXP(-%[1]s * %[1]s)"
+	case "softplus":
+		return "(CASE WHEN %[1]s > 0.0 THEN %[1]s ELSE 0.0 END + LN(1.0 + EXP(-ABS(%[1]s))))"
+	case "relu":
+		return "(CASE WHEN %[1]s < 0.0 THEN 0.0 ELSE %[1]s END)"
+	case "leakyrelu":
+		return "(CASE WHEN %[1]s < 0.0 THEN " + formatNumber(elm.LeakyReluSlope) + " * %[1]s ELSE %[1]s END)"
+	}
+	return "(CASE WHEN %[1]s < 0.0 THEN 0.0 ELSE 1.0 END)"
+}
+
+// quoteSQL quotes an identifier in SQL
+func quoteSQL(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func writeGo(r *codeRenderer, prog codeProgram, model Model, opts ExportOptions) string {
+	var fn strings.Builder
+	params := make([]string, len(prog.inputs))
+	for i, v := range prog.inputs {
+		params[i] = v.ident
+	}
+
+	fmt.Fprintf(&fn, "// %s returns the prediction of %s.\n", opts.FuncName, modelDescription(model))
+	fmt.Fprintf(&fn, "// The arguments are the input columns\n//\n%s", inputDescription(prog, "//"))
+	signature := ""
+	if len(params) > 0 {
+		signature = strings.Join(params, ", ") + " float64"
+	}
+	fmt.Fprintf(&fn, "func %s(%s) float64 {\n", opts.FuncName, signature)
+	for _, v := range prog.body {
+		fmt.Fprintf(&fn, "\t%s := %s\n", v.ident, r.expr(v.expr, true))
+	}
+	fmt.Fprintf(&fn, "\treturn %s\n}\n", r.expr(prog.result, true))
+
+	for _, name := range r.sortedHelpers() {
+		fmt.Fprintf(&fn, "\n%s", r.lang.helper(r.funcName, name))
+	}
+
+	code := "// Code generated by gogafit export. DO NOT EDIT.\n\npackage " + opts.Package + "\n\n"
+	if strings.Contains(fn.String(), "math.") {
+		code += "import \"math\"\n\n"
+	}
+	return code + fn.String()
+}
+
+func writePython(r *codeRenderer, prog codeProgram, model Model, opts ExportOptions) string {
+	var fn strings.Builder
+	params := make([]string, len(prog.inputs))
+	for i, v := range prog.inputs {
+		params[i] = v.ident
+	}
+
+	fmt.Fprintf(&fn, "def %s(%s):\n", opts.FuncName, strings.Join(params, ", "))
+	fmt.Fprintf(&fn, "    \"\"\"Return the prediction of %s.\n\n", modelDescription(model))
+	fmt.Fprintf(&fn, "    The arguments are the input columns\n\n%s    \"\"\"\n", inputDescription(prog, "  "))
+	for _, v := range prog.body {
+		fmt.Fprintf(&fn, "    %s = %s\n", v.ident, r.expr(v.expr, true))
+	}
+	fmt.Fprintf(&fn, "    return %s\n", r.expr(prog.result, true))
+
+	for _, name := range r.sortedHelpers() {
+		fmt.Fprintf(&fn, "\n\n%s", r.lang.helper(r.funcName, name))
+	}
+	code := "# Code generated by gogafit export. DO NOT EDIT.\n"
+	if strings.Contains(fn.String(), "math.") {
+		code += "import math\n"
+	}
+	return code + "\n\n" + fn.String()
+}
+
+func writeC(r *codeRenderer, prog codeProgram, model Model, opts ExportOptions) string {
+	var fn strings.Builder
+	params := make([]string, len(prog.inputs))
+	for i, v := range prog.inputs {
+		params[i] = "double " + v.ident
+	}
+	if len(params) == 0 {
+		params = []string{"void"}
+	}
+
+	fmt.Fprintf(&fn, "/*\n * %s returns the prediction of %s.\n", opts.FuncName, modelDescription(model))
+	fmt.Fprintf(&fn, " * The arguments are the input columns\n *\n%s */\n", inputDescription(prog, " *"))
+	fmt.Fprintf(&fn, "double %s(%s)\n{\n", opts.FuncName, strings.Join(params, ", "))
+	for _, v := range prog.body {
+		fmt.Fprintf(&fn, "    const double %s = %s;\n", v.ident, r.expr(v.expr, true))
+	}
+	fmt.Fprintf(&fn, "    return %s;\n}\n", r.expr(prog.result, true))
+
+	// Helpers must be defined before they are used
+	code := "/* Code generated by gogafit export. DO NOT EDIT. */\n#include <math.h>\n\n"
+	for _, name := range r.sortedHelpers() {
+		code += r.lang.helper(r.funcName, name) + "\n"
+	}
+	return code + fn.String()
+}
+
+func writeSQL(r *codeRenderer, prog codeProgram, model Model, opts ExportOptions) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "-- Code generated by gogafit export. DO NOT EDIT.\n")
+	fmt.Fprintf(&sb, "-- Adds the prediction of %s to all rows of %s.\n", modelDescription(model), opts.Table)
+	fmt.Fprintf(&sb, "-- The table must hold the input columns\n--\n%s", inputDescription(prog, "--"))
+	fmt.Fprintf(&sb, "SELECT *, %s AS \"prediction\" FROM %s;\n", r.expr(prog.result, true), quoteSQL(opts.Table))
+	return sb.String()
+}
@@ -0,0 +1,300 @@
+package gafit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/davidkleiven/gogafit/elm"
+	"gonum.org/v1/gonum/mat"
+)
+
+// exportTestData holds the raw input columns used to evaluate exported code
+func exportTestData() Dataset {
+	return Dataset{
+		X: mat.NewDense(4, 3, []float64{
+			0.5, 1.2, -3.0,
+			-1.3, 0.4, 2.0,
+			2.1, 3.3, 0.1,
+			0.0, 0.7, -0.5,
+		}),
+		ColNames: []string{"a", "b", "c d"},
+	}
+}
+
+func exportTestModels(t *testing.T) []struct {
+	name   string
+	model  Model
+	inputs []string
+} {
+	names := make([]string, 0, len(elm.Activations))
+	for name := range elm.Activations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	layer := elm.Layer{
+		Inputs:  []string{"a", "b"},
+		Scaling: &elm.InputScaling{Method: "standard", Offsets: []float64{0.3, 1.4}, Scales: []float64{1.2, 1.1}},
+	}
+	elmCoeffs := make(map[string]float64)
+	for i, name := range names {
+		layer.Names = append(layer.Names, fmt.Sprintf("h%d", i))
+		layer.Neurons = append(layer.Neurons, elm.Neuron{
+			Weights:        []float64{0.8 - 0.3*float64(i), 0.5 + 0.1*float64(i)},
+			Bias:           0.2*float64(i) - 0.7,
+			ActivationFunc: elm.Activations[name],
+		})
+		elmCoeffs[layer.Names[i]] = 1.0 - 0.25*float64(i)
+	}
+
+	centers := mat.NewDense(3, 2, []float64{0.1, 0.9, -1.0, 2.0, 1.5, 0.3})
+	rbf, err := elm.NewKernel("rbf", []string{"a", "b"}, centers, nil, 0.5, 0, 0.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poly, err := elm.NewKernel("poly", []string{"a", "c d"}, centers, &elm.InputScaling{Offsets: []float64{0.5, -0.5}, Scales: []float64{2.0, 1.5}}, 0.3, 3, 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []struct {
+		name   string
+		model  Model
+		inputs []string
+	}{
+		{
+			name:   "linear",
+			model:  Model{TargetName: "y", Coeffs: map[string]float64{"a": 1.5, "b": -2.0, "c d": 0.25}, Intercept: 0.3},
+			inputs: []string{"a", "b", "c d"},
+		},
+		{
+			name: "features",
+			model: Model{
+				TargetName: "y",
+				Coeffs:     map[string]float64{"a": 1.0, "ap3": 0.5, "log(b)": -1.0, "a*b": 2.0, "f": 0.1},
+				Pipeline: &Pipeline{Steps: []PipelineStep{
+					{Poly: &PolyStep{Columns: []string{"a"}, Order: 3}},
+					{Features: &FeatureManifest{Features: []FeatureSpec{
+						{Name: "log(b)", Expr: "log(b)"},
+						{Name: "a*b", Expr: "a*b"},
+						{Name: "f", Expr: "sqrt(abs(a))/(1 + b^2) - -exp(-b)"},
+						{Name: "unused", Expr: "`c d`*2"},
+					}}},
+				}},
+			},
+			inputs: []string{"a", "b"},
+		},
+		{
+			name:   "elm",
+			model:  Model{TargetName: "y", Coeffs: elmCoeffs, Intercept: -0.7, Pipeline: &Pipeline{Steps: []PipelineStep{{ELM: &ELMStep{layer}}}}},
+			inputs: []string{"a", "b"},
+		},
+		{
+			name:   "rbf",
+			model:  Model{TargetName: "y", Coeffs: map[string]float64{"k0": 1.2, "k2": -0.4}, Pipeline: &Pipeline{Steps: []PipelineStep{{Kernel: &KernelStep{rbf}}}}},
+			inputs: []string{"a", "b"},
+		},
+		{
+			name:   "poly kernel",
+			model:  Model{TargetName: "y", Coeffs: map[string]float64{"k0": 0.2, "k1": -0.4, "k2": 0.9}, Pipeline: &Pipeline{Steps: []PipelineStep{{Kernel: &KernelStep{poly}}}}},
+			inputs: []string{"a", "c d"},
+		},
+	}
+}
+
+// exportRunner runs exported code for each row of args and returns the predictions
+type exportRunner func(dir string, code string, inputs []string, args [][]float64) (string, error)
+
+var exportRunners = map[string]struct {
+	tool string
+	run  exportRunner
+}{
+	"go":     {"go", runExportedGo},
+	"python": {"python3", runExportedPython},
+	"c":      {"cc", runExportedC},
+	"sql":    {"sqlite3", runExportedSQL},
+}
+
+func formatArgs(row []float64) string {
+	args := make([]string, len(row))
+	for i, v := range row {
+		args[i] = formatNumber(v)
+	}
+	return strings.Join(args, ", ")
+}
+
+func runCommand(dir string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s: %s\n%s", name, err, out)
+	}
+	return string(out), nil
+}
+
+func runExportedGo(dir string, code string, inputs []string, args [][]float64) (string, error) {
+	main := "package main\n\nimport \"fmt\"\n\nfunc main() {\n"
+	for _, row := range args {
+		main += fmt.Sprintf("\tfmt.Printf(\"%%.17g\\n\", Predict(%s))\n", formatArgs(row))
+	}
+	main += "}\n"
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "model.go"), []byte(code), 0644); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(main), 0644); err != nil {
+		return "", err
+	}
+	return runCommand(dir, "go", "run", "model.go", "main.go")
+}
+
+func runExportedPython(dir string, code string, inputs []string, args [][]float64) (string, error) {
+	for _, row := range args {
+		code += fmt.Sprintf("print(repr(predict(%s)))\n", formatArgs(row))
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "model.py"), []byte(code), 0644); err != nil {
+		return "", err
+	}
+	return runCommand(dir, "python3", "model.py")
+}
+
+func runExportedC(dir string, code string, inputs []string, args [][]float64) (string, error) {
+	code += "\n#include <stdio.h>\n\nint main(void)\n{\n"
+	for _, row := range args {
+		code += fmt.Sprintf("    printf(\"%%.17g\\n\", predict(%s));\n", formatArgs(row))
+	}
+	code += "    return 0;\n}\n"
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "model.c"), []byte(code), 0644); err != nil {
+		return "", err
+	}
+	if _, err := runCommand(dir, "cc", "-o", "model", "model.c", "-lm"); err != nil {
+		return "", err
+	}
+	return runCommand(dir, "./model")
+}
+
+func runExportedSQL(dir string, code string, inputs []string, args [][]float64) (string, error) {
+	cols := make([]string, len(inputs))
+	for i, name := range inputs {
+		cols[i] = quoteSQL(name) + " REAL"
+	}
+
+	script := fmt.Sprintf("CREATE TABLE \"data\" (%s);\n", strings.Join(cols, ", "))
+	for _, row := range args {
+		script += fmt.Sprintf("INSERT INTO \"data\" VALUES (%s);\n", formatArgs(row))
+	}
+	script += code
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "model.sql"), []byte(script), 0644); err != nil {
+		return "", err
+	}
+	out, err := runCommand(dir, "sqlite3", "-batch", "-noheader", "-list", "-separator", "|", ":memory:", ".read model.sql")
+	if err != nil {
+		return "", err
+	}
+
+	// The prediction is the last column
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	for i, line := range lines {
+		fields := strings.Split(line, "|")
+		lines[i] = fields[len(fields)-1]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func TestExportModel(t *testing.T) {
+	data := exportTestData()
+	for lang, runner := range exportRunners {
+		if _, err := exec.LookPath(runner.tool); err != nil {
+			t.Logf("Skipping %s: %s is not available\n", lang, runner.tool)
+			continue
+		}
+
+		for _, test := range exportTestModels(t) {
+			prepared, err := test.model.Prepare(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := prepared.Dot(test.model.Coeffs)
+
+			opts := ExportOptions{Lang: lang}
+			if lang == "go" {
+				opts.Package = "main"
+			}
+			exported, err := ExportModel(test.model, opts)
+			if err != nil {
+				t.Errorf("%s, %s: %s\n", lang, test.name, err)
+				continue
+			}
+
+			if !reflect.DeepEqual(exported.Inputs, test.inputs) {
+				t.Errorf("%s, %s: Expected inputs %v got %v\n", lang, test.name, test.inputs, exported.Inputs)
+				continue
+			}
+
+			args := make([][]float64, data.NumData())
+			for i := range args {
+				args[i] = mat.Row(nil, 0, data.Submatrix(exported.Inputs).Slice(i, i+1, 0, len(exported.Inputs)))
+			}
+
+			dir, err := ioutil.TempDir("", "gogafitExport")
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := runner.run(dir, exported.Code, exported.Inputs, args)
+			os.RemoveAll(dir)
+			if err != nil {
+				t.Errorf("%s, %s: %s\nCode:\n%s\n", lang, test.name, err, exported.Code)
+				continue
+			}
+
+			lines := strings.Fields(out)
+			if len(lines) != len(args) {
+				t.Errorf("%s, %s: Expected %d predictions got\n%s\n", lang, test.name, len(args), out)
+				continue
+			}
+
+			for i, line := range lines {
+				got, err := strconv.ParseFloat(line, 64)
+				w := want.AtVec(i) + test.model.Intercept
+				if err != nil || math.Abs(got-w) > 1e-9*math.Max(1.0, math.Abs(w)) {
+					t.Errorf("%s, %s, row %d: Expected %.12f got %s\nCode:\n%s\n", lang, test.name, i, w, line, exported.Code)
+				}
+			}
+		}
+	}
+}
+
+func TestExportModelErrors(t *testing.T) {
+	layer := elm.Layer{
+		Inputs:  []string{"a"},
+		Names:   []string{"h0"},
+		Neurons: []elm.Neuron{{Weights: []float64{1.0}, ActivationFunc: elm.Sigmoid}},
+	}
+
+	for i, test := range []struct {
+		model Model
+		lang  string
+	}{
+		{Model{TargetName: "y", Coeffs: map[string]float64{"a": 1.0}}, "fortran"},
+		{Model{TargetName: "y", Classes: []string{"a", "b"}, ClassCoeffs: []map[string]float64{{"a": 1.0}}, ClassIntercepts: []float64{0.0}}, "go"},
+
+		// The raw column a is not available after the hidden layer
+		{Model{TargetName: "y", Coeffs: map[string]float64{"h0": 1.0, "a": 1.0}, Pipeline: &Pipeline{Steps: []PipelineStep{{ELM: &ELMStep{layer}}}}}, "c"},
+	} {
+		if _, err := ExportModel(test.model, ExportOptions{Lang: test.lang}); err == nil {
+			t.Errorf("Test #%d: Expected error\n", i)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package gafit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression is a parsed arithmetic expression over the columns of a dataset. It supports
+// the binary operators + - * / ^, unary minus, parentheses, numbers, column names and the
+// functions listed in ExpressionFunctions. Column names that are not valid identifiers can
+// be quoted with backticks (e.g. `my col`*x2).
+type Expression struct {
+	Source string
+	root   exprNode
+}
+
+// ExpressionFunctions holds the functions that can be used in expressions
+var ExpressionFunctions = map[string]func(float64) float64{
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"tanh":  math.Tanh,
+	"exp":   math.Exp,
+	"log":   math.Log,
+	"log10": math.Log10,
+	"sqrt":  math.Sqrt,
+	"abs":   math.Abs,
+}
+
+// ParseExpression parses the passed string
+func ParseExpression(src string) (Expression, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return Expression{}, err
+	}
+	p := parser{tokens: tokens}
+	root, err := p.parseSum()
+	if err != nil {
+		return Expression{}, err
+	}
+	if p.pos != len(p.tokens) {
+		return Expression{}, fmt.Errorf("Unexpected token %s in expression %s", p.tokens[p.pos].text, src)
+	}
+	return Expression{Source: src, root: root}, nil
+}
+
+// Variables returns the column names referenced by the expression in alphabetical order
+func (e Expression) Variables() []string {
+	unique := make(map[string]bool)
+	e.root.variables(unique)
+	names := []string{}
+	for k := range unique {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Evaluate evaluates the expression for all rows in data
+func (e Expression) Evaluate(data Dataset) ([]float64, error) {
+	cols := make(map[string]int)
+	for i, name := range data.ColNames {
+		cols[name] = i
+	}
+
+	for _, v := range e.Variables() {
+		if _, ok := cols[v]; !ok {
+			return nil, fmt.Errorf("Column %s used in expression %s is not in the dataset", v, e.Source)
+		}
+	}
+
+	res := make([]float64, data.NumData())
+	for i := range res {
+		res[i] = e.root.eval(data.X.RawRowView(i), cols)
+	}
+	return res, nil
+}
+
+type exprNode interface {
+	eval(row []float64, cols map[string]int) float64
+	variables(names map[string]bool)
+}
+
+type numberNode struct {
+	value float64
+}
+
+func (n numberNode) eval(row []float64, cols map[string]int) float64 { return n.value }
+func (n numberNode) variables(names map[string]bool)                 {}
+
+type variableNode struct {
+	name string
+}
+
+func (n variableNode) eval(row []float64, cols map[string]int) float64 { return row[cols[n.name]] }
+func (n variableNode) variables(names map[string]bool)                 { names[n.name] = true }
+
+type unaryNode struct {
+	name string // name of the function in ExpressionFunctions or - for negation
+	fn   func(float64) float64
+	arg  exprNode
+}
+
+func (n unaryNode) eval(row []float64, cols map[string]int) float64 {
+	return n.fn(n.arg.eval(row, cols))
+}
+func (n unaryNode) variables(names map[string]bool) { n.arg.variables(names) }
+
+type binaryNode struct {
+	op          byte
+	left, right exprNode
+}
+
+func (n binaryNode) eval(row []float64, cols map[string]int) float64 {
+	a := n.left.eval(row, cols)
+	b := n.right.eval(row, cols)
+	switch n.op {
+	case '+':
+		return a + b
+	case '-':
+		return a - b
+	case '*':
+		return a * b
+	case '/':
+		return a / b
+	default:
+		return math.Pow(a, b)
+	}
+}
+
+func (n binaryNode) variables(names map[string]bool) {
+	n.left.variables(names)
+	n.right.variables(names)
+}
+
+type tokenKind int
+
+const (
+	numberToken tokenKind = iota
+	identToken
+	opToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func isIdentRune(r rune, first bool) bool {
+	if unicode.IsLetter(r) || r == '_' {
+		return true
+	}
+	return !first && (unicode.IsDigit(r) || r == '.')
+}
+
+func tokenize(src string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case strings.ContainsRune("+-*/^()", r):
+			tokens = append(tokens, token{kind: opToken, text: string(r)})
+			i++
+		case r == '`':
+			end := i + 1
+			for end < len(runes) && runes[end] != '`' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("Missing closing backtick in expression %s", src)
+			}
+			tokens = append(tokens, token{kind: identToken, text: string(runes[i+1 : end])})
+			i = end + 1
+		case unicode.IsDigit(r) || r == '.':
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+
+			// Scientific notation (e.g. 1e-3)
+			if end < len(runes) && (runes[end] == 'e' || runes[end] == 'E') {
+				exp := end + 1
+				if exp < len(runes) && (runes[exp] == '+' || runes[exp] == '-') {
+					exp++
+				}
+				if exp < len(runes) && unicode.IsDigit(runes[exp]) {
+					end = exp
+					for end < len(runes) && unicode.IsDigit(runes[end]) {
+						end++
+					}
+				}
+			}
+			tokens = append(tokens, token{kind: numberToken, text: string(runes[i:end])})
+			i = end
+		case isIdentRune(r, true):
+			end := i
+			for end < len(runes) && isIdentRune(runes[end], false) {
+				end++
+			}
+			tokens = append(tokens, token{kind: identToken, text: string(runes[i:end])})
+			i = end
+		default:
+			return nil, fmt.Errorf("Unexpected character %c in expression %s", r, src)
+		}
+	}
+	return tokens, nil
+}
+
+// parser is a recursive descent parser for the grammar
+//
+// sum     := product (('+' | '-') product)*
+// product := unary (('*' | '/') unary)*
+// unary   := '-' unary | power
+// power   := primary ('^' unary)?
+// primary := number | ident | ident '(' sum ')' | '(' sum ')'
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) acceptOp(ops string) (byte, bool) {
+	tok, ok := p.peek()
+	if ok && tok.kind == opToken && strings.Contains(ops, tok.text) {
+		p.pos++
+		return tok.text[0], true
+	}
+	return 0, false
+}
+
+func (p *parser) parseSum() (exprNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("+-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseProduct() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("*/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (exprNode, error) {
+	if _, ok := p.acceptOp("-"); ok {
+		arg, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{name: "-", fn: func(x float64) float64 { return -x }, arg: arg}, nil
+	}
+	return p.parsePower()
+}
+
+func (p *parser) parsePower() (exprNode, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.acceptOp("^"); ok {
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: '^', left: base, right: exponent}, nil
+	}
+	return base, nil
+}
+
+func (p *parser) parsePrimary() (exprNode, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, errors.New("Unexpected end of expression")
+	}
+
+	switch tok.kind {
+	case numberToken:
+		p.pos++
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, err
+		}
+		return numberNode{value: v}, nil
+	case identToken:
+		p.pos++
+		if _, isCall := p.acceptOp("("); !isCall {
+			return variableNode{name: tok.text}, nil
+		}
+
+		fn, known := ExpressionFunctions[tok.text]
+		if !known {
+			return nil, fmt.Errorf("Unknown function %s", tok.text)
+		}
+		arg, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.acceptOp(")"); !ok {
+			return nil, fmt.Errorf("Missing closing parenthesis after argument to %s", tok.text)
+		}
+		return unaryNode{name: tok.text, fn: fn, arg: arg}, nil
+	default:
+		if _, ok := p.acceptOp("("); ok {
+			node, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.acceptOp(")"); !ok {
+				return nil, errors.New("Missing closing parenthesis")
+			}
+			return node, nil
+		}
+		return nil, fmt.Errorf("Unexpected token %s", tok.text)
+	}
+}
@@ -0,0 +1,95 @@
+package gafit
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestExpressionEvaluate(t *testing.T) {
+	data := Dataset{
+		X:        mat.NewDense(2, 3, []float64{1.0, 2.0, 4.0, 3.0, -1.0, 9.0}),
+		ColNames: []string{"x1", "x2", "my col"},
+	}
+
+	for i, test := range []struct {
+		expr string
+		want []float64
+	}{
+		{
+			expr: "x1 + x2*2",
+			want: []float64{5.0, 1.0},
+		},
+		{
+			expr: "(x1 + x2)*2",
+			want: []float64{6.0, 4.0},
+		},
+		{
+			expr: "-x1^2",
+			want: []float64{-1.0, -9.0},
+		},
+		{
+			expr: "2^3^2",
+			want: []float64{512.0, 512.0},
+		},
+		{
+			expr: "sin(x1)*x2",
+			want: []float64{math.Sin(1.0) * 2.0, -math.Sin(3.0)},
+		},
+		{
+			expr: "sqrt(`my col`)/x1",
+			want: []float64{2.0, 1.0},
+		},
+		{
+			expr: "1e-1*x1 - 2.5E1",
+			want: []float64{-24.9, -24.7},
+		},
+		{
+			expr: "x1/x2/2",
+			want: []float64{0.25, -1.5},
+		},
+	} {
+		expr, err := ParseExpression(test.expr)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+
+		got, err := expr.Evaluate(data)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+
+		if !floats.EqualApprox(got, test.want, 1e-10) {
+			t.Errorf("Test #%d: Want\n%v\ngot\n%v\n", i, test.want, got)
+		}
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	for i, test := range []string{"x1 +", "foo(x1)", "(x1", "x1 $ x2", "`x1", "x1 x2"} {
+		if _, err := ParseExpression(test); err == nil {
+			t.Errorf("Test #%d: Expected error for %s\n", i, test)
+		}
+	}
+}
+
+func TestExpressionVariables(t *testing.T) {
+	expr, err := ParseExpression("x2*sin(x1) + x2/`a b`")
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	want := []string{"a b", "x1", "x2"}
+	if got := expr.Variables(); !allEqualString(got, want) {
+		t.Errorf("Want\n%v\ngot\n%v\n", want, got)
+	}
+
+	data := Dataset{X: mat.NewDense(1, 1, nil), ColNames: []string{"x1"}}
+	if _, err := expr.Evaluate(data); err == nil {
+		t.Errorf("Expected error for missing column\n")
+	}
+}
@@ -0,0 +1,173 @@
+package gafit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/combin"
+)
+
+// FeatureSpec describes a generated feature. Name is the name of the new column and
+// Expr is the expression used to calculate it from the existing columns
+type FeatureSpec struct {
+	Name string
+	Expr string
+}
+
+// FeatureManifest is an ordered list of generated features. It is stored alongside generated
+// datasets such that the same expansion can be replayed on new data (e.g. prediction data)
+type FeatureManifest struct {
+	// Categories holds the encoding of categorical columns in the original data
+	Categories []CategoricalEncoding `json:",omitempty"`
+	Features   []FeatureSpec
+}
+
+// Apply returns a new dataset where the generated features are appended to the columns of data.
+// An error is returned if any of the generated values are not finite
+func (fm FeatureManifest) Apply(data Dataset) (Dataset, error) {
+	names := make([]string, len(fm.Features))
+	cols := make([][]float64, len(fm.Features))
+	for i, spec := range fm.Features {
+		values, err := spec.Evaluate(data)
+		if err != nil {
+			return data, err
+		}
+		names[i] = spec.Name
+		cols[i] = values
+	}
+	return data.AddColumns(names, cols), nil
+}
+
+// Evaluate calculates the value of the feature for all rows in data
+func (fs FeatureSpec) Evaluate(data Dataset) ([]float64, error) {
+	expr, err := ParseExpression(fs.Expr)
+	if err != nil {
+		return nil, err
+	}
+	values, err := expr.Evaluate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("Feature %s is not finite in row %d", fs.Name, i)
+		}
+	}
+	return values, nil
+}
+
+// AddColumns returns a copy of data where the passed columns are appended
+func (data Dataset) AddColumns(names []string, cols [][]float64) Dataset {
+	dataCpy := data.Copy()
+	if len(cols) == 0 {
+		return dataCpy
+	}
+
+	rows, origNumCols := dataCpy.X.Dims()
+	Xnew := dataCpy.X.Grow(0, len(cols)).(*mat.Dense)
+	for i, col := range cols {
+		Xnew.SetCol(origNumCols+i, col[:rows])
+	}
+	dataCpy.X = Xnew
+	dataCpy.ColNames = append(dataCpy.ColNames, names...)
+	return dataCpy
+}
+
+// quoteName wraps a column name in backticks if it can not be used directly in an expression
+func quoteName(name string) string {
+	tokens, err := tokenize(name)
+	if err == nil && len(tokens) == 1 && tokens[0].kind == identToken && tokens[0].text == name {
+		return name
+	}
+	return "`" + name + "`"
+}
+
+// InteractionFeatures returns products of all combinations of 2 up to order distinct columns.
+// For example the interaction of x1 and x2 is named x1*x2
+func InteractionFeatures(names []string, order int) []FeatureSpec {
+	specs := []FeatureSpec{}
+	for k := 2; k <= order && k <= len(names); k++ {
+		for _, comb := range combin.Combinations(len(names), k) {
+			factors := make([]string, k)
+			quoted := make([]string, k)
+			for i, c := range comb {
+				factors[i] = names[c]
+				quoted[i] = quoteName(names[c])
+			}
+			specs = append(specs, FeatureSpec{
+				Name: strings.Join(factors, "*"),
+				Expr: strings.Join(quoted, "*"),
+			})
+		}
+	}
+	return specs
+}
+
+// UnaryFeatures applies a function to each of the passed columns. The function is one of the
+// names in ExpressionFunctions or inv (giving 1/x). The new features are named fn(x), e.g. log(x1)
+// and 1/x1 for the inverse
+func UnaryFeatures(names []string, fn string) ([]FeatureSpec, error) {
+	if _, ok := ExpressionFunctions[fn]; !ok && fn != "inv" {
+		return nil, fmt.Errorf("Unknown function %s", fn)
+	}
+
+	specs := make([]FeatureSpec, len(names))
+	for i, name := range names {
+		if fn == "inv" {
+			specs[i] = FeatureSpec{Name: "1/" + name, Expr: "1/" + quoteName(name)}
+		} else {
+			specs[i] = FeatureSpec{
+				Name: fmt.Sprintf("%s(%s)", fn, name),
+				Expr: fmt.Sprintf("%s(%s)", fn, quoteName(name)),
+			}
+		}
+	}
+	return specs, nil
+}
+
+// ParseFeatureSpec creates a feature from a string on the form name=expr. If no name
+// is given, the expression itself is used as name
+func ParseFeatureSpec(str string) (FeatureSpec, error) {
+	spec := FeatureSpec{Name: strings.TrimSpace(str), Expr: strings.TrimSpace(str)}
+	if idx := strings.Index(str, "="); idx != -1 {
+		spec.Name = strings.TrimSpace(str[:idx])
+		spec.Expr = strings.TrimSpace(str[idx+1:])
+	}
+
+	if _, err := ParseExpression(spec.Expr); err != nil {
+		return spec, err
+	}
+	return spec, nil
+}
+
+// SaveFeatureManifest writes the manifest to a JSON file
+func SaveFeatureManifest(fname string, fm FeatureManifest) error {
+	serialized, err := json.MarshalIndent(fm, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, serialized, 0644)
+}
+
+// ReadFeatureManifest reads a manifest from a JSON file
+func ReadFeatureManifest(fname string) (FeatureManifest, error) {
+	var fm FeatureManifest
+	f, err := os.Open(fname)
+	if err != nil {
+		return fm, err
+	}
+	defer f.Close()
+
+	bytes, err := ioutil.ReadAll(f)
+	if err != nil {
+		return fm, err
+	}
+	err = json.Unmarshal(bytes, &fm)
+	return fm, err
+}
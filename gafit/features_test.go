@@ -0,0 +1,108 @@
+package gafit
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestInteractionFeatures(t *testing.T) {
+	specs := InteractionFeatures([]string{"a", "b", "c d"}, 3)
+	want := []FeatureSpec{
+		{Name: "a*b", Expr: "a*b"},
+		{Name: "a*c d", Expr: "a*`c d`"},
+		{Name: "b*c d", Expr: "b*`c d`"},
+		{Name: "a*b*c d", Expr: "a*b*`c d`"},
+	}
+	if !reflect.DeepEqual(specs, want) {
+		t.Errorf("Want\n%v\ngot\n%v\n", want, specs)
+	}
+}
+
+func TestUnaryFeatures(t *testing.T) {
+	specs, err := UnaryFeatures([]string{"a"}, "inv")
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	want := []FeatureSpec{{Name: "1/a", Expr: "1/a"}}
+	if !reflect.DeepEqual(specs, want) {
+		t.Errorf("Want\n%v\ngot\n%v\n", want, specs)
+	}
+
+	if _, err := UnaryFeatures([]string{"a"}, "unknown"); err == nil {
+		t.Errorf("Expected error for unknown function\n")
+	}
+}
+
+func TestParseFeatureSpec(t *testing.T) {
+	for i, test := range []struct {
+		str  string
+		want FeatureSpec
+	}{
+		{
+			str:  "s = sin(x1)*x2",
+			want: FeatureSpec{Name: "s", Expr: "sin(x1)*x2"},
+		},
+		{
+			str:  "x1*x2",
+			want: FeatureSpec{Name: "x1*x2", Expr: "x1*x2"},
+		},
+	} {
+		spec, err := ParseFeatureSpec(test.str)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+		if spec != test.want {
+			t.Errorf("Test #%d: Want %v got %v\n", i, test.want, spec)
+		}
+	}
+}
+
+func TestFeatureManifestApply(t *testing.T) {
+	data := Dataset{
+		X:        mat.NewDense(2, 2, []float64{1.0, 2.0, 3.0, 4.0}),
+		Y:        mat.NewVecDense(2, []float64{1.0, 2.0}),
+		ColNames: []string{"x1", "x2"},
+	}
+
+	manifest := FeatureManifest{
+		Features: []FeatureSpec{{Name: "x1*x2", Expr: "x1*x2"}, {Name: "1/x1", Expr: "1/x1"}},
+	}
+
+	fname := "manifestRoundTrip.json"
+	defer os.Remove(fname)
+	if err := SaveFeatureManifest(fname, manifest); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	manifest, err := ReadFeatureManifest(fname)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	res, err := manifest.Apply(data)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	want := Dataset{
+		X:        mat.NewDense(2, 4, []float64{1.0, 2.0, 2.0, 1.0, 3.0, 4.0, 12.0, 1.0 / 3.0}),
+		Y:        data.Y,
+		ColNames: []string{"x1", "x2", "x1*x2", "1/x1"},
+	}
+	if !res.IsEqual(want) {
+		t.Errorf("Want\n%+v\ngot\n%+v\n", want, res)
+	}
+
+	manifest.Features = append(manifest.Features, FeatureSpec{Name: "log(x1-1)", Expr: "log(x1-1)"})
+	if _, err := manifest.Apply(data); err == nil {
+		t.Errorf("Expected error for non-finite feature\n")
+	}
+}
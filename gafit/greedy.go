@@ -61,6 +61,84 @@ func OrthogonalMatchingPursuit(dataset Dataset, cost CostFunction, maxFeatures i
 	}
 }
 
+// SimultaneousOrthogonalMatchingPursuit selects a single set of features for several target
+// vectors. In each step the feature with the largest summed absolute projection onto the
+// residuals of all targets is added. The score is the sum of the costs of the individual
+// targets, and the coefficients of the targets are stored one after another (in the order of
+// targets) in the returned coefficient vector
+func SimultaneousOrthogonalMatchingPursuit(dataset Dataset, targets []*mat.VecDense, cost CostFunction, maxFeatures int) OptimizeResult {
+	X := dataset.X
+	Xnorm := mat.DenseCopyOf(X)
+	normalize(Xnorm)
+	_, cols := Xnorm.Dims()
+
+	residuals := make([]*mat.VecDense, len(targets))
+	for t, y := range targets {
+		residuals[t] = mat.VecDenseCopyOf(y)
+	}
+	proj := mat.NewVecDense(cols, nil)
+	total := mat.NewVecDense(cols, nil)
+
+	selected := []int{}
+	names := []string{}
+	bestScore := math.Inf(1)
+	bestSelection := make([]int, 0, cols)
+	end := maxFeatures
+	if cols < end {
+		end = cols
+	}
+
+	for i := 0; i < end; i++ {
+		total.Zero()
+		for _, r := range residuals {
+			proj.MulVec(Xnorm.T(), r)
+			for j := 0; j < cols; j++ {
+				total.SetVec(j, total.AtVec(j)+math.Abs(proj.AtVec(j)))
+			}
+		}
+
+		// Already selected columns are (nearly) orthogonal to the residuals, but exclude
+		// them explicitly such that the same column is never added twice
+		for _, s := range selected {
+			total.SetVec(s, -1.0)
+		}
+		best := argMax(total.RawVector().Data)
+		selected = append(selected, best)
+		names = append(names, dataset.ColNames[best])
+		sub := subMatrix(X, selected)
+
+		score := 0.0
+		for t, y := range targets {
+			tempCoeff := Fit(sub, y)
+			score += cost(sub, y, tempCoeff, names)
+			residuals[t].SubVec(y, Pred(sub, tempCoeff))
+		}
+
+		if score < bestScore {
+			bestScore = score
+			bestSelection = bestSelection[:0]
+			bestSelection = append(bestSelection, selected...)
+		}
+	}
+
+	sort.Ints(bestSelection)
+	sub := subMatrix(X, bestSelection)
+	p := len(bestSelection)
+	coeff := mat.NewVecDense(p*len(targets), nil)
+	for t, y := range targets {
+		c := Fit(sub, y)
+		for j := 0; j < p; j++ {
+			coeff.SetVec(t*p+j, c.AtVec(j))
+		}
+	}
+
+	return OptimizeResult{
+		Score:   bestScore,
+		Coeff:   coeff,
+		Include: selection2bitstring(bestSelection, cols),
+	}
+}
+
 func normalize(X *mat.Dense) {
 	rows, cols := X.Dims()
 	tol := 1e-16
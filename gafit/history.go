@@ -0,0 +1,273 @@
+package gafit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/MaxHalford/eaopt"
+)
+
+// GenerationStats holds statistics of the population of a GA run after one generation.
+// Generation 0 is the initial population. Fitness values that are not finite are nil
+type GenerationStats struct {
+	// Target is the target of the GA run. For shared feature selection of multiple targets,
+	// the targets are joined by commas
+	Target     string
+	Generation uint
+	Best       *float64 `json:",omitempty"`
+	Mean       *float64 `json:",omitempty"`
+	Worst      *float64 `json:",omitempty"`
+
+	// Diversity is the mean fraction of features where two genomes in the population differ,
+	// averaged over all pairs. It is 0 if all genomes are equal
+	Diversity float64
+
+	// NumFeatures is the number of features selected by the best genome
+	NumFeatures int
+}
+
+// GAHistory holds the statistics of all generations of one or more GA runs
+type GAHistory struct {
+	Cost        string
+	Generations []GenerationStats
+}
+
+// historyColumns holds the columns of history CSV files
+var historyColumns = []string{"target", "generation", "best", "mean", "worst", "diversity", "num_features"}
+
+// Recorder returns a callback to the GA that appends the statistics of each generation to
+// the history. A new recorder must be used for each GA run
+func (h *GAHistory) Recorder(target string) func(ga *eaopt.GA) {
+	var counter selectionCounter
+	return func(ga *eaopt.GA) {
+		stats := newGenerationStats(ga, &counter)
+		stats.Target = target
+		h.Generations = append(h.Generations, stats)
+	}
+}
+
+// NewGenerationStats calculates the statistics of the current population of the GA. The
+// genomes must be of type *LinearModel
+func NewGenerationStats(ga *eaopt.GA) GenerationStats {
+	return newGenerationStats(ga, &selectionCounter{})
+}
+
+// newGenerationStats calculates the statistics of the current population, where the number
+// of features selected by the best genome is found by counter
+func newGenerationStats(ga *eaopt.GA, counter *selectionCounter) GenerationStats {
+	var indis eaopt.Individuals
+	for _, pop := range ga.Populations {
+		indis = append(indis, pop.Individuals...)
+	}
+
+	return GenerationStats{
+		Generation:  ga.Generations,
+		Best:        finiteOrNil(ga.HallOfFame[0].Fitness),
+		Mean:        finiteOrNil(indis.FitAvg()),
+		Worst:       finiteOrNil(indis.FitMax()),
+		Diversity:   PopulationDiversity(indis),
+		NumFeatures: counter.numSelected(ga.HallOfFame[0].Genome.(*LinearModel)),
+	}
+}
+
+// selectionCounter caches the number of features selected by a genome. The best genome of a
+// GA run often stays the same for many generations, and the count requires a fit
+type selectionCounter struct {
+	include []int
+	count   int
+}
+
+// numSelected returns the number of features selected when the genome is optimized. The fit
+// is only repeated if the candidate features differ from the previous call
+func (c *selectionCounter) numSelected(genome *LinearModel) int {
+	if c.include != nil && AllEqualInt(c.include, genome.Include) {
+		return c.count
+	}
+
+	c.include = append([]int{}, genome.Include...)
+	c.count = 0
+	for _, v := range genome.Optimize().Include {
+		c.count += v
+	}
+	return c.count
+}
+
+// PopulationDiversity returns the mean normalized Hamming distance between the feature
+// selections of all pairs of individuals. The genomes must be of type *LinearModel
+func PopulationDiversity(indis eaopt.Individuals) float64 {
+	if len(indis) < 2 {
+		return 0.0
+	}
+
+	sum := 0.0
+	for i := range indis {
+		a := indis[i].Genome.(*LinearModel).Include
+		for j := i + 1; j < len(indis); j++ {
+			b := indis[j].Genome.(*LinearModel).Include
+			diff := 0
+			for k := range a {
+				if a[k] != b[k] {
+					diff++
+				}
+			}
+			sum += float64(diff) / float64(len(a))
+		}
+	}
+	numPairs := len(indis) * (len(indis) - 1) / 2
+	return sum / float64(numPairs)
+}
+
+// Targets returns the targets of the history in the order of their first appearance
+func (h GAHistory) Targets() []string {
+	seen := make(map[string]bool)
+	targets := []string{}
+	for _, g := range h.Generations {
+		if !seen[g.Target] {
+			seen[g.Target] = true
+			targets = append(targets, g.Target)
+		}
+	}
+	return targets
+}
+
+// WriteJSON writes the history in JSON format
+func (h GAHistory) WriteJSON(w io.Writer) error {
+	serialized, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", serialized)
+	return err
+}
+
+// WriteCSV writes the history as a CSV file with one row per generation. Fitness values that
+// are not finite are empty. The name of the cost function is not included
+func (h GAHistory) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(historyColumns); err != nil {
+		return err
+	}
+
+	optional := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'g', -1, 64)
+	}
+
+	for _, g := range h.Generations {
+		record := []string{
+			g.Target,
+			strconv.FormatUint(uint64(g.Generation), 10),
+			optional(g.Best),
+			optional(g.Mean),
+			optional(g.Worst),
+			strconv.FormatFloat(g.Diversity, 'g', -1, 64),
+			strconv.Itoa(g.NumFeatures),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// isJSONFile returns true if the file has the extension .json
+func isJSONFile(fname string) bool {
+	return strings.ToLower(filepath.Ext(fname)) == ".json"
+}
+
+// SaveGAHistory writes the history to a file. Files with the extension .json are written in
+// JSON format, and all other files as CSV
+func SaveGAHistory(fname string, h GAHistory) error {
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if isJSONFile(fname) {
+		return h.WriteJSON(f)
+	}
+	return h.WriteCSV(f)
+}
+
+// ReadGAHistory reads a history written by SaveGAHistory
+func ReadGAHistory(fname string) (GAHistory, error) {
+	if isJSONFile(fname) {
+		bytes, err := ioutil.ReadFile(fname)
+		if err != nil {
+			return GAHistory{}, err
+		}
+
+		var h GAHistory
+		err = json.Unmarshal(bytes, &h)
+		return h, err
+	}
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return GAHistory{}, err
+	}
+	defer f.Close()
+	return parseHistoryCSV(f)
+}
+
+// parseHistoryCSV parses a history in the format written by WriteCSV
+func parseHistoryCSV(r io.Reader) (GAHistory, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return GAHistory{}, err
+	}
+
+	if len(records) == 0 || !allEqualString(records[0], historyColumns) {
+		return GAHistory{}, fmt.Errorf("Expected the columns %s", strings.Join(historyColumns, ","))
+	}
+
+	optional := func(s string) (*float64, error) {
+		if s == "" {
+			return nil, nil
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		return finiteOrNil(v), nil
+	}
+
+	var h GAHistory
+	for i, record := range records[1:] {
+		var g GenerationStats
+		g.Target = record[0]
+		gen, err := strconv.ParseUint(record[1], 10, 64)
+		if err == nil {
+			g.Generation = uint(gen)
+			g.Best, err = optional(record[2])
+		}
+		if err == nil {
+			g.Mean, err = optional(record[3])
+		}
+		if err == nil {
+			g.Worst, err = optional(record[4])
+		}
+		if err == nil {
+			g.Diversity, err = strconv.ParseFloat(record[5], 64)
+		}
+		if err == nil {
+			g.NumFeatures, err = strconv.Atoi(record[6])
+		}
+		if err != nil {
+			return GAHistory{}, fmt.Errorf("Row %d: %s", i+1, err)
+		}
+		h.Generations = append(h.Generations, g)
+	}
+	return h, nil
+}
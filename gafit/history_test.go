@@ -0,0 +1,145 @@
+package gafit
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/MaxHalford/eaopt"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestPopulationDiversity(t *testing.T) {
+	indis := func(includes ...[]int) eaopt.Individuals {
+		res := make(eaopt.Individuals, len(includes))
+		for i, inc := range includes {
+			res[i] = eaopt.Individual{Genome: &LinearModel{Include: inc}}
+		}
+		return res
+	}
+
+	for i, test := range []struct {
+		indis eaopt.Individuals
+		want  float64
+	}{
+		{indis([]int{1, 0, 1, 0}), 0.0},
+		{indis([]int{1, 0, 1, 0}, []int{1, 0, 1, 0}), 0.0},
+		{indis([]int{1, 0, 1, 0}, []int{0, 1, 0, 1}), 1.0},
+		{indis([]int{1, 0, 1, 0}, []int{1, 1, 1, 0}, []int{1, 1, 1, 1}), (0.25 + 0.5 + 0.25) / 3.0},
+	} {
+		if got := PopulationDiversity(test.indis); math.Abs(got-test.want) > 1e-12 {
+			t.Errorf("Test #%d: Expected %f got %f\n", i, test.want, got)
+		}
+	}
+}
+
+func TestSelectionCounter(t *testing.T) {
+	data := Dataset{
+		X:        mat.NewDense(4, 2, []float64{1.0, 0.0, 1.0, 1.0, 1.0, 2.0, 1.0, 3.0}),
+		Y:        mat.NewVecDense(4, []float64{0.1, 0.9, 2.1, 2.9}),
+		ColNames: []string{"one", "x"},
+	}
+	genome := &LinearModel{Config: LinearModelConfig{Data: data, Cost: Aicc}, Include: []int{1, 1}}
+
+	var counter selectionCounter
+	want := 0
+	for _, v := range genome.Optimize().Include {
+		want += v
+	}
+	if got := counter.numSelected(genome); got != want {
+		t.Errorf("Expected %d features got %d\n", want, got)
+	}
+
+	// The count is reused for the same candidate features, so a genome without data does not
+	// need to be fitted
+	if got := counter.numSelected(&LinearModel{Include: []int{1, 1}}); got != want {
+		t.Errorf("Expected cached count %d got %d\n", want, got)
+	}
+
+	// The cache holds a copy of the candidate features, such that a modified genome is refitted
+	genome.Include[1] = 0
+	if got := counter.numSelected(genome); got != 1 {
+		t.Errorf("Expected 1 feature got %d\n", got)
+	}
+}
+
+func TestGAHistory(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	numData, numFeat := 40, 6
+	data := Dataset{
+		X:          mat.NewDense(numData, numFeat, nil),
+		Y:          mat.NewVecDense(numData, nil),
+		ColNames:   []string{"a", "b", "c", "d", "e", "f"},
+		TargetName: "y",
+	}
+	for i := 0; i < numData; i++ {
+		for j := 0; j < numFeat; j++ {
+			data.X.Set(i, j, rng.NormFloat64())
+		}
+		data.Y.SetVec(i, 2.0*data.X.At(i, 0)-data.X.At(i, 3)+0.1*rng.NormFloat64())
+	}
+
+	conf := eaopt.NewDefaultGAConfig()
+	conf.PopSize = 10
+	conf.RNG = rng
+	ga, err := conf.NewGA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ga.NGenerations = 5
+
+	history := GAHistory{Cost: "aicc"}
+	ga.Callback = history.Recorder("y")
+	factory := LinearModelFactory{Config: LinearModelConfig{Data: data, Cost: Aicc}}
+	if err := ga.Minimize(factory.Generate); err != nil {
+		t.Fatal(err)
+	}
+
+	// The initial population is recorded as generation 0
+	if len(history.Generations) != 6 {
+		t.Fatalf("Expected 6 generations got %d\n", len(history.Generations))
+	}
+
+	for i, g := range history.Generations {
+		if g.Generation != uint(i) || g.Target != "y" {
+			t.Errorf("Generation %d: Unexpected generation %d or target %s\n", i, g.Generation, g.Target)
+		}
+		if g.Best == nil || g.Mean == nil || g.Worst == nil || *g.Best > *g.Mean || *g.Mean > *g.Worst {
+			t.Errorf("Generation %d: Expected best <= mean <= worst\n", i)
+		}
+		if i > 0 && *g.Best > *history.Generations[i-1].Best {
+			t.Errorf("Generation %d: The best fitness increased\n", i)
+		}
+		if g.Diversity < 0.0 || g.Diversity > 1.0 || g.NumFeatures < 1 || g.NumFeatures > numFeat {
+			t.Errorf("Generation %d: Unexpected diversity %f or number of features %d\n", i, g.Diversity, g.NumFeatures)
+		}
+	}
+
+	if !reflect.DeepEqual(history.Targets(), []string{"y"}) {
+		t.Errorf("Unexpected targets %v\n", history.Targets())
+	}
+
+	for _, fname := range []string{"gaHistory.csv", "gaHistory.json"} {
+		if err := SaveGAHistory(fname, history); err != nil {
+			t.Errorf("%s\n", err)
+			continue
+		}
+		read, err := ReadGAHistory(fname)
+		os.Remove(fname)
+		if err != nil {
+			t.Errorf("%s\n", err)
+			continue
+		}
+
+		// The cost is not part of the CSV files
+		want := history
+		if fname == "gaHistory.csv" {
+			want.Cost = ""
+		}
+		if !reflect.DeepEqual(read, want) {
+			t.Errorf("%s: Expected\n%v\ngot\n%v\n", fname, want, read)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package gafit
+
+import (
+	"errors"
+	"log"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// ModelSchemaVersion is the version of the model file format written by SaveModel. Files
+// written before the version was introduced have version 0
+const ModelSchemaVersion = 1
+
+// Inference holds the quantities needed to calculate the uncertainty of predictions without
+// access to the training data
+type Inference struct {
+	// Features holds the features of the model in the order used for the rows and columns of
+	// Covariance. If the model has an intercept, it corresponds to the last row and column
+	Features []string
+
+	// Covariance is the covariance matrix of the coefficients. It is nil if the covariance is
+	// not defined (e.g. when there are more coefficients than data points)
+	Covariance [][]float64 `json:",omitempty"`
+
+	// ResidualVariance is the residual sum of squares divided by DegreesOfFreedom
+	ResidualVariance float64
+
+	// DegreesOfFreedom is the number of data points minus the number of coefficients. It is
+	// set to 1 if there are more coefficients than data points
+	DegreesOfFreedom int
+}
+
+// NewInference calculates the coefficient covariance and the residual variance of a model
+// fitted to data
+func NewInference(model Model, data Dataset) *Inference {
+	sub := model.DesignMatrix(data)
+	coeffs := model.CoeffVector()
+
+	rss := Rss(sub, data.Y, coeffs)
+	numData, numFeat := sub.Dims()
+	dof := numData - numFeat
+	if dof <= 0 {
+		dof = 1
+	}
+
+	inf := &Inference{
+		Features:         model.Features(),
+		ResidualVariance: rss / float64(dof),
+		DegreesOfFreedom: dof,
+	}
+
+	// If there are more features than data points (e.g. ELM networks fitted with
+	// regularization), the coefficient covariance is not defined and only the residual
+	// variance contributes to the standard deviation
+	cov, err := CovMatrix(sub, rss)
+	if err != nil {
+		log.Printf("Could not calculate coefficient covariance: %s\n", err)
+		return inf
+	}
+
+	inf.Covariance = make([][]float64, numFeat)
+	for i := range inf.Covariance {
+		inf.Covariance[i] = make([]float64, numFeat)
+		for j := range inf.Covariance[i] {
+			inf.Covariance[i][j] = cov.At(i, j)
+		}
+	}
+	return inf
+}
+
+// CovarianceMatrix returns the covariance matrix of the coefficients, or nil if not defined
+func (inf Inference) CovarianceMatrix() *mat.SymDense {
+	n := len(inf.Covariance)
+	if n == 0 {
+		return nil
+	}
+
+	cov := mat.NewSymDense(n, nil)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			cov.SetSym(i, j, inf.Covariance[i][j])
+		}
+	}
+	return cov
+}
+
+// WithInference returns a copy of the model where the inference quantities calculated from
+// the training data are embedded. Classification models are returned unchanged
+func (m Model) WithInference(data Dataset) Model {
+	if m.IsClassifier() {
+		return m
+	}
+	m.Inference = NewInference(m, data)
+	return m
+}
+
+// PredictWithUncertainty returns the predictions for all rows in data together with the
+// standard deviations and the confidence and prediction intervals at the given level (e.g.
+// 0.95). The intervals are based on the quantile of the Student-t distribution with the
+// degrees of freedom of the model. The model must hold the inference quantities (see
+// WithInference)
+func (m Model) PredictWithUncertainty(data Dataset, level float64) ([]Prediction, error) {
+	if m.Inference == nil {
+		return nil, errors.New("The model holds no covariance information. Refit the model or pass the training data")
+	}
+
+	inf := m.Inference
+	sub := data.Submatrix(inf.Features)
+	coeffs := mat.NewVecDense(len(inf.Features), nil)
+	for i, name := range inf.Features {
+		coeffs.SetVec(i, m.Coeffs[name])
+	}
+
+	if m.HasIntercept() {
+		r, c := sub.Dims()
+		sub = sub.Grow(0, 1).(*mat.Dense)
+		for i := 0; i < r; i++ {
+			sub.Set(i, c, 1.0)
+		}
+		coeffs = mat.NewVecDense(c+1, append(coeffs.RawVector().Data, m.Intercept))
+	}
+
+	_, numCoeff := sub.Dims()
+	cov := inf.CovarianceMatrix()
+	if cov != nil && cov.Symmetric() != numCoeff {
+		return nil, errors.New("The size of the covariance matrix does not match the number of coefficients")
+	}
+
+	pred := Pred(sub, coeffs)
+	t := distuv.StudentsT{Mu: 0.0, Sigma: 1.0, Nu: float64(inf.DegreesOfFreedom)}.Quantile(0.5 + 0.5*level)
+
+	predictions := make([]Prediction, pred.Len())
+	for i := 0; i < pred.Len(); i++ {
+		// Variance of the mean response, x^T Cov x
+		meanVariance := 0.0
+		if cov != nil {
+			x := sub.RowView(i)
+			meanVariance = mat.Inner(x, cov, x)
+		}
+
+		value := pred.AtVec(i)
+		confStd := math.Sqrt(meanVariance)
+		predStd := math.Sqrt(inf.ResidualVariance + meanVariance)
+		predictions[i] = Prediction{
+			Value:     value,
+			Std:       predStd,
+			ConfLower: value - t*confStd,
+			ConfUpper: value + t*confStd,
+			PredLower: value - t*predStd,
+			PredUpper: value + t*predStd,
+		}
+	}
+	return predictions, nil
+}
@@ -0,0 +1,140 @@
+package gafit
+
+import (
+	"math"
+	"os"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestInferenceRoundTrip(t *testing.T) {
+	// Same regression problem as in TestGetPredictionsWithLevel, but the intercept is stored
+	// separately from the coefficients
+	data := Dataset{
+		X:          mat.NewDense(4, 1, []float64{0.0, 1.0, 2.0, 3.0}),
+		Y:          mat.NewVecDense(4, []float64{0.1, 0.9, 2.1, 2.9}),
+		ColNames:   []string{"x"},
+		TargetName: "y",
+	}
+	model := Model{
+		TargetName:      "y",
+		Coeffs:          map[string]float64{"x": 0.96},
+		Intercept:       0.06,
+		Standardization: &Standardization{Means: map[string]float64{"x": 0.0}, Scales: map[string]float64{"x": 1.0}},
+	}.WithInference(data)
+
+	inf := model.Inference
+	if !reflect.DeepEqual(inf.Features, []string{"x"}) || inf.DegreesOfFreedom != 2 {
+		t.Errorf("Unexpected features %v or degrees of freedom %d\n", inf.Features, inf.DegreesOfFreedom)
+	}
+
+	if math.Abs(inf.ResidualVariance-0.016) > 1e-10 {
+		t.Errorf("Expected residual variance 0.016 got %f\n", inf.ResidualVariance)
+	}
+
+	// The covariance is s^2(X^TX)^{-1}, where the intercept is the last column of X
+	want := mat.NewDense(2, 2, []float64{0.0032, -0.0048, -0.0048, 0.0112})
+	if !mat.EqualApprox(inf.CovarianceMatrix(), want, 1e-10) {
+		t.Errorf("Expected covariance\n%v\ngot\n%v\n", mat.Formatted(want), mat.Formatted(inf.CovarianceMatrix()))
+	}
+
+	fname := "inferenceRoundTrip.json"
+	defer os.Remove(fname)
+	if err := SaveModel(fname, model); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	read, err := ReadModel(fname)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	if read.SchemaVersion != ModelSchemaVersion {
+		t.Errorf("Expected schema version %d got %d\n", ModelSchemaVersion, read.SchemaVersion)
+	}
+
+	predData := Dataset{
+		X:        mat.NewDense(2, 1, []float64{1.5, 10.0}),
+		ColNames: []string{"x"},
+	}
+	got, err := read.PredictWithUncertainty(predData, 0.95)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	wantPred, err := GetPredictionsWithLevel(Dataset{
+		X:        mat.NewDense(4, 2, []float64{1.0, 0.0, 1.0, 1.0, 1.0, 2.0, 1.0, 3.0}),
+		Y:        data.Y,
+		ColNames: []string{"one", "x"},
+	}, Model{Coeffs: map[string]float64{"one": 0.06, "x": 0.96}}, &Dataset{
+		X:        mat.NewDense(2, 2, []float64{1.0, 1.5, 1.0, 10.0}),
+		ColNames: []string{"one", "x"},
+	}, 0.95)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range wantPred {
+		w, g := wantPred[i], got[i]
+		if !w.IsEqual(g) || math.Abs(w.ConfLower-g.ConfLower) > 1e-8 || math.Abs(w.PredUpper-g.PredUpper) > 1e-8 {
+			t.Errorf("Row %d: Expected\n%+v\ngot\n%+v\n", i, w, g)
+		}
+	}
+}
+
+func TestPredictWithUncertaintyErrors(t *testing.T) {
+	data := Dataset{
+		X:        mat.NewDense(2, 2, []float64{1.0, 2.0, 3.0, 4.0}),
+		ColNames: []string{"x0", "x1"},
+	}
+
+	for i, model := range []Model{
+		// No inference information
+		{Coeffs: map[string]float64{"x0": 1.0}},
+
+		// Covariance does not match the number of coefficients
+		{
+			Coeffs: map[string]float64{"x0": 1.0, "x1": 2.0},
+			Inference: &Inference{
+				Features:         []string{"x0", "x1"},
+				Covariance:       [][]float64{{1.0}},
+				DegreesOfFreedom: 1,
+			},
+		},
+	} {
+		if _, err := model.PredictWithUncertainty(data, 0.95); err == nil {
+			t.Errorf("Test #%d: Expected error\n", i)
+		}
+	}
+}
+
+func TestWithInferenceUndefinedCovariance(t *testing.T) {
+	// More coefficients than data points
+	data := Dataset{
+		X:        mat.NewDense(2, 3, []float64{1.0, 2.0, 3.0, 4.0, 5.0, 7.0}),
+		Y:        mat.NewVecDense(2, []float64{1.0, 2.0}),
+		ColNames: []string{"x0", "x1", "x2"},
+	}
+	model := Model{Coeffs: map[string]float64{"x0": 1.0, "x1": 0.0, "x2": 0.0}}.WithInference(data)
+	if model.Inference.Covariance != nil || model.Inference.DegreesOfFreedom != 1 {
+		t.Errorf("Expected no covariance and one degree of freedom. Got %+v\n", model.Inference)
+	}
+
+	// The residuals are 0 and -2, such that the residual variance is 4 (one degree of freedom)
+	pred, err := model.PredictWithUncertainty(data, 0.95)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	for i, p := range pred {
+		if math.Abs(p.Std-2.0) > 1e-10 || p.ConfLower != p.Value || p.ConfUpper != p.Value {
+			t.Errorf("Row %d: Unexpected prediction %+v\n", i, p)
+		}
+	}
+}
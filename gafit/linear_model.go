@@ -20,6 +20,16 @@ type LinearModelConfig struct {
 	// MaxFeatToDataRatio specifies the maximum value of #feat/#data. If not given,
 	// a default value of 0.5 is used
 	MaxFeatToDataRatio float64
+
+	// Classifier holds the settings for classification models. If nil, a regression model
+	// is fitted. For classification, the target values are class indices
+	Classifier *ClassifierConfig
+
+	// ExtraTargets holds additional target vectors that are fitted with the same features as
+	// Data.Y (shared feature selection for multi-target regression). The score is the sum of
+	// the scores of all targets, and the coefficients of the targets are stored one after
+	// another in OptimizeResult.Coeff
+	ExtraTargets []*mat.VecDense
 }
 
 // GetCostFunction returns the cost function. If not given, AICC is used as default
@@ -145,8 +155,18 @@ func (l *LinearModel) NumIncluded() int {
 // Optimize flips all inclusions in. After a call to this
 // function, the included features are affected and set to the best genome
 func (l *LinearModel) Optimize() OptimizeResult {
+	if l.Config.Classifier != nil {
+		return l.optimizeClassifier()
+	}
+
 	data := l.subDataset()
-	greedyRes := OrthogonalMatchingPursuit(data, l.Config.GetCostFunction(), l.Config.LargestModel())
+	var greedyRes OptimizeResult
+	if len(l.Config.ExtraTargets) > 0 {
+		targets := append([]*mat.VecDense{data.Y}, l.Config.ExtraTargets...)
+		greedyRes = SimultaneousOrthogonalMatchingPursuit(data, targets, l.Config.GetCostFunction(), l.Config.LargestModel())
+	} else {
+		greedyRes = OrthogonalMatchingPursuit(data, l.Config.GetCostFunction(), l.Config.LargestModel())
+	}
 	res := OptimizeResult{
 		Score:   greedyRes.Score,
 		Coeff:   greedyRes.Coeff,
@@ -161,6 +181,37 @@ func (l *LinearModel) Optimize() OptimizeResult {
 	return res
 }
 
+// optimizeClassifier fits a logistic regression model with an intercept to the included
+// features. The coefficients are stored class by class, where the intercept is the last
+// coefficient of each class (see LogisticRegression).
+func (l *LinearModel) optimizeClassifier() OptimizeResult {
+	data := l.subDataset()
+	numClasses := l.Config.Classifier.NumClasses
+	res := OptimizeResult{
+		Score:   math.Inf(1),
+		Include: make([]int, len(l.Include)),
+	}
+	copy(res.Include, l.Include)
+
+	beta, err := LogisticRegression(withIntercept(data.X), data.Y, numClasses)
+	if err != nil {
+		res.Coeff = mat.NewVecDense((data.NumFeatures()+1)*(numClasses-1), nil)
+		return res
+	}
+
+	p, m := beta.Dims()
+	res.Coeff = mat.NewVecDense(p*m, nil)
+	for k := 0; k < m; k++ {
+		for j := 0; j < p; j++ {
+			res.Coeff.SetVec(k*p+j, beta.At(j, k))
+		}
+	}
+
+	logL := MultinomialLogLikelihood(withIntercept(data.X), data.Y, beta)
+	res.Score = l.Config.Classifier.GetCostFunction()(logL, p*m, data.NumData())
+	return res
+}
+
 // OptimizeResult is returned by local optimization of the linear model
 type OptimizeResult struct {
 	Score   float64
@@ -0,0 +1,226 @@
+package gafit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// logisticRidge is a small L2 penalty added in the IRLS iterations. It keeps the Hessian
+// invertible for collinear features and the coefficients finite for separable data
+const logisticRidge = 1e-6
+
+// ClassificationCost is a type used to represent cost functions for classification models.
+// logL is the log-likelihood of the model, k is the number of parameters and n is the number
+// of data points
+type ClassificationCost func(logL float64, k int, n int) float64
+
+// LogisticAic returns Afaike's information criteria based on the multinomial log-likelihood
+func LogisticAic(logL float64, k int, n int) float64 {
+	return 2.0*float64(k) - 2.0*logL
+}
+
+// LogisticAicc returns the corrected Afaike's information criteria based on the multinomial
+// log-likelihood
+func LogisticAicc(logL float64, k int, n int) float64 {
+	denum := float64(n - k - 1)
+	if denum < 1 {
+		denum = 1
+	}
+	return LogisticAic(logL, k, n) + 2.0*float64(k*(k+1))/denum
+}
+
+// LogisticBic returns the Bayes information criterion based on the multinomial log-likelihood
+func LogisticBic(logL float64, k int, n int) float64 {
+	return float64(k)*math.Log(float64(n)) - 2.0*logL
+}
+
+// ClassifierConfig holds the settings used when the genetic algorithm selects features for
+// a classification model
+type ClassifierConfig struct {
+	NumClasses int
+	Cost       ClassificationCost
+}
+
+// GetCostFunction returns the cost function. If not given, LogisticAicc is used
+func (c ClassifierConfig) GetCostFunction() ClassificationCost {
+	if c.Cost == nil {
+		return LogisticAicc
+	}
+	return c.Cost
+}
+
+// ClassProbabilities returns the probability of each class for each row in X. beta holds one
+// column of coefficients for each class except the first (reference) class, whose linear
+// predictor is zero. The returned matrix has one row per row in X and one column per class.
+func ClassProbabilities(X *mat.Dense, beta *mat.Dense) *mat.Dense {
+	var eta mat.Dense
+	eta.Mul(X, beta)
+	r, m := eta.Dims()
+
+	prob := mat.NewDense(r, m+1, nil)
+	for i := 0; i < r; i++ {
+		// Subtract the largest linear predictor to avoid overflow
+		maxEta := 0.0
+		for k := 0; k < m; k++ {
+			maxEta = math.Max(maxEta, eta.At(i, k))
+		}
+
+		norm := math.Exp(-maxEta)
+		prob.Set(i, 0, norm)
+		for k := 0; k < m; k++ {
+			v := math.Exp(eta.At(i, k) - maxEta)
+			prob.Set(i, k+1, v)
+			norm += v
+		}
+
+		for k := 0; k <= m; k++ {
+			prob.Set(i, k, prob.At(i, k)/norm)
+		}
+	}
+	return prob
+}
+
+// MultinomialLogLikelihood returns the log-likelihood of the class indices in y given the
+// coefficients beta (see ClassProbabilities)
+func MultinomialLogLikelihood(X *mat.Dense, y *mat.VecDense, beta *mat.Dense) float64 {
+	prob := ClassProbabilities(X, beta)
+	logL := 0.0
+	for i := 0; i < y.Len(); i++ {
+		// Guard against log(0) for perfectly separated data
+		logL += math.Log(math.Max(prob.At(i, int(y.AtVec(i))), 1e-300))
+	}
+	return logL
+}
+
+// LogisticRegression fits a multinomial logistic regression model by iteratively reweighted
+// least squares (Newton's method). y holds the class index (0, 1, ..., numClasses-1) of each
+// row in X, and the first class is used as reference. The returned matrix has one row per
+// column in X and one column per non-reference class. For two classes this is the ordinary
+// binary logistic regression, where the coefficients give the log-odds of the second class.
+// An intercept is not added automatically.
+func LogisticRegression(X *mat.Dense, y *mat.VecDense, numClasses int) (*mat.Dense, error) {
+	r, p := X.Dims()
+	if r != y.Len() {
+		return nil, errors.New("The number of rows in X must match the length of y")
+	}
+
+	if numClasses < 2 {
+		return nil, errors.New("Classification requires at least two classes")
+	}
+
+	for i := 0; i < y.Len(); i++ {
+		c := y.AtVec(i)
+		if c != math.Floor(c) || c < 0 || int(c) >= numClasses {
+			return nil, fmt.Errorf("Invalid class index %f in row %d", c, i)
+		}
+	}
+
+	m := numClasses - 1
+	n := p * m
+	beta := mat.NewDense(p, m, nil)
+	penalized := func(b *mat.Dense) float64 {
+		return MultinomialLogLikelihood(X, y, b) - 0.5*logisticRidge*mat.Norm(b, 2)*mat.Norm(b, 2)
+	}
+	current := penalized(beta)
+
+	maxIter := 100
+	for iter := 0; iter < maxIter; iter++ {
+		prob := ClassProbabilities(X, beta)
+
+		// Gradient and negative Hessian of the penalized log-likelihood. The parameter of
+		// feature j in class k has index k*p + j
+		grad := mat.NewVecDense(n, nil)
+		hess := mat.NewSymDense(n, nil)
+		for i := 0; i < r; i++ {
+			x := X.RawRowView(i)
+			for k := 0; k < m; k++ {
+				pk := prob.At(i, k+1)
+				resid := -pk
+				if int(y.AtVec(i)) == k+1 {
+					resid += 1.0
+				}
+				for j := 0; j < p; j++ {
+					grad.SetVec(k*p+j, grad.AtVec(k*p+j)+x[j]*resid)
+				}
+
+				for l := k; l < m; l++ {
+					w := -pk * prob.At(i, l+1)
+					if l == k {
+						w += pk
+					}
+					for j := 0; j < p; j++ {
+						start := 0
+						if l == k {
+							start = j
+						}
+						for jj := start; jj < p; jj++ {
+							row, col := k*p+j, l*p+jj
+							hess.SetSym(row, col, hess.At(row, col)+w*x[j]*x[jj])
+						}
+					}
+				}
+			}
+		}
+
+		for k := 0; k < m; k++ {
+			for j := 0; j < p; j++ {
+				idx := k*p + j
+				grad.SetVec(idx, grad.AtVec(idx)-logisticRidge*beta.At(j, k))
+				hess.SetSym(idx, idx, hess.At(idx, idx)+logisticRidge)
+			}
+		}
+
+		var chol mat.Cholesky
+		step := mat.NewVecDense(n, nil)
+		if ok := chol.Factorize(hess); ok {
+			if err := chol.SolveVecTo(step, grad); err != nil {
+				return nil, err
+			}
+		} else if err := step.SolveVec(hess, grad); err != nil {
+			return nil, err
+		}
+
+		// Newton step with step halving, such that the penalized log-likelihood never decreases
+		stepSize := 1.0
+		var trial *mat.Dense
+		next := current
+		for halving := 0; halving < 30; halving++ {
+			trial = mat.DenseCopyOf(beta)
+			for k := 0; k < m; k++ {
+				for j := 0; j < p; j++ {
+					trial.Set(j, k, beta.At(j, k)+stepSize*step.AtVec(k*p+j))
+				}
+			}
+			next = penalized(trial)
+			if next >= current {
+				break
+			}
+			stepSize *= 0.5
+		}
+
+		if next < current {
+			break
+		}
+		beta = trial
+		converged := math.Abs(next-current) < 1e-10*(math.Abs(current)+1e-10)
+		current = next
+		if converged {
+			break
+		}
+	}
+	return beta, nil
+}
+
+// withIntercept returns a copy of X where a column of ones is appended
+func withIntercept(X *mat.Dense) *mat.Dense {
+	r, c := X.Dims()
+	res := mat.NewDense(r, c+1, nil)
+	res.Slice(0, r, 0, c).(*mat.Dense).Copy(X)
+	for i := 0; i < r; i++ {
+		res.Set(i, c, 1.0)
+	}
+	return res
+}
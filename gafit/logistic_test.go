@@ -0,0 +1,160 @@
+package gafit
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/MaxHalford/eaopt"
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// classificationData draws rows from a multinomial logistic model with the given true
+// coefficients (one column per non-reference class, the last row is the intercept)
+func classificationData(n int, beta *mat.Dense, rng *rand.Rand) Dataset {
+	p, _ := beta.Dims()
+	X := mat.NewDense(n, p-1, nil)
+	for i := 0; i < n; i++ {
+		for j := 0; j < p-1; j++ {
+			X.Set(i, j, rng.NormFloat64())
+		}
+	}
+
+	prob := ClassProbabilities(withIntercept(X), beta)
+	_, numClasses := prob.Dims()
+	y := mat.NewVecDense(n, nil)
+	for i := 0; i < n; i++ {
+		r := rng.Float64()
+		c := 0
+		for ; c < numClasses-1 && r > prob.At(i, c); c++ {
+			r -= prob.At(i, c)
+		}
+		y.SetVec(i, float64(c))
+	}
+
+	names := make([]string, p-1)
+	for j := range names {
+		names[j] = string(rune('a' + j))
+	}
+	return Dataset{X: X, Y: y, ColNames: names, TargetName: "class"}
+}
+
+func TestClassProbabilities(t *testing.T) {
+	X := mat.NewDense(2, 1, []float64{0.0, 1.0})
+	beta := mat.NewDense(1, 2, []float64{1.0, 800.0})
+	prob := ClassProbabilities(X, beta)
+
+	want := mat.NewDense(2, 3, []float64{
+		1.0 / 3.0, 1.0 / 3.0, 1.0 / 3.0,
+		0.0, 0.0, 1.0,
+	})
+	if !mat.EqualApprox(prob, want, 1e-10) {
+		t.Errorf("Want\n%v\ngot\n%v\n", mat.Formatted(want), mat.Formatted(prob))
+	}
+}
+
+func TestLogisticRegression(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	for i, test := range []struct {
+		beta *mat.Dense
+	}{
+		// Binary
+		{beta: mat.NewDense(3, 1, []float64{2.0, -1.0, 0.5})},
+
+		// Three classes
+		{beta: mat.NewDense(3, 2, []float64{2.0, 0.0, 0.0, -2.0, 0.0, 1.0})},
+	} {
+		data := classificationData(2000, test.beta, rng)
+		_, m := test.beta.Dims()
+		X := withIntercept(data.X)
+		beta, err := LogisticRegression(X, data.Y, m+1)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+
+		// The estimates should be close to the true coefficients
+		if !mat.EqualApprox(beta, test.beta, 0.3) {
+			t.Errorf("Test #%d: Want\n%v\ngot\n%v\n", i, mat.Formatted(test.beta), mat.Formatted(beta))
+		}
+
+		// The score equations X^T(y_k - p_k) = 0 should be satisfied at the maximum
+		prob := ClassProbabilities(X, beta)
+		r, p := X.Dims()
+		for k := 0; k < m; k++ {
+			for j := 0; j < p; j++ {
+				grad := 0.0
+				for row := 0; row < r; row++ {
+					indicator := 0.0
+					if int(data.Y.AtVec(row)) == k+1 {
+						indicator = 1.0
+					}
+					grad += X.At(row, j) * (indicator - prob.At(row, k+1))
+				}
+				if math.Abs(grad) > 1e-4 {
+					t.Errorf("Test #%d: Gradient component (%d, %d) is %e\n", i, j, k, grad)
+				}
+			}
+		}
+	}
+
+	if _, err := LogisticRegression(mat.NewDense(1, 1, []float64{1.0}), mat.NewVecDense(1, []float64{2.0}), 2); err == nil {
+		t.Errorf("Expected error for invalid class index\n")
+	}
+}
+
+func TestLogisticCosts(t *testing.T) {
+	logL := -10.0
+	got := []float64{LogisticAic(logL, 2, 10), LogisticAicc(logL, 2, 10), LogisticBic(logL, 2, 10)}
+	want := []float64{24.0, 24.0 + 12.0/7.0, 2.0*math.Log(10.0) + 20.0}
+	if !floats.EqualApprox(got, want, 1e-10) {
+		t.Errorf("Want\n%v\ngot\n%v\n", want, got)
+	}
+}
+
+func TestClassificationFeatureSelection(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+
+	// Only the first feature affects the class
+	data := classificationData(400, mat.NewDense(4, 1, []float64{3.0, 0.0, 0.0, 0.0}), rng)
+	data.Classes = []string{"no", "yes"}
+
+	conf := eaopt.NewDefaultGAConfig()
+	conf.PopSize = 10
+	conf.RNG = rng
+	conf.ParallelEval = false
+	ga, err := conf.NewGA()
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	ga.NGenerations = 10
+
+	factory := LinearModelFactory{
+		Config: LinearModelConfig{
+			Data:       data,
+			Classifier: &ClassifierConfig{NumClasses: 2, Cost: LogisticBic},
+		},
+	}
+	if err := ga.Minimize(factory.Generate); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	model := NewModel(ga.HallOfFame[0], data, "bic", "data.csv")
+	if !model.IsClassifier() || !allEqualString(model.Features(), []string{"a"}) {
+		t.Errorf("Expected classifier with feature a. Got %v\n", model)
+	}
+
+	// Most of the training data should be classified correctly
+	correct := 0
+	for i, c := range model.PredictClass(data) {
+		if float64(c) == data.Y.AtVec(i) {
+			correct++
+		}
+	}
+	if correct < 300 {
+		t.Errorf("Only %d of 400 rows are classified correctly\n", correct)
+	}
+}
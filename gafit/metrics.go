@@ -0,0 +1,199 @@
+package gafit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// ClassMetrics holds the performance of a classification model for a single class
+type ClassMetrics struct {
+	Class     string
+	Precision float64
+	Recall    float64
+	F1        float64
+
+	// Support is the number of rows that belong to the class
+	Support int
+
+	// AUC is the area under the ROC curve when the class is treated as the positive class
+	// and all other classes as negative (one-vs-rest). It is nil if the data contains only
+	// positive or only negative examples
+	AUC *float64 `json:",omitempty"`
+}
+
+// ClassificationMetrics holds the performance of a classification model on a labelled dataset
+type ClassificationMetrics struct {
+	Classes  []string
+	Accuracy float64
+	LogLoss  float64
+
+	// AUC is the area under the ROC curve. For two classes, this is the AUC of the second
+	// class. For more classes it is the average of the one-vs-rest AUC of all classes
+	// where it is defined
+	AUC *float64 `json:",omitempty"`
+
+	PerClass []ClassMetrics
+
+	// Confusion holds the number of rows of class i (row) that are predicted as class j (column)
+	Confusion [][]int
+}
+
+// NewClassificationMetrics calculates the metrics from the true class indices y and the
+// predicted class probabilities (one column per class)
+func NewClassificationMetrics(classes []string, y *mat.VecDense, prob *mat.Dense) ClassificationMetrics {
+	numClasses := len(classes)
+	cm := ClassificationMetrics{
+		Classes:   classes,
+		Confusion: make([][]int, numClasses),
+		PerClass:  make([]ClassMetrics, numClasses),
+	}
+	for i := range cm.Confusion {
+		cm.Confusion[i] = make([]int, numClasses)
+	}
+
+	n := y.Len()
+	correct := 0
+	for i := 0; i < n; i++ {
+		row := prob.RawRowView(i)
+		actual := int(y.AtVec(i))
+		predicted := argMax(row)
+		cm.Confusion[actual][predicted]++
+		if actual == predicted {
+			correct++
+		}
+		cm.LogLoss -= math.Log(math.Max(row[actual], 1e-15))
+	}
+	cm.Accuracy = float64(correct) / float64(n)
+	cm.LogLoss /= float64(n)
+
+	aucSum := 0.0
+	numAuc := 0
+	for c := range classes {
+		tp := cm.Confusion[c][c]
+		numPredicted, support := 0, 0
+		for k := 0; k < numClasses; k++ {
+			numPredicted += cm.Confusion[k][c]
+			support += cm.Confusion[c][k]
+		}
+
+		metrics := ClassMetrics{Class: classes[c], Support: support}
+		if numPredicted > 0 {
+			metrics.Precision = float64(tp) / float64(numPredicted)
+		}
+		if support > 0 {
+			metrics.Recall = float64(tp) / float64(support)
+		}
+		if metrics.Precision+metrics.Recall > 0.0 {
+			metrics.F1 = 2.0 * metrics.Precision * metrics.Recall / (metrics.Precision + metrics.Recall)
+		}
+
+		scores := mat.Col(nil, c, prob)
+		positive := make([]bool, n)
+		for i := range positive {
+			positive[i] = int(y.AtVec(i)) == c
+		}
+		if auc, ok := RocAuc(scores, positive); ok {
+			metrics.AUC = &auc
+			aucSum += auc
+			numAuc++
+		}
+		cm.PerClass[c] = metrics
+	}
+
+	if numClasses == 2 {
+		cm.AUC = cm.PerClass[1].AUC
+	} else if numAuc > 0 {
+		auc := aucSum / float64(numAuc)
+		cm.AUC = &auc
+	}
+	return cm
+}
+
+// RocAuc returns the area under the ROC curve, which equals the probability that a randomly
+// selected positive example gets a higher score than a randomly selected negative example
+// (ties count as one half). The second return value is false if there are no positive or no
+// negative examples
+func RocAuc(scores []float64, positive []bool) (float64, bool) {
+	idx := make([]int, len(scores))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool { return scores[idx[i]] < scores[idx[j]] })
+
+	// Sum of the (average) ranks of the positive examples
+	rankSum := 0.0
+	numPos := 0
+	for start := 0; start < len(idx); {
+		end := start
+		for end < len(idx) && scores[idx[end]] == scores[idx[start]] {
+			end++
+		}
+		avgRank := 0.5 * float64(start+end+1)
+		for _, i := range idx[start:end] {
+			if positive[i] {
+				rankSum += avgRank
+				numPos++
+			}
+		}
+		start = end
+	}
+
+	numNeg := len(scores) - numPos
+	if numPos == 0 || numNeg == 0 {
+		return 0.0, false
+	}
+	u := rankSum - float64(numPos*(numPos+1))/2.0
+	return u / float64(numPos*numNeg), true
+}
+
+// WriteJSON writes the metrics in JSON format
+func (cm ClassificationMetrics) WriteJSON(w io.Writer) error {
+	serialized, err := json.MarshalIndent(cm, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", serialized)
+	return err
+}
+
+// WriteTable writes the metrics as human readable tables
+func (cm ClassificationMetrics) WriteTable(w io.Writer) error {
+	fmt.Fprintf(w, "Accuracy: %.4f\n", cm.Accuracy)
+	fmt.Fprintf(w, "Log-loss: %.4f\n", cm.LogLoss)
+	fmt.Fprintf(w, "ROC AUC:  %s\n\n", formatOptional(cm.AUC))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Class\tPrecision\tRecall\tF1\tAUC\tSupport\n")
+	for _, m := range cm.PerClass {
+		fmt.Fprintf(tw, "%s\t%.4f\t%.4f\t%.4f\t%s\t%d\n", m.Class, m.Precision, m.Recall, m.F1, formatOptional(m.AUC), m.Support)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nConfusion matrix (rows: true class, columns: predicted class)\n")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\t%s\n", strings.Join(cm.Classes, "\t"))
+	for i, row := range cm.Confusion {
+		counts := make([]string, len(row))
+		for j, v := range row {
+			counts[j] = fmt.Sprintf("%d", v)
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", cm.Classes[i], strings.Join(counts, "\t"))
+	}
+	return tw.Flush()
+}
+
+func formatOptional(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.4f", *v)
+}
@@ -0,0 +1,128 @@
+package gafit
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestRocAuc(t *testing.T) {
+	for i, test := range []struct {
+		scores   []float64
+		positive []bool
+		want     float64
+		defined  bool
+	}{
+		{
+			scores:   []float64{0.1, 0.4, 0.35, 0.8},
+			positive: []bool{false, false, true, true},
+			want:     0.75,
+			defined:  true,
+		},
+		{
+			scores:   []float64{0.5, 0.5, 0.5, 0.5},
+			positive: []bool{false, true, false, true},
+			want:     0.5,
+			defined:  true,
+		},
+		{
+			scores:   []float64{0.1, 0.9},
+			positive: []bool{false, true},
+			want:     1.0,
+			defined:  true,
+		},
+		{
+			scores:   []float64{0.1, 0.9},
+			positive: []bool{true, true},
+			defined:  false,
+		},
+	} {
+		auc, ok := RocAuc(test.scores, test.positive)
+		if ok != test.defined || (ok && math.Abs(auc-test.want) > 1e-10) {
+			t.Errorf("Test #%d: Want %f (%v) got %f (%v)\n", i, test.want, test.defined, auc, ok)
+		}
+	}
+}
+
+func TestClassificationMetrics(t *testing.T) {
+	classes := []string{"a", "b", "c"}
+	y := mat.NewVecDense(5, []float64{0, 0, 1, 2, 2})
+	prob := mat.NewDense(5, 3, []float64{
+		0.8, 0.1, 0.1,
+		0.3, 0.6, 0.1,
+		0.2, 0.7, 0.1,
+		0.1, 0.1, 0.8,
+		0.5, 0.2, 0.3,
+	})
+
+	cm := NewClassificationMetrics(classes, y, prob)
+
+	wantConfusion := [][]int{{1, 1, 0}, {0, 1, 0}, {1, 0, 1}}
+	if !reflect.DeepEqual(cm.Confusion, wantConfusion) {
+		t.Errorf("Want\n%v\ngot\n%v\n", wantConfusion, cm.Confusion)
+	}
+
+	if math.Abs(cm.Accuracy-0.6) > 1e-10 {
+		t.Errorf("Want accuracy 0.6 got %f\n", cm.Accuracy)
+	}
+
+	wantLogLoss := -(math.Log(0.8) + math.Log(0.3) + math.Log(0.7) + math.Log(0.8) + math.Log(0.3)) / 5.0
+	if math.Abs(cm.LogLoss-wantLogLoss) > 1e-10 {
+		t.Errorf("Want log-loss %f got %f\n", wantLogLoss, cm.LogLoss)
+	}
+
+	// Class a: 2 predicted, 1 correct, 2 in support
+	a := cm.PerClass[0]
+	if a.Precision != 0.5 || a.Recall != 0.5 || a.F1 != 0.5 || a.Support != 2 {
+		t.Errorf("Unexpected metrics for class a: %v\n", a)
+	}
+
+	// Class b: 2 predicted, 1 correct, 1 in support
+	b := cm.PerClass[1]
+	if b.Precision != 0.5 || b.Recall != 1.0 || math.Abs(b.F1-2.0/3.0) > 1e-10 {
+		t.Errorf("Unexpected metrics for class b: %v\n", b)
+	}
+
+	if cm.AUC == nil || cm.PerClass[2].AUC == nil || math.Abs(*cm.PerClass[2].AUC-1.0) > 1e-10 {
+		t.Errorf("Unexpected AUC %v\n", cm.PerClass[2].AUC)
+	}
+
+	var buf bytes.Buffer
+	if err := cm.WriteJSON(&buf); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	var read ClassificationMetrics
+	if err := json.Unmarshal(buf.Bytes(), &read); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	if !reflect.DeepEqual(read, cm) {
+		t.Errorf("JSON round trip failed. Want\n%v\ngot\n%v\n", cm, read)
+	}
+
+	buf.Reset()
+	if err := cm.WriteTable(&buf); err != nil {
+		t.Errorf("%s\n", err)
+	}
+}
+
+func TestClassificationMetricsUndefinedAuc(t *testing.T) {
+	// All rows belong to the first class, so the AUC is not defined
+	y := mat.NewVecDense(2, []float64{0, 0})
+	prob := mat.NewDense(2, 2, []float64{0.9, 0.1, 0.4, 0.6})
+	cm := NewClassificationMetrics([]string{"a", "b"}, y, prob)
+	if cm.AUC != nil {
+		t.Errorf("Expected undefined AUC. Got %f\n", *cm.AUC)
+	}
+
+	var buf bytes.Buffer
+	if err := cm.WriteJSON(&buf); err != nil {
+		t.Errorf("%s\n", err)
+	}
+}
@@ -0,0 +1,183 @@
+package gafit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/MaxHalford/eaopt"
+	"gonum.org/v1/gonum/mat"
+)
+
+// MultiTargetModel holds one regression model for each of several target columns in the
+// same datafile
+type MultiTargetModel struct {
+	// SchemaVersion is the version of the file format (see ModelSchemaVersion)
+	SchemaVersion int
+
+	Targets []string
+
+	// SharedFeatures is true if all models use the same set of features
+	SharedFeatures bool `json:",omitempty"`
+
+	// Models holds the model of each target, in the same order as Targets
+	Models []Model
+}
+
+// Model returns the model of the passed target
+func (m MultiTargetModel) Model(target string) (Model, error) {
+	for i, t := range m.Targets {
+		if t == target {
+			return m.Models[i], nil
+		}
+	}
+	return Model{}, fmt.Errorf("No model for target %s. Available targets: %s", target, strings.Join(m.Targets, ", "))
+}
+
+// SplitTargets creates one dataset for each target column. data must be read without a
+// target (such that all columns are in X). In each of the returned datasets Y holds the values
+// of the target, and all target columns are removed from X
+func SplitTargets(data Dataset, targets []string) ([]Dataset, error) {
+	idx := make(map[string]int)
+	for i, name := range data.ColNames {
+		idx[name] = i
+	}
+
+	isTarget := make(map[string]bool)
+	for _, t := range targets {
+		if _, ok := idx[t]; !ok {
+			return nil, fmt.Errorf("Target column %s not found among the numerical columns", t)
+		}
+		if isTarget[t] {
+			return nil, fmt.Errorf("Target column %s is given more than once", t)
+		}
+		isTarget[t] = true
+	}
+
+	features := []string{}
+	for _, name := range data.ColNames {
+		if !isTarget[name] {
+			features = append(features, name)
+		}
+	}
+
+	if len(features) == 0 {
+		return nil, errors.New("No feature columns left when the target columns are removed")
+	}
+
+	X := data.Submatrix(features)
+	datasets := make([]Dataset, len(targets))
+	for i, t := range targets {
+		datasets[i] = Dataset{
+			X:          X,
+			Y:          mat.NewVecDense(data.NumData(), mat.Col(nil, idx[t], data.X)),
+			ColNames:   features,
+			TargetName: t,
+			Categories: data.Categories,
+		}
+	}
+	return datasets, nil
+}
+
+// NewSharedFeatureModels creates one model per target from the best individual of a GA run
+// where the features are shared between the targets (see LinearModelConfig.ExtraTargets).
+// datasets holds the data of each target, where the first one is the dataset of the GA
+// configuration. The score of each model is the cost of the individual target.
+func NewSharedFeatureModels(best eaopt.Individual, datasets []Dataset, cost string, datafile string) []Model {
+	bestMod := best.Genome.(*LinearModel)
+	res := bestMod.Optimize()
+	features := datasets[0].IncludedFeatures(res.Include)
+	costFunc := bestMod.Config.GetCostFunction()
+
+	p := len(features)
+	models := make([]Model, len(datasets))
+	for t, data := range datasets {
+		block := res.Coeff.RawVector().Data[t*p : (t+1)*p]
+		X := data.Submatrix(features)
+		models[t] = Model{
+			Datafile:   datafile,
+			TargetName: data.TargetName,
+			Coeffs:     join2map(features, block),
+			Categories: data.Categories,
+			Score: Score{
+				Name:  cost,
+				Value: costFunc(X, data.Y, mat.NewVecDense(p, block), features),
+			},
+		}
+	}
+	return models
+}
+
+// ReadMultiTargetModel reads a multi-target model from a JSON file. A file holding a single
+// model is returned as a multi-target model with one target. All models are upgraded and
+// validated (see ParseMultiTargetModel)
+func ReadMultiTargetModel(fname string) (MultiTargetModel, error) {
+	bytes, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return MultiTargetModel{}, err
+	}
+
+	multi, _, err := ParseMultiTargetModel(bytes)
+	if err != nil {
+		return multi, fmt.Errorf("%s: %s", fname, err)
+	}
+	return multi, nil
+}
+
+// SaveMultiTargetModel writes a JSON version of the multi-target model to file. The schema
+// version of the file and all models is set to ModelSchemaVersion
+func SaveMultiTargetModel(fname string, model MultiTargetModel) error {
+	model.SchemaVersion = ModelSchemaVersion
+	models := make([]Model, len(model.Models))
+	for i, m := range model.Models {
+		m.SchemaVersion = ModelSchemaVersion
+		models[i] = m
+	}
+	model.Models = models
+	modelSerialized, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, modelSerialized, 0644)
+}
+
+// SaveMultiTargetPredictions stores the predictions of several targets in a file. For each
+// target there are the same columns as written by SavePredictions, where the first is named
+// <target> and the others are prefixed by <target>_ (e.g. <target>_stddev)
+func SaveMultiTargetPredictions(fname string, targets []string, pred [][]Prediction) error {
+	ofile, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer ofile.Close()
+
+	writer := csv.NewWriter(ofile)
+	defer writer.Flush()
+
+	header := []string{}
+	for _, t := range targets {
+		header = append(header, t, t+"_stddev", t+"_conf_lower", t+"_conf_upper", t+"_pred_lower", t+"_pred_upper")
+	}
+	if err = writer.Write(header); err != nil {
+		return err
+	}
+
+	if len(pred) == 0 {
+		return nil
+	}
+
+	for i := range pred[0] {
+		record := []string{}
+		for t := range targets {
+			record = append(record, pred[t][i].record()...)
+		}
+		if err = writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package gafit
+
+import (
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/MaxHalford/eaopt"
+	"gonum.org/v1/gonum/mat"
+)
+
+// multiTargetData returns a dataset with the feature columns x0, x1, x2, x3 and the target
+// columns y0 = 2x0 - x1, y1 = x0 + 3x2 and y2 = x0 + x1
+func multiTargetData() Dataset {
+	rng := rand.New(rand.NewSource(1))
+	n := 40
+	X := mat.NewDense(n, 7, nil)
+	for i := 0; i < n; i++ {
+		for j := 0; j < 4; j++ {
+			X.Set(i, j, 2.0*rng.Float64()-1.0)
+		}
+		X.Set(i, 4, 2.0*X.At(i, 0)-X.At(i, 1)+0.01*rng.NormFloat64())
+		X.Set(i, 5, X.At(i, 0)+3.0*X.At(i, 2)+0.01*rng.NormFloat64())
+		X.Set(i, 6, X.At(i, 0)+X.At(i, 1)+0.01*rng.NormFloat64())
+	}
+	return Dataset{
+		X:        X,
+		Y:        mat.NewVecDense(n, nil),
+		ColNames: []string{"x0", "x1", "x2", "x3", "y0", "y1", "y2"},
+	}
+}
+
+func TestSplitTargets(t *testing.T) {
+	data := Dataset{
+		X:        mat.NewDense(2, 4, []float64{1.0, 2.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0}),
+		ColNames: []string{"a", "y1", "b", "y2"},
+	}
+
+	datasets, err := SplitTargets(data, []string{"y2", "y1"})
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	for i, test := range []struct {
+		target string
+		y      []float64
+	}{
+		{"y2", []float64{4.0, 8.0}},
+		{"y1", []float64{2.0, 6.0}},
+	} {
+		d := datasets[i]
+		if d.TargetName != test.target {
+			t.Errorf("Test #%d: Expected target %s got %s\n", i, test.target, d.TargetName)
+		}
+
+		if !reflect.DeepEqual(d.ColNames, []string{"a", "b"}) {
+			t.Errorf("Test #%d: Unexpected features %v\n", i, d.ColNames)
+		}
+
+		if !mat.Equal(d.Y, mat.NewVecDense(2, test.y)) {
+			t.Errorf("Test #%d: Expected target values %v got %v\n", i, test.y, d.Y.RawVector().Data)
+		}
+
+		if !mat.Equal(d.X, mat.NewDense(2, 2, []float64{1.0, 3.0, 5.0, 7.0})) {
+			t.Errorf("Test #%d: Unexpected feature matrix\n%v\n", i, mat.Formatted(d.X))
+		}
+	}
+
+	for i, targets := range [][]string{
+		{"y1", "y3"},
+		{"y1", "y1"},
+		{"a", "b", "y1", "y2"},
+	} {
+		if _, err := SplitTargets(data, targets); err == nil {
+			t.Errorf("Test #%d: Expected error for targets %v\n", i, targets)
+		}
+	}
+}
+
+func TestSimultaneousOrthogonalMatchingPursuit(t *testing.T) {
+	datasets, err := SplitTargets(multiTargetData(), []string{"y0", "y1", "y2"})
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	targets := []*mat.VecDense{datasets[0].Y, datasets[1].Y, datasets[2].Y}
+	res := SimultaneousOrthogonalMatchingPursuit(datasets[0], targets, Aicc, 4)
+	if !AllEqualInt(res.Include, []int{1, 1, 1, 0}) {
+		t.Errorf("Expected x0, x1 and x2 to be selected. Got %v\n", res.Include)
+	}
+
+	want := []float64{2.0, -1.0, 0.0, 1.0, 0.0, 3.0, 1.0, 1.0, 0.0}
+	for i, w := range want {
+		if math.Abs(res.Coeff.AtVec(i)-w) > 0.02 {
+			t.Errorf("Coefficient #%d: Want %f got %f\n", i, w, res.Coeff.AtVec(i))
+		}
+	}
+
+	score := 0.0
+	sub := datasets[0].Submatrix([]string{"x0", "x1", "x2"})
+	for i, y := range targets {
+		coeff := mat.NewVecDense(3, res.Coeff.RawVector().Data[3*i:3*i+3])
+		score += Aicc(sub, y, coeff, nil)
+	}
+
+	if math.Abs(score-res.Score) > 1e-8 {
+		t.Errorf("Expected the score to be the sum of the target scores (%f) got %f\n", score, res.Score)
+	}
+}
+
+func TestNewSharedFeatureModels(t *testing.T) {
+	datasets, err := SplitTargets(multiTargetData(), []string{"y0", "y1"})
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	model := LinearModel{
+		Config: LinearModelConfig{
+			Data:         datasets[0],
+			Cost:         Aicc,
+			ExtraTargets: []*mat.VecDense{datasets[1].Y},
+		},
+		Include: []int{1, 1, 1, 1},
+	}
+
+	models := NewSharedFeatureModels(eaopt.Individual{Genome: &model}, datasets, "aicc", "data.csv")
+	if len(models) != 2 {
+		t.Errorf("Expected 2 models got %d\n", len(models))
+		return
+	}
+
+	for i, test := range []struct {
+		target string
+		coeffs map[string]float64
+	}{
+		{"y0", map[string]float64{"x0": 2.0, "x1": -1.0, "x2": 0.0}},
+		{"y1", map[string]float64{"x0": 1.0, "x1": 0.0, "x2": 3.0}},
+	} {
+		m := models[i]
+		if m.TargetName != test.target || m.Datafile != "data.csv" || m.Score.Name != "aicc" {
+			t.Errorf("Test #%d: Unexpected model %v\n", i, m)
+		}
+
+		if !reflect.DeepEqual(m.Features(), []string{"x0", "x1", "x2"}) {
+			t.Errorf("Test #%d: Expected shared features got %v\n", i, m.Features())
+		}
+
+		for name, want := range test.coeffs {
+			if math.Abs(m.Coeffs[name]-want) > 0.02 {
+				t.Errorf("Test #%d: Coefficient %s: Want %f got %f\n", i, name, want, m.Coeffs[name])
+			}
+		}
+	}
+}
+
+func TestMultiTargetModelReadWrite(t *testing.T) {
+	multi := MultiTargetModel{
+		SchemaVersion:  ModelSchemaVersion,
+		Targets:        []string{"y0", "y1"},
+		SharedFeatures: true,
+		Models: []Model{
+			{SchemaVersion: ModelSchemaVersion, Datafile: "data.csv", TargetName: "y0", Coeffs: map[string]float64{"x0": 1.0}},
+			{SchemaVersion: ModelSchemaVersion, Datafile: "data.csv", TargetName: "y1", Coeffs: map[string]float64{"x0": -1.0}},
+		},
+	}
+
+	fname := "multiTargetRoundTrip.json"
+	defer os.Remove(fname)
+	if err := SaveMultiTargetModel(fname, multi); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	read, err := ReadMultiTargetModel(fname)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	if !reflect.DeepEqual(read, multi) {
+		t.Errorf("Expected\n%v\ngot\n%v\n", multi, read)
+	}
+
+	if m, err := read.Model("y1"); err != nil || m.Coeffs["x0"] != -1.0 {
+		t.Errorf("Could not extract model of y1: %v\n", err)
+	}
+
+	if _, err := read.Model("y2"); err == nil {
+		t.Errorf("Expected error for unknown target\n")
+	}
+
+	// A file with a single model is read as a multi-target model with one target
+	single := Model{SchemaVersion: ModelSchemaVersion, Datafile: "data.csv", TargetName: "y", Coeffs: map[string]float64{"x0": 2.0}}
+	if err := SaveModel(fname, single); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	read, err = ReadMultiTargetModel(fname)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	if !reflect.DeepEqual(read.Targets, []string{"y"}) || !reflect.DeepEqual(read.Models, []Model{single}) {
+		t.Errorf("Unexpected multi-target model from single model file: %v\n", read)
+	}
+
+	// Inconsistent number of targets and models
+	if err := ioutil.WriteFile(fname, []byte(`{"Targets": ["y0"], "Models": [{}, {}]}`), 0644); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	if _, err := ReadMultiTargetModel(fname); err == nil {
+		t.Errorf("Expected error when the number of targets and models differ\n")
+	}
+}
@@ -0,0 +1,192 @@
+package gafit
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// Versions written to ONNX files. LinearRegressor and Scaler are part of the ai.onnx.ml domain
+const (
+	onnxIRVersion = 8
+	onnxOpset     = 15
+	onnxMLOpset   = 3
+	onnxMLDomain  = "ai.onnx.ml"
+
+	// onnxFloat is the element type of single precision tensors (TensorProto.FLOAT)
+	onnxFloat = 1
+)
+
+// Attribute types (AttributeProto.AttributeType)
+const (
+	onnxAttrInt    = 2
+	onnxAttrString = 3
+	onnxAttrFloats = 6
+)
+
+// protoBuffer encodes a protocol buffer message. Fields are written in the order the methods
+// are called. Repeated scalar fields are written unpacked, as required by the proto2 syntax
+// of the ONNX schema
+type protoBuffer struct {
+	buf []byte
+}
+
+func (p *protoBuffer) varint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	p.buf = append(p.buf, tmp[:n]...)
+}
+
+func (p *protoBuffer) tag(field int, wireType int) {
+	p.varint(uint64(field<<3 | wireType))
+}
+
+func (p *protoBuffer) int(field int, v int64) {
+	p.tag(field, 0)
+	p.varint(uint64(v))
+}
+
+func (p *protoBuffer) bytes(field int, b []byte) {
+	p.tag(field, 2)
+	p.varint(uint64(len(b)))
+	p.buf = append(p.buf, b...)
+}
+
+func (p *protoBuffer) string(field int, s string) {
+	p.bytes(field, []byte(s))
+}
+
+func (p *protoBuffer) message(field int, m protoBuffer) {
+	p.bytes(field, m.buf)
+}
+
+func (p *protoBuffer) float(field int, v float32) {
+	p.tag(field, 5)
+	var tmp [4]byte
+	binary.LittleEndian.PutUint32(tmp[:], math.Float32bits(v))
+	p.buf = append(p.buf, tmp[:]...)
+}
+
+// onnxTensorInfo returns a ValueInfoProto of a float tensor with shape [N, cols], where N is
+// the (variable) number of rows
+func onnxTensorInfo(name string, cols int) protoBuffer {
+	var rows, columns, shape, tensor, typ, info protoBuffer
+	rows.string(2, "N")
+	columns.int(1, int64(cols))
+	shape.message(1, rows)
+	shape.message(1, columns)
+	tensor.int(1, onnxFloat)
+	tensor.message(2, shape)
+	typ.message(1, tensor)
+	info.string(1, name)
+	info.message(2, typ)
+	return info
+}
+
+func onnxFloatsAttribute(name string, values []float64) protoBuffer {
+	var attr protoBuffer
+	attr.string(1, name)
+	for _, v := range values {
+		attr.float(7, float32(v))
+	}
+	attr.int(20, onnxAttrFloats)
+	return attr
+}
+
+func onnxIntAttribute(name string, v int64) protoBuffer {
+	var attr protoBuffer
+	attr.string(1, name)
+	attr.int(3, v)
+	attr.int(20, onnxAttrInt)
+	return attr
+}
+
+func onnxStringAttribute(name string, v string) protoBuffer {
+	var attr protoBuffer
+	attr.string(1, name)
+	attr.string(4, v)
+	attr.int(20, onnxAttrString)
+	return attr
+}
+
+func onnxNode(name string, opType string, domain string, inputs []string, outputs []string, attrs ...protoBuffer) protoBuffer {
+	var node protoBuffer
+	for _, in := range inputs {
+		node.string(1, in)
+	}
+	for _, out := range outputs {
+		node.string(2, out)
+	}
+	node.string(3, name)
+	node.string(4, opType)
+	for _, attr := range attrs {
+		node.message(5, attr)
+	}
+	if domain != "" {
+		node.string(7, domain)
+	}
+	return node
+}
+
+func onnxOpsetImport(domain string, version int64) protoBuffer {
+	var opset protoBuffer
+	if domain != "" {
+		opset.string(1, domain)
+	}
+	opset.int(2, version)
+	return opset
+}
+
+// WriteONNX writes a regression model as an ONNX graph. Each feature of the model is a graph
+// input of shape [N, 1] (the pipeline of the model is not included). The inputs are
+// concatenated, standardized by a Scaler node if the model was fitted to standardized data,
+// and passed to a LinearRegressor node. The output of the graph is named prediction. Note
+// that ONNX stores the coefficients in single precision
+func WriteONNX(w io.Writer, model Model) error {
+	lc, err := newLinearCoefficients(model)
+	if err != nil {
+		return err
+	}
+
+	var graph protoBuffer
+	graph.message(1, onnxNode("concat", "Concat", "", lc.Features, []string{"gogafit.features"}, onnxIntAttribute("axis", 1)))
+
+	regressorInput := "gogafit.features"
+	if lc.Means != nil {
+		inverse := make([]float64, len(lc.Scales))
+		for i, s := range lc.Scales {
+			inverse[i] = 1.0 / s
+		}
+		graph.message(1, onnxNode("standardize", "Scaler", onnxMLDomain, []string{"gogafit.features"}, []string{"gogafit.standardized"},
+			onnxFloatsAttribute("offset", lc.Means), onnxFloatsAttribute("scale", inverse)))
+		regressorInput = "gogafit.standardized"
+	}
+
+	graph.message(1, onnxNode("regressor", "LinearRegressor", onnxMLDomain, []string{regressorInput}, []string{"prediction"},
+		onnxFloatsAttribute("coefficients", lc.Coeffs),
+		onnxFloatsAttribute("intercepts", []float64{lc.Intercept}),
+		onnxIntAttribute("targets", 1),
+		onnxStringAttribute("post_transform", "NONE")))
+
+	graph.string(2, "gogafit")
+	graph.string(10, "Linear model of "+model.TargetName+" fitted with gogafit")
+	for _, name := range lc.Features {
+		graph.message(11, onnxTensorInfo(name, 1))
+	}
+	graph.message(12, onnxTensorInfo("prediction", 1))
+
+	var target protoBuffer
+	target.string(1, "target")
+	target.string(2, model.TargetName)
+
+	var m protoBuffer
+	m.int(1, onnxIRVersion)
+	m.string(2, "gogafit")
+	m.message(7, graph)
+	m.message(8, onnxOpsetImport("", onnxOpset))
+	m.message(8, onnxOpsetImport(onnxMLDomain, onnxMLOpset))
+	m.message(14, target)
+
+	_, err = w.Write(m.buf)
+	return err
+}
@@ -0,0 +1,161 @@
+package gafit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// protoField is a decoded protocol buffer field. Value holds varints and fixed32 values, and
+// Bytes length delimited fields
+type protoField struct {
+	Num   int
+	Value uint64
+	Bytes []byte
+}
+
+// decodeProto splits a protocol buffer message into its fields
+func decodeProto(buf []byte) ([]protoField, error) {
+	fields := []protoField{}
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return nil, errors.New("Invalid field key")
+		}
+		buf = buf[n:]
+
+		f := protoField{Num: int(key >> 3)}
+		switch key & 7 {
+		case 0:
+			f.Value, n = binary.Uvarint(buf)
+			if n <= 0 {
+				return nil, errors.New("Invalid varint")
+			}
+			buf = buf[n:]
+		case 2:
+			length, n := binary.Uvarint(buf)
+			if n <= 0 || uint64(len(buf)-n) < length {
+				return nil, errors.New("Invalid length")
+			}
+			f.Bytes = buf[n : n+int(length)]
+			buf = buf[n+int(length):]
+		case 5:
+			if len(buf) < 4 {
+				return nil, errors.New("Truncated fixed32")
+			}
+			f.Value = uint64(binary.LittleEndian.Uint32(buf))
+			buf = buf[4:]
+		default:
+			return nil, errors.New("Unsupported wire type")
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// protoFields returns all fields with the given number
+func protoFields(t *testing.T, buf []byte, num int) []protoField {
+	fields, err := decodeProto(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := []protoField{}
+	for _, f := range fields {
+		if f.Num == num {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+func protoString(t *testing.T, buf []byte, num int) string {
+	fields := protoFields(t, buf, num)
+	if len(fields) == 0 {
+		return ""
+	}
+	return string(fields[0].Bytes)
+}
+
+// onnxNodeAttributes returns the float attributes of all nodes in the graph by operator type
+func onnxNodeAttributes(t *testing.T, graph []byte) map[string]map[string][]float64 {
+	res := make(map[string]map[string][]float64)
+	for _, node := range protoFields(t, graph, 1) {
+		attrs := make(map[string][]float64)
+		for _, attr := range protoFields(t, node.Bytes, 5) {
+			values := []float64{}
+			for _, f := range protoFields(t, attr.Bytes, 7) {
+				values = append(values, float64(math.Float32frombits(uint32(f.Value))))
+			}
+			attrs[protoString(t, attr.Bytes, 1)] = values
+		}
+		res[protoString(t, node.Bytes, 4)] = attrs
+	}
+	return res
+}
+
+func TestWriteONNX(t *testing.T) {
+	for _, test := range linearExportModels() {
+		var buf bytes.Buffer
+		if err := WriteONNX(&buf, test.model); err != nil {
+			t.Errorf("%s: %s\n", test.name, err)
+			continue
+		}
+		checkGolden(t, test.name+".onnx", buf.Bytes())
+
+		model := buf.Bytes()
+		if v := protoFields(t, model, 1); len(v) != 1 || v[0].Value != onnxIRVersion {
+			t.Errorf("%s: Unexpected IR version %v\n", test.name, v)
+		}
+
+		domains := []string{}
+		for _, opset := range protoFields(t, model, 8) {
+			domains = append(domains, protoString(t, opset.Bytes, 1))
+		}
+		if !reflect.DeepEqual(domains, []string{"", onnxMLDomain}) {
+			t.Errorf("%s: Unexpected opset domains %v\n", test.name, domains)
+		}
+
+		graph := protoFields(t, model, 7)[0].Bytes
+		inputs := []string{}
+		for _, in := range protoFields(t, graph, 11) {
+			inputs = append(inputs, protoString(t, in.Bytes, 1))
+		}
+		if !reflect.DeepEqual(inputs, test.model.Features()) {
+			t.Errorf("%s: Expected inputs %v got %v\n", test.name, test.model.Features(), inputs)
+		}
+
+		nodes := onnxNodeAttributes(t, graph)
+		regressor, ok := nodes["LinearRegressor"]
+		if !ok {
+			t.Errorf("%s: No LinearRegressor node\n", test.name)
+			continue
+		}
+
+		scaler, standardized := nodes["Scaler"]
+		if standardized != (test.model.Standardization != nil) {
+			t.Errorf("%s: Expected a Scaler node only for standardized models\n", test.name)
+		}
+
+		// Evaluate the graph and compare with the predictions of the model
+		data := linearExportData(test.model)
+		want := test.model.Predict(data)
+		for i := 0; i < data.NumData(); i++ {
+			got := regressor["intercepts"][0]
+			for j, c := range regressor["coefficients"] {
+				x := data.X.At(i, j)
+				if standardized {
+					x = (x - scaler["offset"][j]) * scaler["scale"][j]
+				}
+				got += c * x
+			}
+
+			if math.Abs(got-want.AtVec(i)) > 1e-5*math.Max(1.0, math.Abs(want.AtVec(i))) {
+				t.Errorf("%s, row %d: Expected %f got %f\n", test.name, i, want.AtVec(i), got)
+			}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package gafit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/davidkleiven/gogafit/elm"
+)
+
+// PolyStep adds polynomial versions of the given columns (see AddPoly)
+type PolyStep struct {
+	Columns []string
+	Order   int
+}
+
+// Apply adds the polynomial columns to data
+func (p PolyStep) Apply(data Dataset) (Dataset, error) {
+	cols, err := columnIndices(data, p.Columns)
+	if err != nil {
+		return data, err
+	}
+	return AddPoly(cols, data, p.Order), nil
+}
+
+// ELMStep replaces the columns of a dataset by the output of a hidden layer in an
+// extreme learning machine
+type ELMStep struct {
+	elm.Layer
+}
+
+// Apply calculates the output of the hidden layer
+func (e ELMStep) Apply(data Dataset) (Dataset, error) {
+	if _, err := columnIndices(data, e.Inputs); err != nil {
+		return data, err
+	}
+
+	res := data.Copy()
+	res.X = e.Output(data.Submatrix(e.Inputs))
+	res.ColNames = make([]string, len(e.Names))
+	copy(res.ColNames, e.Names)
+	res.Categories = nil
+	return res, nil
+}
+
+// KernelStep replaces the columns of a dataset by the kernel between each row and the
+// centers of a kernel ELM
+type KernelStep struct {
+	elm.Kernel
+}
+
+// Apply calculates the kernel columns
+func (k KernelStep) Apply(data Dataset) (Dataset, error) {
+	if _, err := columnIndices(data, k.Inputs); err != nil {
+		return data, err
+	}
+
+	res := data.Copy()
+	res.X = k.Output(data.Submatrix(k.Inputs))
+	res.ColNames = make([]string, len(k.Names))
+	copy(res.ColNames, k.Names)
+	res.Categories = nil
+	return res, nil
+}
+
+// PipelineStep is one transformation in a pipeline. Exactly one of the fields is set.
+type PipelineStep struct {
+	Poly     *PolyStep        `json:",omitempty"`
+	Features *FeatureManifest `json:",omitempty"`
+	ELM      *ELMStep         `json:",omitempty"`
+	Kernel   *KernelStep      `json:",omitempty"`
+}
+
+// Apply applies the transformation to data
+func (ps PipelineStep) Apply(data Dataset) (Dataset, error) {
+	switch {
+	case ps.Poly != nil:
+		return ps.Poly.Apply(data)
+	case ps.Features != nil:
+		return ps.Features.Apply(data)
+	case ps.ELM != nil:
+		return ps.ELM.Apply(data)
+	case ps.Kernel != nil:
+		return ps.Kernel.Apply(data)
+	}
+	return data, errors.New("Empty pipeline step")
+}
+
+// Pipeline is an ordered list of transformations that converts raw input columns into the
+// features used by a model. Categories holds the encoding of categorical columns in the raw data.
+type Pipeline struct {
+	Categories []CategoricalEncoding `json:",omitempty"`
+	Steps      []PipelineStep
+}
+
+// Apply applies all steps in order
+func (p Pipeline) Apply(data Dataset) (Dataset, error) {
+	var err error
+	for i, step := range p.Steps {
+		data, err = step.Apply(data)
+		if err != nil {
+			return data, fmt.Errorf("Pipeline step %d: %s", i, err)
+		}
+	}
+	return data, nil
+}
+
+// Append returns a new pipeline with step added to the end
+func (p Pipeline) Append(step PipelineStep) Pipeline {
+	steps := make([]PipelineStep, len(p.Steps), len(p.Steps)+1)
+	copy(steps, p.Steps)
+	return Pipeline{
+		Categories: p.Categories,
+		Steps:      append(steps, step),
+	}
+}
+
+// PipelineFile returns the name of the file where the pipeline that produced datafile is stored.
+// For data.csv this is data_pipeline.json
+func PipelineFile(datafile string) string {
+	return strings.TrimSuffix(datafile, ".csv") + "_pipeline.json"
+}
+
+// SavePipeline writes the pipeline to a JSON file
+func SavePipeline(fname string, p Pipeline) error {
+	serialized, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, serialized, 0644)
+}
+
+// ReadPipeline reads a pipeline from a JSON file
+func ReadPipeline(fname string) (Pipeline, error) {
+	var p Pipeline
+	f, err := os.Open(fname)
+	if err != nil {
+		return p, err
+	}
+	defer f.Close()
+
+	bytes, err := ioutil.ReadAll(f)
+	if err != nil {
+		return p, err
+	}
+	err = json.Unmarshal(bytes, &p)
+	return p, err
+}
+
+// ReadPipelineIfExists reads the pipeline stored alongside datafile (see PipelineFile). If no
+// such file exists, an empty pipeline and false is returned
+func ReadPipelineIfExists(datafile string) (Pipeline, bool, error) {
+	fname := PipelineFile(datafile)
+	if _, err := os.Stat(fname); os.IsNotExist(err) {
+		return Pipeline{}, false, nil
+	}
+	p, err := ReadPipeline(fname)
+	return p, err == nil, err
+}
+
+// columnIndices returns the index of each of the passed column names
+func columnIndices(data Dataset, names []string) ([]int, error) {
+	idx := make(map[string]int)
+	for i, n := range data.ColNames {
+		idx[n] = i
+	}
+
+	cols := make([]int, len(names))
+	for i, n := range names {
+		c, ok := idx[n]
+		if !ok {
+			return nil, fmt.Errorf("Column %s is not in the dataset", n)
+		}
+		cols[i] = c
+	}
+	return cols, nil
+}
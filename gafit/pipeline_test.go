@@ -0,0 +1,190 @@
+package gafit
+
+import (
+	"os"
+	"testing"
+
+	"github.com/davidkleiven/gogafit/elm"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestPipelineApply(t *testing.T) {
+	data := Dataset{
+		X:        mat.NewDense(2, 2, []float64{1.0, 2.0, 3.0, 4.0}),
+		Y:        mat.NewVecDense(2, []float64{1.0, 2.0}),
+		ColNames: []string{"x1", "x2"},
+	}
+
+	pipeline := Pipeline{}.
+		Append(PipelineStep{Poly: &PolyStep{Columns: []string{"x2"}, Order: 2}}).
+		Append(PipelineStep{Features: &FeatureManifest{Features: []FeatureSpec{{Name: "x1*x2", Expr: "x1*x2"}}}}).
+		Append(PipelineStep{
+			ELM: &ELMStep{
+				Layer: elm.Layer{
+					Inputs: []string{"x2p2", "x1*x2"},
+					Names:  []string{"relu0"},
+					Neurons: []elm.Neuron{
+						{Weights: []float64{1.0, -1.0}, ActivationFunc: elm.Relu},
+					},
+				},
+			},
+		})
+
+	fname := "pipelineRoundTrip.json"
+	defer os.Remove(fname)
+	if err := SavePipeline(fname, pipeline); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	pipeline, err := ReadPipeline(fname)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	res, err := pipeline.Apply(data)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	// x2^2 - x1*x2 = 2.0 and 4.0
+	want := Dataset{
+		X:        mat.NewDense(2, 1, []float64{2.0, 4.0}),
+		Y:        data.Y,
+		ColNames: []string{"relu0"},
+	}
+	if !res.IsEqual(want) {
+		t.Errorf("Want\n%+v\ngot\n%+v\n", want, res)
+	}
+
+	pipeline.Steps[0].Poly.Columns = []string{"x3"}
+	if _, err := pipeline.Apply(data); err == nil {
+		t.Errorf("Expected error for missing column\n")
+	}
+}
+
+func TestModelPrepare(t *testing.T) {
+	raw := Dataset{
+		X:        mat.NewDense(2, 1, []float64{2.0, 3.0}),
+		ColNames: []string{"x1"},
+	}
+
+	model := Model{
+		Coeffs:   map[string]float64{"x1p2": 1.0},
+		Pipeline: &Pipeline{Steps: []PipelineStep{{Poly: &PolyStep{Columns: []string{"x1"}, Order: 2}}}},
+	}
+
+	// Raw data should be transformed
+	prepared, err := model.Prepare(raw)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	pred := model.Predict(prepared)
+	want := mat.NewVecDense(2, []float64{4.0, 9.0})
+	if !mat.EqualApprox(pred, want, 1e-10) {
+		t.Errorf("Want\n%v\ngot\n%v\n", mat.Formatted(want), mat.Formatted(pred))
+	}
+
+	// Data that already contains the features should be unchanged
+	again, err := model.Prepare(prepared)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	if !again.IsEqual(prepared) {
+		t.Errorf("Pipeline applied to already transformed data\n")
+	}
+
+	model.Pipeline = nil
+	if _, err := model.Prepare(raw); err == nil {
+		t.Errorf("Expected error when features are missing and there is no pipeline\n")
+	}
+}
+
+func TestNewELMModel(t *testing.T) {
+	data := Dataset{
+		X:          mat.NewDense(3, 2, []float64{1.0, 2.0, 3.0, 1.0, -1.0, 0.5}),
+		Y:          mat.NewVecDense(3, []float64{1.0, 2.0, 0.0}),
+		ColNames:   []string{"x1", "x2"},
+		TargetName: "y",
+	}
+
+	network := elm.Model{
+		Layer: elm.Layer{
+			Inputs: []string{"x2", "x1"},
+			Names:  []string{"relu0", "sigmoid0"},
+			Neurons: []elm.Neuron{
+				{Weights: []float64{1.0, -1.0}, ActivationFunc: elm.Relu},
+				{Weights: []float64{0.5, 0.2}, Bias: 0.1, ActivationFunc: elm.Sigmoid},
+			},
+		},
+		OutputWeights: []float64{2.0, -1.0},
+	}
+
+	model := NewELMModel(network, Pipeline{}, data, "data.csv")
+	prepared, err := model.Prepare(data)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	want := network.Predict(data.Submatrix(network.Layer.Inputs))
+	got := model.Predict(prepared)
+	if !mat.EqualApprox(want, got, 1e-10) {
+		t.Errorf("Want\n%v\ngot\n%v\n", mat.Formatted(want), mat.Formatted(got))
+	}
+}
+
+func TestNewKernelELMModel(t *testing.T) {
+	data := Dataset{
+		X:          mat.NewDense(4, 2, []float64{1.0, 2.0, 3.0, 1.0, -1.0, 0.5, 0.0, 0.0}),
+		Y:          mat.NewVecDense(4, []float64{1.0, 2.0, 0.0, -1.0}),
+		ColNames:   []string{"x1", "x2"},
+		TargetName: "y",
+	}
+
+	kernel, err := elm.NewKernel("rbf", []string{"x2", "x1"}, data.Submatrix([]string{"x2", "x1"}), nil, 0.5, 0, 0.0)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	network := elm.KernelModel{Kernel: kernel}
+	if err := network.Train(data.Submatrix(kernel.Inputs), data.Y); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	model := NewKernelELMModel(network, Pipeline{}, data, "data.csv")
+	if model.Score.Value > 1e-8 {
+		t.Errorf("Expected the kernel ELM to interpolate the training data. RMSE: %f\n", model.Score.Value)
+	}
+
+	// Round trip through JSON to make sure the kernel step is preserved
+	fname := "kernelModel.json"
+	defer os.Remove(fname)
+	if err := SaveModel(fname, model); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	model, err = ReadModel(fname)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	prepared, err := model.Prepare(data)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	got := model.Predict(prepared)
+	if !mat.EqualApprox(data.Y, got, 1e-8) {
+		t.Errorf("Want\n%v\ngot\n%v\n", mat.Formatted(data.Y), mat.Formatted(got))
+	}
+}
@@ -0,0 +1,219 @@
+package gafit
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// linearCoefficients holds the coefficients of a regression model as used in PMML and ONNX
+// exports. If the model was fitted to standardized data, Means and Scales holds the
+// standardization of each feature and the coefficients and the intercept refer to the
+// standardized features. The scaling of the target is included in the coefficients.
+type linearCoefficients struct {
+	Features  []string
+	Coeffs    []float64
+	Intercept float64
+	Means     []float64
+	Scales    []float64
+}
+
+// newLinearCoefficients extracts the coefficients of a regression model. The features are
+// ordered as in Features
+func newLinearCoefficients(model Model) (linearCoefficients, error) {
+	if model.IsClassifier() {
+		return linearCoefficients{}, errors.New("Export of classification models is not supported")
+	}
+
+	features := model.Features()
+	lc := linearCoefficients{
+		Features:  features,
+		Coeffs:    make([]float64, len(features)),
+		Intercept: model.Intercept,
+	}
+	for i, name := range features {
+		lc.Coeffs[i] = model.Coeffs[name]
+	}
+
+	// y = sum_i c_i x_i + b = sum_i c_i s_i (x_i - m_i)/s_i + b + sum_i c_i m_i
+	if s := model.Standardization; s != nil {
+		lc.Means = make([]float64, len(features))
+		lc.Scales = make([]float64, len(features))
+		for i, name := range features {
+			lc.Means[i] = s.Means[name]
+			lc.Scales[i] = s.Scales[name]
+			lc.Intercept += lc.Coeffs[i] * lc.Means[i]
+			lc.Coeffs[i] *= lc.Scales[i]
+		}
+	}
+	return lc, nil
+}
+
+// pmmlNamespace is the namespace of the PMML version written by WritePMML
+const pmmlNamespace = "http://www.dmg.org/PMML-4_4"
+
+type pmmlDocument struct {
+	XMLName         xml.Name             `xml:"PMML"`
+	Xmlns           string               `xml:"xmlns,attr"`
+	Version         string               `xml:"version,attr"`
+	Header          pmmlHeader           `xml:"Header"`
+	DataDictionary  pmmlDataDictionary   `xml:"DataDictionary"`
+	Transformations *pmmlTransformations `xml:"TransformationDictionary,omitempty"`
+	Model           pmmlRegressionModel  `xml:"RegressionModel"`
+}
+
+type pmmlHeader struct {
+	Description string          `xml:"description,attr"`
+	Application pmmlApplication `xml:"Application"`
+}
+
+type pmmlApplication struct {
+	Name string `xml:"name,attr"`
+}
+
+type pmmlDataDictionary struct {
+	NumberOfFields int             `xml:"numberOfFields,attr"`
+	Fields         []pmmlDataField `xml:"DataField"`
+}
+
+type pmmlDataField struct {
+	Name     string `xml:"name,attr"`
+	Optype   string `xml:"optype,attr"`
+	DataType string `xml:"dataType,attr"`
+}
+
+type pmmlTransformations struct {
+	Fields []pmmlDerivedField `xml:"DerivedField"`
+}
+
+type pmmlDerivedField struct {
+	Name     string    `xml:"name,attr"`
+	Optype   string    `xml:"optype,attr"`
+	DataType string    `xml:"dataType,attr"`
+	Apply    pmmlApply `xml:"Apply"`
+}
+
+// pmmlApply applies a function to its arguments. The arguments are written in the order
+// Apply, FieldRef, Constant
+type pmmlApply struct {
+	Function  string         `xml:"function,attr"`
+	Apply     *pmmlApply     `xml:"Apply,omitempty"`
+	FieldRef  *pmmlFieldRef  `xml:"FieldRef,omitempty"`
+	Constants []pmmlConstant `xml:"Constant"`
+}
+
+type pmmlFieldRef struct {
+	Field string `xml:"field,attr"`
+}
+
+type pmmlConstant struct {
+	DataType string `xml:"dataType,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type pmmlRegressionModel struct {
+	FunctionName  string              `xml:"functionName,attr"`
+	ModelName     string              `xml:"modelName,attr,omitempty"`
+	AlgorithmName string              `xml:"algorithmName,attr"`
+	MiningSchema  pmmlMiningSchema    `xml:"MiningSchema"`
+	Table         pmmlRegressionTable `xml:"RegressionTable"`
+}
+
+type pmmlMiningSchema struct {
+	Fields []pmmlMiningField `xml:"MiningField"`
+}
+
+type pmmlMiningField struct {
+	Name      string `xml:"name,attr"`
+	UsageType string `xml:"usageType,attr,omitempty"`
+}
+
+type pmmlRegressionTable struct {
+	Intercept  string                 `xml:"intercept,attr"`
+	Predictors []pmmlNumericPredictor `xml:"NumericPredictor"`
+}
+
+type pmmlNumericPredictor struct {
+	Name        string `xml:"name,attr"`
+	Coefficient string `xml:"coefficient,attr"`
+}
+
+func pmmlNumber(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// standardizedName returns the name of the standardized version of a feature
+func standardizedName(feature string) string {
+	return feature + "_standardized"
+}
+
+// WritePMML writes a regression model as a PMML 4.4 RegressionModel. The features of the model
+// are the inputs (the pipeline of the model is not included). If the model was fitted to
+// standardized data, the standardization is written as derived fields (x - mean)/scale, and
+// the coefficients refer to the standardized fields
+func WritePMML(w io.Writer, model Model) error {
+	lc, err := newLinearCoefficients(model)
+	if err != nil {
+		return err
+	}
+
+	doc := pmmlDocument{
+		Xmlns:   pmmlNamespace,
+		Version: "4.4",
+		Header: pmmlHeader{
+			Description: "Linear model of " + model.TargetName + " fitted with gogafit",
+			Application: pmmlApplication{Name: "gogafit"},
+		},
+		DataDictionary: pmmlDataDictionary{NumberOfFields: len(lc.Features) + 1},
+		Model: pmmlRegressionModel{
+			FunctionName:  "regression",
+			ModelName:     model.TargetName,
+			AlgorithmName: "gogafit",
+			Table:         pmmlRegressionTable{Intercept: pmmlNumber(lc.Intercept)},
+		},
+	}
+
+	if lc.Means != nil {
+		doc.Transformations = &pmmlTransformations{}
+	}
+
+	for i, name := range lc.Features {
+		doc.DataDictionary.Fields = append(doc.DataDictionary.Fields, pmmlDataField{Name: name, Optype: "continuous", DataType: "double"})
+		doc.Model.MiningSchema.Fields = append(doc.Model.MiningSchema.Fields, pmmlMiningField{Name: name})
+
+		predictor := name
+		if lc.Means != nil {
+			predictor = standardizedName(name)
+			doc.Transformations.Fields = append(doc.Transformations.Fields, pmmlDerivedField{
+				Name:     predictor,
+				Optype:   "continuous",
+				DataType: "double",
+				Apply: pmmlApply{
+					Function: "/",
+					Apply: &pmmlApply{
+						Function:  "-",
+						FieldRef:  &pmmlFieldRef{Field: name},
+						Constants: []pmmlConstant{{DataType: "double", Value: pmmlNumber(lc.Means[i])}},
+					},
+					Constants: []pmmlConstant{{DataType: "double", Value: pmmlNumber(lc.Scales[i])}},
+				},
+			})
+		}
+		doc.Model.Table.Predictors = append(doc.Model.Table.Predictors, pmmlNumericPredictor{Name: predictor, Coefficient: pmmlNumber(lc.Coeffs[i])})
+	}
+
+	doc.DataDictionary.Fields = append(doc.DataDictionary.Fields, pmmlDataField{Name: model.TargetName, Optype: "continuous", DataType: "double"})
+	doc.Model.MiningSchema.Fields = append(doc.Model.MiningSchema.Fields, pmmlMiningField{Name: model.TargetName, UsageType: "target"})
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
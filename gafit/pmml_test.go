@@ -0,0 +1,168 @@
+package gafit
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+var updateGolden = flag.Bool("update", false, "Update the golden files in _testdata/golden")
+
+// linearExportModels returns the models used to test PMML and ONNX exports. The name is the
+// name of the golden file (without extension)
+func linearExportModels() []struct {
+	name  string
+	model Model
+} {
+	return []struct {
+		name  string
+		model Model
+	}{
+		{
+			name:  "linear",
+			model: Model{TargetName: "y", Coeffs: map[string]float64{"x1": 1.5, "x2": -0.25}},
+		},
+		{
+			name: "standardized",
+			model: Model{
+				TargetName: "energy",
+				Coeffs:     map[string]float64{"x1": 2.0, "x<2>&x3": -0.5},
+				Intercept:  0.7,
+				Standardization: &Standardization{
+					Means:       map[string]float64{"x1": 1.0, "x<2>&x3": -2.0},
+					Scales:      map[string]float64{"x1": 2.0, "x<2>&x3": 0.5},
+					TargetMean:  3.0,
+					TargetScale: 1.5,
+				},
+			},
+		},
+	}
+}
+
+// linearExportData returns rows for the features of a model exported with PMML or ONNX
+func linearExportData(model Model) Dataset {
+	return Dataset{
+		X:        mat.NewDense(3, 2, []float64{0.5, 1.2, -1.3, 0.4, 2.1, -3.3}),
+		ColNames: model.Features(),
+	}
+}
+
+// checkGolden compares data with the golden file. If the tests are run with -update, the
+// golden file is written instead
+func checkGolden(t *testing.T, fname string, data []byte) {
+	fname = filepath.Join("_testdata", "golden", fname)
+	if *updateGolden {
+		if err := ioutil.WriteFile(fname, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	if !bytes.Equal(golden, data) {
+		t.Errorf("%s: The export differs from the golden file. Run go test -run %s -update if the change is intended\n", fname, t.Name())
+	}
+}
+
+// evalPMMLApply evaluates the arithmetic functions used for the standardization
+func evalPMMLApply(t *testing.T, apply pmmlApply, values map[string]float64) float64 {
+	args := []float64{}
+	if apply.Apply != nil {
+		args = append(args, evalPMMLApply(t, *apply.Apply, values))
+	}
+	if apply.FieldRef != nil {
+		args = append(args, values[apply.FieldRef.Field])
+	}
+	for _, c := range apply.Constants {
+		v, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args = append(args, v)
+	}
+
+	if len(args) != 2 {
+		t.Fatalf("Function %s expects 2 arguments. Got %d\n", apply.Function, len(args))
+	}
+
+	switch apply.Function {
+	case "-":
+		return args[0] - args[1]
+	case "/":
+		return args[0] / args[1]
+	}
+	t.Fatalf("Unknown function %s\n", apply.Function)
+	return 0.0
+}
+
+func TestWritePMML(t *testing.T) {
+	for _, test := range linearExportModels() {
+		var buf bytes.Buffer
+		if err := WritePMML(&buf, test.model); err != nil {
+			t.Errorf("%s: %s\n", test.name, err)
+			continue
+		}
+		checkGolden(t, test.name+".pmml", buf.Bytes())
+
+		var doc pmmlDocument
+		if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Errorf("%s: %s\n", test.name, err)
+			continue
+		}
+
+		if doc.DataDictionary.NumberOfFields != len(doc.DataDictionary.Fields) {
+			t.Errorf("%s: numberOfFields is %d, but there are %d fields\n", test.name, doc.DataDictionary.NumberOfFields, len(doc.DataDictionary.Fields))
+		}
+
+		mining := doc.Model.MiningSchema.Fields
+		if target := mining[len(mining)-1]; target.Name != test.model.TargetName || target.UsageType != "target" {
+			t.Errorf("%s: Unexpected target field %v\n", test.name, target)
+		}
+
+		// Evaluate the PMML model and compare with the predictions of the model
+		data := linearExportData(test.model)
+		want := test.model.Predict(data)
+		for i := 0; i < data.NumData(); i++ {
+			values := make(map[string]float64)
+			for j, name := range data.ColNames {
+				values[name] = data.X.At(i, j)
+			}
+			if doc.Transformations != nil {
+				for _, field := range doc.Transformations.Fields {
+					values[field.Name] = evalPMMLApply(t, field.Apply, values)
+				}
+			}
+
+			got, err := strconv.ParseFloat(doc.Model.Table.Intercept, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range doc.Model.Table.Predictors {
+				c, err := strconv.ParseFloat(p.Coefficient, 64)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got += c * values[p.Name]
+			}
+
+			if math.Abs(got-want.AtVec(i)) > 1e-10 {
+				t.Errorf("%s, row %d: Expected %f got %f\n", test.name, i, want.AtVec(i), got)
+			}
+		}
+	}
+
+	if err := WritePMML(&bytes.Buffer{}, Model{TargetName: "y", Classes: []string{"a", "b"}}); err == nil {
+		t.Errorf("Expected error for classification model\n")
+	}
+}
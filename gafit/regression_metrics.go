@@ -0,0 +1,278 @@
+package gafit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// ResidualStats summarizes the distribution of the residuals (target - prediction). The
+// higher moments are nil if they are not defined (e.g. when all residuals are zero)
+type ResidualStats struct {
+	Mean     float64
+	Std      *float64 `json:",omitempty"`
+	Min      float64
+	Median   float64
+	Max      float64
+	Skewness *float64 `json:",omitempty"`
+	Kurtosis *float64 `json:",omitempty"`
+}
+
+// RegressionMetrics holds the performance of a regression model on a dataset. Metrics that
+// are not defined (e.g. MAPE when all target values are zero) are nil
+type RegressionMetrics struct {
+	NumData   int
+	NumParams int
+
+	RMSE     float64
+	MAE      float64
+	MaxError float64
+	R2       *float64 `json:",omitempty"`
+	AdjR2    *float64 `json:",omitempty"`
+
+	// MAPE is the mean absolute percentage error. Rows where the target is zero are skipped
+	MAPE *float64 `json:",omitempty"`
+
+	// GCV is the generalized cross validation score (see GeneralizedCV)
+	GCV  *float64 `json:",omitempty"`
+	AICC float64
+	BIC  float64
+
+	Residuals ResidualStats
+}
+
+// NewRegressionMetrics evaluates the model on data. The data must hold the features used by
+// the model and the target values
+func NewRegressionMetrics(model Model, data Dataset) RegressionMetrics {
+	pred := model.Predict(data)
+	y := data.Y
+	n := y.Len()
+	X := model.DesignMatrix(data)
+	coeff := model.CoeffVector()
+
+	rm := RegressionMetrics{
+		NumData:   n,
+		NumParams: coeff.Len(),
+	}
+
+	resid := make([]float64, n)
+	rss, absSum, apeSum := 0.0, 0.0, 0.0
+	numApe := 0
+	for i := range resid {
+		resid[i] = y.AtVec(i) - pred.AtVec(i)
+		rss += resid[i] * resid[i]
+		absSum += math.Abs(resid[i])
+		rm.MaxError = math.Max(rm.MaxError, math.Abs(resid[i]))
+		if y.AtVec(i) != 0.0 {
+			apeSum += math.Abs(resid[i] / y.AtVec(i))
+			numApe++
+		}
+	}
+
+	rm.RMSE = math.Sqrt(rss / float64(n))
+	rm.MAE = absSum / float64(n)
+	if numApe > 0 {
+		rm.MAPE = finiteOrNil(100.0 * apeSum / float64(numApe))
+	}
+
+	sst := meanSumOfSquares(y) * float64(n)
+	rm.R2 = finiteOrNil(1.0 - rss/sst)
+	if rm.R2 != nil && n > rm.NumParams {
+		rm.AdjR2 = finiteOrNil(1.0 - (1.0-*rm.R2)*float64(n-1)/float64(n-rm.NumParams))
+	}
+
+	if n > rm.NumParams {
+		rm.GCV = finiteOrNil(GeneralizedCV(rm.RMSE, X))
+	}
+	rm.AICC = Aicc(X, y, coeff, model.Features())
+	rm.BIC = Bic(X, y, coeff, model.Features())
+
+	sorted := make([]float64, n)
+	copy(sorted, resid)
+	sort.Float64s(sorted)
+	rm.Residuals = ResidualStats{
+		Mean:     stat.Mean(resid, nil),
+		Std:      finiteOrNil(stat.StdDev(resid, nil)),
+		Min:      sorted[0],
+		Median:   stat.Quantile(0.5, stat.Empirical, sorted, nil),
+		Max:      sorted[n-1],
+		Skewness: finiteOrNil(stat.Skew(resid, nil)),
+		Kurtosis: finiteOrNil(stat.ExKurtosis(resid, nil)),
+	}
+	return rm
+}
+
+// finiteOrNil returns a pointer to v if v is finite, otherwise nil
+func finiteOrNil(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+// metricValue is a named value used when writing the metrics as a list
+type metricValue struct {
+	Name  string
+	Value *float64
+}
+
+// values returns the metrics as an ordered list
+func (rm RegressionMetrics) values() []metricValue {
+	num := func(v float64) *float64 { return &v }
+	return []metricValue{
+		{"n", num(float64(rm.NumData))},
+		{"num_params", num(float64(rm.NumParams))},
+		{"rmse", num(rm.RMSE)},
+		{"mae", num(rm.MAE)},
+		{"max_error", num(rm.MaxError)},
+		{"r2", rm.R2},
+		{"adj_r2", rm.AdjR2},
+		{"mape", rm.MAPE},
+		{"gcv", rm.GCV},
+		{"aicc", num(rm.AICC)},
+		{"bic", num(rm.BIC)},
+		{"residual_mean", num(rm.Residuals.Mean)},
+		{"residual_std", rm.Residuals.Std},
+		{"residual_min", num(rm.Residuals.Min)},
+		{"residual_median", num(rm.Residuals.Median)},
+		{"residual_max", num(rm.Residuals.Max)},
+		{"residual_skewness", rm.Residuals.Skewness},
+		{"residual_kurtosis", rm.Residuals.Kurtosis},
+	}
+}
+
+// WriteJSON writes the metrics in JSON format
+func (rm RegressionMetrics) WriteJSON(w io.Writer) error {
+	serialized, err := json.MarshalIndent(rm, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", serialized)
+	return err
+}
+
+// WriteCSV writes the metrics as a CSV file with the columns metric and value. Undefined
+// metrics have an empty value
+func (rm RegressionMetrics) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"metric", "value"}); err != nil {
+		return err
+	}
+
+	for _, mv := range rm.values() {
+		value := ""
+		if mv.Value != nil {
+			value = strconv.FormatFloat(*mv.Value, 'g', -1, 64)
+		}
+		if err := writer.Write([]string{mv.Name, value}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteTable writes the metrics as a human readable table
+func (rm RegressionMetrics) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, mv := range rm.values() {
+		value := "-"
+		if mv.Value != nil {
+			value = fmt.Sprintf("%.6g", *mv.Value)
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", mv.Name, value)
+	}
+	return tw.Flush()
+}
+
+// TargetMetrics holds the regression metrics of one target of a multi-target model
+type TargetMetrics struct {
+	Target string
+	RegressionMetrics
+}
+
+// WriteTargetMetricsJSON writes the metrics of several targets as a JSON array
+func WriteTargetMetricsJSON(w io.Writer, metrics []TargetMetrics) error {
+	serialized, err := json.MarshalIndent(metrics, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", serialized)
+	return err
+}
+
+// WriteTargetMetricsCSV writes the metrics of several targets as a CSV file with the column
+// metric followed by one column per target. Undefined metrics have an empty value
+func WriteTargetMetricsCSV(w io.Writer, metrics []TargetMetrics) error {
+	writer := csv.NewWriter(w)
+	header := []string{"metric"}
+	for _, m := range metrics {
+		header = append(header, m.Target)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range targetMetricRows(metrics) {
+		record := []string{row[0].Name}
+		for _, mv := range row {
+			value := ""
+			if mv.Value != nil {
+				value = strconv.FormatFloat(*mv.Value, 'g', -1, 64)
+			}
+			record = append(record, value)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteTargetMetricsTable writes the metrics of several targets as a human readable table with
+// one column per target
+func WriteTargetMetricsTable(w io.Writer, metrics []TargetMetrics) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "metric")
+	for _, m := range metrics {
+		fmt.Fprintf(tw, "\t%s", m.Target)
+	}
+	fmt.Fprintf(tw, "\n")
+
+	for _, row := range targetMetricRows(metrics) {
+		fmt.Fprintf(tw, "%s", row[0].Name)
+		for _, mv := range row {
+			value := "-"
+			if mv.Value != nil {
+				value = fmt.Sprintf("%.6g", *mv.Value)
+			}
+			fmt.Fprintf(tw, "\t%s", value)
+		}
+		fmt.Fprintf(tw, "\n")
+	}
+	return tw.Flush()
+}
+
+// targetMetricRows returns the metrics ordered as in values, where each row holds the value of
+// one metric for all targets
+func targetMetricRows(metrics []TargetMetrics) [][]metricValue {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	rows := make([][]metricValue, len(metrics[0].values()))
+	for _, m := range metrics {
+		for i, mv := range m.values() {
+			rows[i] = append(rows[i], mv)
+		}
+	}
+	return rows
+}
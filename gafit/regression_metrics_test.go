@@ -0,0 +1,138 @@
+package gafit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestRegressionMetrics(t *testing.T) {
+	data := Dataset{
+		X:          mat.NewDense(4, 1, []float64{1.0, 2.0, 3.0, 5.0}),
+		Y:          mat.NewVecDense(4, []float64{1.0, 2.0, 3.0, 4.0}),
+		ColNames:   []string{"x"},
+		TargetName: "y",
+	}
+	model := Model{Coeffs: map[string]float64{"x": 1.0}}
+
+	// The residuals are 0, 0, 0, -1
+	rm := NewRegressionMetrics(model, data)
+	for i, test := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"rmse", rm.RMSE, 0.5},
+		{"mae", rm.MAE, 0.25},
+		{"max_error", rm.MaxError, 1.0},
+		{"r2", *rm.R2, 0.8},
+		{"adj_r2", *rm.AdjR2, 0.8},
+		{"mape", *rm.MAPE, 6.25},
+		{"residual_mean", rm.Residuals.Mean, -0.25},
+		{"residual_min", rm.Residuals.Min, -1.0},
+		{"residual_median", rm.Residuals.Median, 0.0},
+		{"residual_max", rm.Residuals.Max, 0.0},
+	} {
+		if math.Abs(test.got-test.want) > 1e-10 {
+			t.Errorf("Test #%d (%s): Want %f got %f\n", i, test.name, test.want, test.got)
+		}
+	}
+
+	if rm.NumData != 4 || rm.NumParams != 1 {
+		t.Errorf("Unexpected number of data points (%d) or parameters (%d)\n", rm.NumData, rm.NumParams)
+	}
+
+	var buf bytes.Buffer
+	if err := rm.WriteCSV(&buf); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	if len(records) != len(rm.values())+1 || records[3][0] != "rmse" || records[3][1] != "0.5" {
+		t.Errorf("Unexpected csv output %v\n", records)
+	}
+}
+
+func TestRegressionMetricsPerfectFit(t *testing.T) {
+	data := Dataset{
+		X:          mat.NewDense(3, 1, []float64{0.0, 1.0, 2.0}),
+		Y:          mat.NewVecDense(3, []float64{0.0, 2.0, 4.0}),
+		ColNames:   []string{"x"},
+		TargetName: "y",
+	}
+	model := Model{Coeffs: map[string]float64{"x": 2.0}}
+
+	// The residuals are all zero, such that the higher moments are not defined. The zero
+	// target is skipped in MAPE
+	rm := NewRegressionMetrics(model, data)
+	if rm.Residuals.Skewness != nil || rm.Residuals.Kurtosis != nil {
+		t.Errorf("Expected undefined skewness and kurtosis\n")
+	}
+
+	if rm.MAPE == nil || *rm.MAPE != 0.0 {
+		t.Errorf("Expected MAPE of zero\n")
+	}
+
+	var buf bytes.Buffer
+	for _, write := range []func(*bytes.Buffer) error{
+		func(b *bytes.Buffer) error { return rm.WriteJSON(b) },
+		func(b *bytes.Buffer) error { return rm.WriteCSV(b) },
+		func(b *bytes.Buffer) error { return rm.WriteTable(b) },
+	} {
+		if err := write(&buf); err != nil {
+			t.Errorf("%s\n", err)
+		}
+	}
+}
+
+func TestWriteTargetMetricsCSV(t *testing.T) {
+	data := Dataset{
+		X:          mat.NewDense(4, 1, []float64{1.0, 2.0, 3.0, 5.0}),
+		Y:          mat.NewVecDense(4, []float64{1.0, 2.0, 3.0, 4.0}),
+		ColNames:   []string{"x"},
+		TargetName: "y",
+	}
+
+	metrics := []TargetMetrics{
+		{Target: "y1", RegressionMetrics: NewRegressionMetrics(Model{Coeffs: map[string]float64{"x": 1.0}}, data)},
+		{Target: "y2", RegressionMetrics: NewRegressionMetrics(Model{Coeffs: map[string]float64{"x": 0.0}}, data)},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTargetMetricsCSV(&buf, metrics); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	if !reflect.DeepEqual(records[0], []string{"metric", "y1", "y2"}) {
+		t.Errorf("Unexpected header %v\n", records[0])
+	}
+
+	// The second model predicts zero, such that the RMSE equals sqrt(mean(y^2))
+	if !reflect.DeepEqual(records[3], []string{"rmse", "0.5", strconv.FormatFloat(math.Sqrt(7.5), 'g', -1, 64)}) {
+		t.Errorf("Unexpected rmse row %v\n", records[3])
+	}
+
+	buf.Reset()
+	if err := WriteTargetMetricsTable(&buf, metrics); err != nil {
+		t.Errorf("%s\n", err)
+	}
+
+	if err := WriteTargetMetricsJSON(&buf, metrics); err != nil {
+		t.Errorf("%s\n", err)
+	}
+}
@@ -0,0 +1,300 @@
+package gafit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// modelMigrations holds the functions that upgrade a model from schema version i to i+1. The
+// number of migrations must equal ModelSchemaVersion
+var modelMigrations = []func(m *Model) error{
+	upgradeFromV0,
+}
+
+// upgradeFromV0 upgrades to version 1, which adds the optional coefficient covariance (see
+// Inference). The covariance requires the training data, and is not calculated here. Use
+// WithInference to add it
+func upgradeFromV0(m *Model) error {
+	return nil
+}
+
+// UpgradeModel migrates a model written with an older schema version to ModelSchemaVersion. The
+// second return value is true if the model was upgraded. Models written with a newer schema
+// version than supported result in an error
+func UpgradeModel(m Model) (Model, bool, error) {
+	if m.SchemaVersion > ModelSchemaVersion {
+		return m, false, fmt.Errorf("The model has schema version %d, but the latest supported version is %d. Upgrade gogafit", m.SchemaVersion, ModelSchemaVersion)
+	}
+
+	if m.SchemaVersion < 0 {
+		return m, false, fmt.Errorf("Invalid schema version %d", m.SchemaVersion)
+	}
+
+	upgraded := m.SchemaVersion < ModelSchemaVersion
+	for m.SchemaVersion < ModelSchemaVersion {
+		if err := modelMigrations[m.SchemaVersion](&m); err != nil {
+			return m, false, fmt.Errorf("Upgrade from schema version %d: %s", m.SchemaVersion, err)
+		}
+		m.SchemaVersion++
+	}
+	return m, upgraded, nil
+}
+
+// Validate checks that the model is complete and consistent. It returns an error if the
+// target is missing, a coefficient is not finite, or a part of the model (classes,
+// standardization, covariance or pipeline) refers to features that are unknown to the model
+func (m Model) Validate() error {
+	if strings.TrimSpace(m.TargetName) == "" {
+		return errors.New("The model has no target column (TargetName)")
+	}
+
+	features := m.Features()
+	if len(features) == 0 {
+		return errors.New("The model has no coefficients")
+	}
+
+	for _, name := range features {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("The model has a feature with an empty name")
+		}
+	}
+
+	if err := checkFinite("Intercept", m.Intercept); err != nil {
+		return err
+	}
+
+	if m.IsClassifier() {
+		if err := m.validateClasses(features); err != nil {
+			return err
+		}
+	} else {
+		for _, name := range features {
+			if err := checkFinite("Coefficient of "+name, m.Coeffs[name]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if m.Standardization != nil {
+		for _, name := range features {
+			scale, ok := m.Standardization.Scales[name]
+			if _, hasMean := m.Standardization.Means[name]; !ok || !hasMean {
+				return fmt.Errorf("Feature %s is missing in the standardization", name)
+			}
+			if scale == 0.0 || math.IsNaN(scale) || math.IsInf(scale, 0) {
+				return fmt.Errorf("Invalid standardization scale %f for feature %s", scale, name)
+			}
+		}
+	}
+
+	if m.Inference != nil {
+		if err := m.validateInference(features); err != nil {
+			return err
+		}
+	}
+
+	if m.Pipeline != nil && len(m.Pipeline.Steps) > 0 {
+		// The output columns are only known if the last step is a hidden layer or a kernel
+		last := m.Pipeline.Steps[len(m.Pipeline.Steps)-1]
+		var outputs []string
+		if last.ELM != nil {
+			outputs = last.ELM.Names
+		} else if last.Kernel != nil {
+			outputs = last.Kernel.Names
+		}
+
+		if outputs != nil {
+			known := make(map[string]bool)
+			for _, name := range outputs {
+				known[name] = true
+			}
+			for _, name := range features {
+				if !known[name] {
+					return fmt.Errorf("Unknown feature %s: it is not produced by the last pipeline step", name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateClasses checks that the coefficients of all classes refer to the same features
+func (m Model) validateClasses(features []string) error {
+	if len(m.Classes) < 2 {
+		return fmt.Errorf("A classification model needs at least two classes. Got %d", len(m.Classes))
+	}
+
+	numCoeff := len(m.Classes) - 1
+	if len(m.ClassCoeffs) != numCoeff || len(m.ClassIntercepts) != numCoeff {
+		return fmt.Errorf("Expected coefficients and intercepts for %d classes. Got %d and %d", numCoeff, len(m.ClassCoeffs), len(m.ClassIntercepts))
+	}
+
+	for k, coeffs := range m.ClassCoeffs {
+		class := m.Classes[k+1]
+		if len(coeffs) != len(features) {
+			return fmt.Errorf("Class %s has %d coefficients, but the model has %d features", class, len(coeffs), len(features))
+		}
+
+		for name, v := range coeffs {
+			if _, ok := m.ClassCoeffs[0][name]; !ok {
+				return fmt.Errorf("Unknown feature %s in the coefficients of class %s", name, class)
+			}
+			if err := checkFinite(fmt.Sprintf("Coefficient of %s for class %s", name, class), v); err != nil {
+				return err
+			}
+		}
+
+		if err := checkFinite("Intercept of class "+class, m.ClassIntercepts[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateInference checks that the covariance matches the coefficients of the model
+func (m Model) validateInference(features []string) error {
+	inf := m.Inference
+	if !allEqualString(inf.Features, features) {
+		return fmt.Errorf("The features of the covariance (%s) do not match the features of the model (%s)", strings.Join(inf.Features, ", "), strings.Join(features, ", "))
+	}
+
+	if inf.DegreesOfFreedom < 1 {
+		return fmt.Errorf("Invalid number of degrees of freedom %d", inf.DegreesOfFreedom)
+	}
+
+	if err := checkFinite("ResidualVariance", inf.ResidualVariance); err != nil {
+		return err
+	}
+	if inf.ResidualVariance < 0.0 {
+		return fmt.Errorf("Negative residual variance %f", inf.ResidualVariance)
+	}
+
+	if inf.Covariance == nil {
+		return nil
+	}
+
+	n := len(features)
+	if m.HasIntercept() {
+		n++
+	}
+
+	if len(inf.Covariance) != n {
+		return fmt.Errorf("The covariance matrix has %d rows, but the model has %d coefficients", len(inf.Covariance), n)
+	}
+
+	for i, row := range inf.Covariance {
+		if len(row) != n {
+			return fmt.Errorf("Row %d of the covariance matrix has %d columns, but the model has %d coefficients", i, len(row), n)
+		}
+		for j, v := range row {
+			if err := checkFinite(fmt.Sprintf("Covariance element (%d, %d)", i, j), v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkFinite returns an error if v is NaN or infinite
+func checkFinite(name string, v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("%s is not finite (%f)", name, v)
+	}
+	return nil
+}
+
+// ParseModel decodes a model from JSON, upgrades it to the current schema version (see
+// UpgradeModel) and validates it. Fields that are not part of the schema result in an error.
+// The second return value is true if the model was upgraded
+func ParseModel(data []byte) (Model, bool, error) {
+	var model Model
+	if err := decodeStrict(data, &model); err != nil {
+		return model, false, err
+	}
+	return loadModel(model)
+}
+
+// loadModel upgrades and validates a decoded model
+func loadModel(model Model) (Model, bool, error) {
+	model, upgraded, err := UpgradeModel(model)
+	if err != nil {
+		return model, false, err
+	}
+
+	if err := model.Validate(); err != nil {
+		return model, upgraded, err
+	}
+	return model, upgraded, nil
+}
+
+// IsMultiTargetModel returns true if the JSON document holds a multi-target model
+func IsMultiTargetModel(data []byte) (bool, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return false, err
+	}
+	_, ok := fields["Models"]
+	return ok, nil
+}
+
+// ParseMultiTargetModel decodes a multi-target model from JSON, where all models are upgraded
+// and validated (see ParseModel). A document holding a single model is returned as a
+// multi-target model with one target. The second return value is true if any model was upgraded
+func ParseMultiTargetModel(data []byte) (MultiTargetModel, bool, error) {
+	isMulti, err := IsMultiTargetModel(data)
+	if err != nil {
+		return MultiTargetModel{}, false, err
+	}
+
+	if !isMulti {
+		model, upgraded, err := ParseModel(data)
+		if err != nil {
+			return MultiTargetModel{}, false, err
+		}
+		return MultiTargetModel{
+			SchemaVersion: model.SchemaVersion,
+			Targets:       []string{model.TargetName},
+			Models:        []Model{model},
+		}, upgraded, nil
+	}
+
+	var multi MultiTargetModel
+	if err := decodeStrict(data, &multi); err != nil {
+		return multi, false, err
+	}
+
+	if multi.SchemaVersion > ModelSchemaVersion {
+		return multi, false, fmt.Errorf("The model has schema version %d, but the latest supported version is %d. Upgrade gogafit", multi.SchemaVersion, ModelSchemaVersion)
+	}
+
+	if len(multi.Models) == 0 || len(multi.Models) != len(multi.Targets) {
+		return multi, false, fmt.Errorf("The file holds %d targets, but %d models", len(multi.Targets), len(multi.Models))
+	}
+
+	upgraded := multi.SchemaVersion < ModelSchemaVersion
+	multi.SchemaVersion = ModelSchemaVersion
+	for i, model := range multi.Models {
+		if model.TargetName != multi.Targets[i] {
+			return multi, false, fmt.Errorf("Model %d has target %s, but the target list holds %s", i, model.TargetName, multi.Targets[i])
+		}
+
+		m, up, err := loadModel(model)
+		if err != nil {
+			return multi, false, fmt.Errorf("Target %s: %s", multi.Targets[i], err)
+		}
+		multi.Models[i] = m
+		upgraded = upgraded || up
+	}
+	return multi, upgraded, nil
+}
+
+// decodeStrict decodes JSON into v, where fields that are not part of v result in an error
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
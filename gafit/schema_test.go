@@ -0,0 +1,198 @@
+package gafit
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/davidkleiven/gogafit/elm"
+)
+
+func TestValidate(t *testing.T) {
+	valid := func() Model {
+		return Model{
+			TargetName: "y",
+			Coeffs:     map[string]float64{"x0": 1.0, "x1": 2.0},
+		}
+	}
+
+	for i, test := range []struct {
+		model func() Model
+		msg   string
+	}{
+		{valid, ""},
+		{func() Model { m := valid(); m.TargetName = " "; return m }, "no target"},
+		{func() Model { m := valid(); m.Coeffs = map[string]float64{}; return m }, "no coefficients"},
+		{func() Model { m := valid(); m.Coeffs["x1"] = math.NaN(); return m }, "Coefficient of x1 is not finite"},
+		{func() Model { m := valid(); m.Intercept = math.Inf(1); return m }, "Intercept is not finite"},
+		{
+			func() Model {
+				m := valid()
+				m.Standardization = &Standardization{
+					Means:  map[string]float64{"x0": 0.0, "x1": 0.0},
+					Scales: map[string]float64{"x0": 1.0},
+				}
+				return m
+			},
+			"Feature x1 is missing in the standardization",
+		},
+		{
+			func() Model {
+				m := valid()
+				m.Inference = &Inference{Features: []string{"x0", "x2"}, DegreesOfFreedom: 1}
+				return m
+			},
+			"do not match",
+		},
+		{
+			func() Model {
+				m := valid()
+				m.Inference = &Inference{
+					Features:         []string{"x0", "x1"},
+					Covariance:       [][]float64{{1.0, 0.0}, {0.0, 1.0}, {0.0, 0.0}},
+					DegreesOfFreedom: 1,
+				}
+				return m
+			},
+			"3 rows",
+		},
+		{
+			func() Model {
+				m := valid()
+				m.Inference = &Inference{Features: []string{"x0", "x1"}, DegreesOfFreedom: 0}
+				return m
+			},
+			"degrees of freedom",
+		},
+		{
+			func() Model {
+				m := valid()
+				m.Pipeline = &Pipeline{
+					Steps: []PipelineStep{{ELM: &ELMStep{Layer: elm.Layer{Names: []string{"x0", "h1"}}}}},
+				}
+				return m
+			},
+			"Unknown feature x1",
+		},
+		{
+			func() Model {
+				return Model{
+					TargetName:      "y",
+					Classes:         []string{"a", "b", "c"},
+					ClassCoeffs:     []map[string]float64{{"x0": 1.0}, {"x0": 1.0}},
+					ClassIntercepts: []float64{0.0, 0.0},
+				}
+			},
+			"",
+		},
+		{
+			func() Model {
+				return Model{
+					TargetName:      "y",
+					Classes:         []string{"a", "b", "c"},
+					ClassCoeffs:     []map[string]float64{{"x0": 1.0}, {"x1": 1.0}},
+					ClassIntercepts: []float64{0.0, 0.0},
+				}
+			},
+			"Unknown feature x1 in the coefficients of class c",
+		},
+		{
+			func() Model {
+				return Model{
+					TargetName:      "y",
+					Classes:         []string{"a", "b", "c"},
+					ClassCoeffs:     []map[string]float64{{"x0": 1.0}},
+					ClassIntercepts: []float64{0.0},
+				}
+			},
+			"for 2 classes",
+		},
+	} {
+		err := test.model().Validate()
+		if test.msg == "" {
+			if err != nil {
+				t.Errorf("Test #%d: Unexpected error %s\n", i, err)
+			}
+			continue
+		}
+
+		if err == nil || !strings.Contains(err.Error(), test.msg) {
+			t.Errorf("Test #%d: Expected error containing '%s' got %v\n", i, test.msg, err)
+		}
+	}
+}
+
+func TestUpgradeModel(t *testing.T) {
+	v0 := Model{
+		Datafile:   "_testdata/dataset.csv",
+		TargetName: "Var4",
+		Coeffs:     map[string]float64{"Var1": 1.0, "Var3": 0.5},
+	}
+
+	model, upgraded, err := UpgradeModel(v0)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	if !upgraded || model.SchemaVersion != ModelSchemaVersion {
+		t.Errorf("Expected the model to be upgraded to version %d. Got version %d\n", ModelSchemaVersion, model.SchemaVersion)
+	}
+
+	// The upgrade does not read the training data
+	if model.Inference != nil {
+		t.Errorf("Expected an upgrade without covariance\n")
+	}
+
+	// The model is not changed when it is already at the latest version
+	model.Inference = &Inference{Features: []string{"Var1", "Var3"}, DegreesOfFreedom: 1}
+	again, upgraded, err := UpgradeModel(model)
+	if err != nil || upgraded || again.Inference != model.Inference {
+		t.Errorf("Expected no upgrade of a model at the latest version\n")
+	}
+
+	v0.SchemaVersion = ModelSchemaVersion + 1
+	if _, _, err := UpgradeModel(v0); err == nil {
+		t.Errorf("Expected error for a newer schema version\n")
+	}
+}
+
+func TestParseModel(t *testing.T) {
+	for i, test := range []struct {
+		json string
+		ok   bool
+	}{
+		{`{"SchemaVersion": 1, "TargetName": "y", "Coeffs": {"x": 1.0}}`, true},
+		{`{"SchemaVersion": 1, "TargetName": "y", "Coeffs": {"x": 1.0}, "Coefs": {}}`, false},
+		{`{"SchemaVersion": 1, "Coeffs": {"x": 1.0}}`, false},
+		{`{"SchemaVersion": 1, "TargetName": "y", "Coeffs": {"x": NaN}}`, false},
+		{`{"SchemaVersion": 1, "TargetName": "y", "Coeffs": {"x": 1.0}`, false},
+	} {
+		_, _, err := ParseModel([]byte(test.json))
+		if (err == nil) != test.ok {
+			t.Errorf("Test #%d: Expected ok=%v got error %v\n", i, test.ok, err)
+		}
+	}
+
+	if _, err := ReadModel("_testdata/doesNotExist.json"); err == nil {
+		t.Errorf("Expected error when the model file does not exist\n")
+	}
+}
+
+func TestParseMultiTargetModel(t *testing.T) {
+	for i, test := range []struct {
+		json string
+		ok   bool
+	}{
+		{`{"SchemaVersion": 1, "Targets": ["y0"], "Models": [{"SchemaVersion": 1, "TargetName": "y0", "Coeffs": {"x": 1.0}}]}`, true},
+		{`{"SchemaVersion": 1, "Targets": ["y1"], "Models": [{"SchemaVersion": 1, "TargetName": "y0", "Coeffs": {"x": 1.0}}]}`, false},
+		{`{"SchemaVersion": 1, "Targets": ["y0"], "Models": [{"SchemaVersion": 1, "TargetName": "y0", "Coeffs": {}}]}`, false},
+		{`{"SchemaVersion": 2, "Targets": ["y0"], "Models": [{"SchemaVersion": 1, "TargetName": "y0", "Coeffs": {"x": 1.0}}]}`, false},
+		{`{"SchemaVersion": 1, "Targets": [], "Models": []}`, false},
+	} {
+		_, _, err := ParseMultiTargetModel([]byte(test.json))
+		if (err == nil) != test.ok {
+			t.Errorf("Test #%d: Expected ok=%v got error %v\n", i, test.ok, err)
+		}
+	}
+}
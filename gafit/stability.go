@@ -0,0 +1,252 @@
+package gafit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"text/tabwriter"
+)
+
+// Resampling methods used in stability analysis
+const (
+	// BootstrapResampling draws n rows with replacement
+	BootstrapResampling = "bootstrap"
+
+	// SubsampleResampling draws a fraction of the rows without replacement
+	SubsampleResampling = "subsample"
+)
+
+// FeatureSelector selects the features of a model fitted to data (e.g. by running the GA). The
+// random number generator is seeded for each resample, and should be used for all random
+// choices of the selection, such that the analysis is reproducible
+type FeatureSelector func(data Dataset, rng *rand.Rand) ([]string, error)
+
+// StabilityConfig holds the settings of a stability analysis
+type StabilityConfig struct {
+	// Method is BootstrapResampling or SubsampleResampling
+	Method       string
+	NumResamples int
+
+	// Fraction is the fraction of the rows in each subsample. If not given, 0.5 is used as
+	// in stability selection. It is not used for bootstrap resamples
+	Fraction float64
+
+	// Threshold is the selection frequency above which a feature is considered stable
+	Threshold float64
+
+	// Seed is used to draw the resamples. Resample i uses a random number generator seeded
+	// with Seed + i, which is also passed to the feature selector
+	Seed int64
+}
+
+// ResampleRows returns the row indices of a resample of n rows
+func ResampleRows(n int, method string, fraction float64, rng *rand.Rand) ([]int, error) {
+	switch method {
+	case BootstrapResampling:
+		idx := make([]int, n)
+		for i := range idx {
+			idx[i] = rng.Intn(n)
+		}
+		return idx, nil
+	case SubsampleResampling:
+		if fraction <= 0.0 || fraction > 1.0 {
+			return nil, fmt.Errorf("The subsample fraction must be in (0, 1]. Got %f", fraction)
+		}
+		size := int(fraction*float64(n) + 0.5)
+		if size < 1 {
+			size = 1
+		}
+		idx := rng.Perm(n)[:size]
+		sort.Ints(idx)
+		return idx, nil
+	}
+	return nil, fmt.Errorf("Unknown resampling method %s. Must be %s or %s", method, BootstrapResampling, SubsampleResampling)
+}
+
+// StabilityResult holds the number of resamples where each feature was selected
+type StabilityResult struct {
+	Method       string
+	NumResamples int
+	Threshold    float64
+
+	// Fraction is the fraction of the rows in each subsample. It is zero for bootstrap
+	// resamples
+	Fraction float64
+
+	// NumCandidates is the number of features in the data
+	NumCandidates int
+
+	// Features holds the features that were selected in at least one resample, and Counts
+	// the number of resamples where they were selected. The features are sorted by
+	// decreasing count
+	Features []string
+	Counts   []int
+
+	// MeanNumSelected is the average number of features selected per resample
+	MeanNumSelected float64
+}
+
+// StabilityAnalysis repeats the feature selection on resamples of data and counts how often
+// each feature is selected
+func StabilityAnalysis(data Dataset, selector FeatureSelector, conf StabilityConfig) (StabilityResult, error) {
+	if conf.NumResamples < 1 {
+		return StabilityResult{}, fmt.Errorf("The number of resamples must be positive. Got %d", conf.NumResamples)
+	}
+
+	fraction := conf.Fraction
+	if fraction == 0.0 {
+		fraction = 0.5
+	}
+
+	counts := make(map[string]int)
+	totalSelected := 0
+	for i := 0; i < conf.NumResamples; i++ {
+		rng := rand.New(rand.NewSource(conf.Seed + int64(i)))
+		idx, err := ResampleRows(data.NumData(), conf.Method, fraction, rng)
+		if err != nil {
+			return StabilityResult{}, err
+		}
+
+		features, err := selector(data.Rows(idx), rng)
+		if err != nil {
+			return StabilityResult{}, fmt.Errorf("Resample %d: %s", i, err)
+		}
+
+		for _, name := range features {
+			counts[name]++
+		}
+		totalSelected += len(features)
+	}
+
+	res := StabilityResult{
+		Method:          conf.Method,
+		NumResamples:    conf.NumResamples,
+		Threshold:       conf.Threshold,
+		NumCandidates:   data.NumFeatures(),
+		MeanNumSelected: float64(totalSelected) / float64(conf.NumResamples),
+	}
+	if conf.Method == SubsampleResampling {
+		res.Fraction = fraction
+	}
+
+	for name := range counts {
+		res.Features = append(res.Features, name)
+	}
+	sort.Slice(res.Features, func(i, j int) bool {
+		ci, cj := counts[res.Features[i]], counts[res.Features[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		return res.Features[i] < res.Features[j]
+	})
+
+	res.Counts = make([]int, len(res.Features))
+	for i, name := range res.Features {
+		res.Counts[i] = counts[name]
+	}
+	return res, nil
+}
+
+// Frequencies returns the fraction of the resamples where each feature was selected
+func (r StabilityResult) Frequencies() []float64 {
+	freq := make([]float64, len(r.Counts))
+	for i, c := range r.Counts {
+		freq[i] = float64(c) / float64(r.NumResamples)
+	}
+	return freq
+}
+
+// Stable returns the features with a selection frequency of at least Threshold
+func (r StabilityResult) Stable() []string {
+	stable := []string{}
+	for i, f := range r.Frequencies() {
+		if f >= r.Threshold {
+			stable = append(stable, r.Features[i])
+		}
+	}
+	return stable
+}
+
+// ExpectedFalseSelections returns the upper bound q^2/((2t - 1)p) of the expected number of
+// falsely selected stable features from stability selection (Meinshausen and Bühlmann, 2010),
+// where q is the mean number of selected features, t is the threshold and p is the number of
+// candidate features. The bound is only valid for subsamples of half the rows and thresholds
+// above 0.5, otherwise nil is returned
+func (r StabilityResult) ExpectedFalseSelections() *float64 {
+	if r.Method != SubsampleResampling || r.Fraction != 0.5 || r.Threshold <= 0.5 || r.NumCandidates == 0 {
+		return nil
+	}
+	q := r.MeanNumSelected
+	bound := q * q / ((2.0*r.Threshold - 1.0) * float64(r.NumCandidates))
+	return &bound
+}
+
+// WriteTable writes the selection count and frequency of each feature as a human readable
+// table, where stable features are marked with *
+func (r StabilityResult) WriteTable(w io.Writer) error {
+	fmt.Fprintf(w, "%d %s resamples, %d candidate features, %.3g features selected on average\n\n",
+		r.NumResamples, r.Method, r.NumCandidates, r.MeanNumSelected)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "feature\tcount\tfrequency\tstable\n")
+	for i, f := range r.Frequencies() {
+		stable := ""
+		if f >= r.Threshold {
+			stable = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.3f\t%s\n", r.Features[i], r.Counts[i], f, stable)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\n%d stable features with selection frequency >= %.3g\n", len(r.Stable()), r.Threshold)
+	if bound := r.ExpectedFalseSelections(); bound != nil {
+		fmt.Fprintf(w, "Expected number of falsely selected stable features <= %.3g\n", *bound)
+	}
+	return nil
+}
+
+// stabilityFeature is the JSON representation of a feature in a stability analysis
+type stabilityFeature struct {
+	Name      string
+	Count     int
+	Frequency float64
+	Stable    bool
+}
+
+// WriteJSON writes the result of the stability analysis in JSON format
+func (r StabilityResult) WriteJSON(w io.Writer) error {
+	doc := struct {
+		Method                  string
+		NumResamples            int
+		Threshold               float64
+		Fraction                float64 `json:",omitempty"`
+		NumCandidates           int
+		MeanNumSelected         float64
+		ExpectedFalseSelections *float64 `json:",omitempty"`
+		Features                []stabilityFeature
+	}{
+		Method:                  r.Method,
+		NumResamples:            r.NumResamples,
+		Threshold:               r.Threshold,
+		Fraction:                r.Fraction,
+		NumCandidates:           r.NumCandidates,
+		MeanNumSelected:         r.MeanNumSelected,
+		ExpectedFalseSelections: r.ExpectedFalseSelections(),
+		Features:                []stabilityFeature{},
+	}
+
+	for i, f := range r.Frequencies() {
+		doc.Features = append(doc.Features, stabilityFeature{Name: r.Features[i], Count: r.Counts[i], Frequency: f, Stable: f >= r.Threshold})
+	}
+
+	serialized, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", serialized)
+	return err
+}
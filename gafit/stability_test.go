@@ -0,0 +1,186 @@
+package gafit
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestResampleRows(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i, test := range []struct {
+		method   string
+		fraction float64
+		size     int
+		unique   bool
+	}{
+		{BootstrapResampling, 0.0, 20, false},
+		{SubsampleResampling, 0.5, 10, true},
+		{SubsampleResampling, 0.25, 5, true},
+		{SubsampleResampling, 1.0, 20, true},
+	} {
+		idx, err := ResampleRows(20, test.method, test.fraction, rng)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+
+		if len(idx) != test.size {
+			t.Errorf("Test #%d: Expected %d rows got %d\n", i, test.size, len(idx))
+		}
+
+		seen := make(map[int]bool)
+		for _, row := range idx {
+			if row < 0 || row >= 20 || (test.unique && seen[row]) {
+				t.Errorf("Test #%d: Invalid or repeated row %d\n", i, row)
+			}
+			seen[row] = true
+		}
+	}
+
+	for i, test := range []struct {
+		method   string
+		fraction float64
+	}{
+		{"jackknife", 0.5},
+		{SubsampleResampling, 0.0},
+		{SubsampleResampling, 1.5},
+	} {
+		if _, err := ResampleRows(20, test.method, test.fraction, rng); err == nil {
+			t.Errorf("Test #%d: Expected error\n", i)
+		}
+	}
+}
+
+func TestStabilityAnalysis(t *testing.T) {
+	data := Dataset{
+		X:        mat.NewDense(10, 4, nil),
+		Y:        mat.NewVecDense(10, nil),
+		ColNames: []string{"a", "b", "c", "d"},
+	}
+	for i := 0; i < 10; i++ {
+		data.X.Set(i, 3, float64(i))
+	}
+
+	// a is always selected, b in every second resample and c when the resample contains
+	// a row where d is larger than 8
+	call := 0
+	selector := func(d Dataset, rng *rand.Rand) ([]string, error) {
+		features := []string{"a"}
+		if call%2 == 0 {
+			features = append(features, "b")
+		}
+		if mat.Max(d.X.ColView(3)) > 8.0 {
+			features = append(features, "c")
+		}
+		call++
+		return features, nil
+	}
+
+	res, err := StabilityAnalysis(data, selector, StabilityConfig{
+		Method:       SubsampleResampling,
+		NumResamples: 20,
+		Threshold:    0.6,
+		Seed:         2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Features[0] != "a" || res.Counts[0] != 20 || res.Frequencies()[0] != 1.0 {
+		t.Errorf("Expected a to be selected in all resamples. Got %v %v\n", res.Features, res.Counts)
+	}
+
+	counts := make(map[string]int)
+	for i, name := range res.Features {
+		counts[name] = res.Counts[i]
+	}
+	if counts["b"] != 10 || counts["d"] != 0 {
+		t.Errorf("Unexpected counts %v\n", counts)
+	}
+
+	// c is selected if row 9 is part of the subsample, which happens with probability 1/2
+	if counts["c"] < 3 || counts["c"] > 17 {
+		t.Errorf("Unexpected count %d for c\n", counts["c"])
+	}
+
+	wantMean := float64(20+10+counts["c"]) / 20.0
+	if math.Abs(res.MeanNumSelected-wantMean) > 1e-12 {
+		t.Errorf("Expected mean number of selected features %f got %f\n", wantMean, res.MeanNumSelected)
+	}
+
+	wantBound := wantMean * wantMean / (0.2 * 4.0)
+	if bound := res.ExpectedFalseSelections(); bound == nil || math.Abs(*bound-wantBound) > 1e-12 {
+		t.Errorf("Expected bound %f got %v\n", wantBound, bound)
+	}
+
+	wantStable := []string{"a"}
+	if counts["c"] >= 12 {
+		wantStable = append(wantStable, "c")
+	}
+	if !reflect.DeepEqual(res.Stable(), wantStable) {
+		t.Errorf("Expected stable features %v got %v\n", wantStable, res.Stable())
+	}
+
+	var buf bytes.Buffer
+	if err := res.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"20 subsample resamples, 4 candidate features",
+		"a        20     1.000      *",
+		"b        10     0.500",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Expected the table to contain\n%s\ngot\n%s\n", line, buf.String())
+		}
+	}
+
+	if err := res.WriteJSON(&buf); err != nil {
+		t.Errorf("%s\n", err)
+	}
+
+	res.Threshold = 0.5
+	if res.ExpectedFalseSelections() != nil {
+		t.Errorf("Expected no bound for threshold 0.5\n")
+	}
+
+	res.Threshold = 0.6
+	res.Method = BootstrapResampling
+	if res.ExpectedFalseSelections() != nil {
+		t.Errorf("Expected no bound for bootstrap resamples\n")
+	}
+}
+
+func TestStabilityAnalysisReproducible(t *testing.T) {
+	data := Dataset{
+		X:        mat.NewDense(10, 4, nil),
+		Y:        mat.NewVecDense(10, nil),
+		ColNames: []string{"a", "b", "c", "d"},
+	}
+
+	// The selection is random, such that the counts depend on the generator of each resample
+	selector := func(d Dataset, rng *rand.Rand) ([]string, error) {
+		return []string{d.ColNames[rng.Intn(len(d.ColNames))]}, nil
+	}
+
+	conf := StabilityConfig{Method: BootstrapResampling, NumResamples: 20, Threshold: 0.6, Seed: 7}
+	first, err := StabilityAnalysis(data, selector, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := StabilityAnalysis(data, selector, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same result for the same seed. Got\n%v\n%v\n", first, second)
+	}
+}
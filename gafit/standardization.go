@@ -0,0 +1,100 @@
+package gafit
+
+import (
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// Standardization holds the means and scales used to standardize the columns of a dataset
+// prior to fitting. Feature columns are transformed according to x' = (x - mean)/scale and
+// the target according to y' = (y - TargetMean)/TargetScale. Constant columns are left
+// unchanged (mean 0 and scale 1).
+type Standardization struct {
+	Means       map[string]float64
+	Scales      map[string]float64
+	TargetMean  float64
+	TargetScale float64
+}
+
+// NewStandardization calculates the mean and standard deviation of all columns in data.
+// The target is always centered, since the standardized model has no intercept. If
+// scaleTarget is true, the target is in addition divided by its standard deviation.
+func NewStandardization(data Dataset, scaleTarget bool) Standardization {
+	s := Standardization{
+		Means:       make(map[string]float64),
+		Scales:      make(map[string]float64),
+		TargetScale: 1.0,
+	}
+
+	for i, name := range data.ColNames {
+		col := mat.Col(nil, i, data.X)
+		s.Means[name], s.Scales[name] = meanAndScale(col)
+	}
+
+	if data.Y != nil {
+		var scale float64
+		s.TargetMean, scale = meanAndScale(data.Y.RawVector().Data)
+		if scaleTarget {
+			s.TargetScale = scale
+		}
+	}
+	return s
+}
+
+// meanAndScale returns the mean and the standard deviation of x. If all values are equal,
+// 0 and 1 are returned such that the transformation leaves x unchanged
+func meanAndScale(x []float64) (float64, float64) {
+	if len(x) < 2 || allConstant(mat.NewVecDense(len(x), x), 1e-10) {
+		return 0.0, 1.0
+	}
+	return stat.MeanStdDev(x, nil)
+}
+
+// Apply returns a copy of data where the columns are standardized. Columns that are not
+// part of the standardization are left unchanged.
+func (s Standardization) Apply(data Dataset) Dataset {
+	res := data.Copy()
+	rows, _ := res.X.Dims()
+	for j, name := range res.ColNames {
+		mean, ok := s.Means[name]
+		if !ok {
+			continue
+		}
+		scale := s.Scales[name]
+		for i := 0; i < rows; i++ {
+			res.X.Set(i, j, (res.X.At(i, j)-mean)/scale)
+		}
+	}
+
+	if res.Y != nil {
+		for i := 0; i < res.Y.Len(); i++ {
+			res.Y.SetVec(i, (res.Y.AtVec(i)-s.TargetMean)/s.TargetScale)
+		}
+	}
+	return res
+}
+
+// BackTransform converts coefficients fitted to standardized data into coefficients in the
+// original units. The intercept that arises from the centering is returned as the second
+// value.
+func (s Standardization) BackTransform(coeffs map[string]float64) (map[string]float64, float64) {
+	res := make(map[string]float64)
+	intercept := s.TargetMean
+	for name, c := range coeffs {
+		scale, ok := s.Scales[name]
+		if !ok {
+			scale = 1.0
+		}
+		res[name] = s.TargetScale * c / scale
+		intercept -= res[name] * s.Means[name]
+	}
+	return res, intercept
+}
+
+// Unscale returns a copy of a model fitted to standardized data, where the coefficients are
+// in the original units. The standardization is stored in the returned model.
+func (s Standardization) Unscale(model Model) Model {
+	model.Coeffs, model.Intercept = s.BackTransform(model.Coeffs)
+	model.Standardization = &s
+	return model
+}
@@ -0,0 +1,66 @@
+package gafit
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestStandardizationApply(t *testing.T) {
+	data := Dataset{
+		X:        mat.NewDense(3, 2, []float64{1.0, 5.0, 2.0, 5.0, 3.0, 5.0}),
+		Y:        mat.NewVecDense(3, []float64{2.0, 4.0, 6.0}),
+		ColNames: []string{"feat1", "const"},
+	}
+
+	s := NewStandardization(data, true)
+	res := s.Apply(data)
+
+	want := mat.NewDense(3, 2, []float64{-1.0, 5.0, 0.0, 5.0, 1.0, 5.0})
+	if !mat.EqualApprox(res.X, want, 1e-10) {
+		t.Errorf("Want\n%v\ngot\n%v\n", mat.Formatted(want), mat.Formatted(res.X))
+	}
+
+	wantY := mat.NewVecDense(3, []float64{-1.0, 0.0, 1.0})
+	if !mat.EqualApprox(res.Y, wantY, 1e-10) {
+		t.Errorf("Want\n%v\ngot\n%v\n", mat.Formatted(wantY), mat.Formatted(res.Y))
+	}
+
+	// Original data should be unchanged
+	if math.Abs(data.X.At(0, 0)-1.0) > 1e-10 {
+		t.Errorf("Apply modified the original dataset\n")
+	}
+}
+
+func TestStandardizationBackTransform(t *testing.T) {
+	// y = 2 + 3*x1 - x2
+	X := mat.NewDense(5, 2, []float64{1.0, 4.0, 2.0, 1.0, 3.0, 7.0, 4.0, 2.0, 5.0, 3.0})
+	y := mat.NewVecDense(5, nil)
+	for i := 0; i < 5; i++ {
+		y.SetVec(i, 2.0+3.0*X.At(i, 0)-X.At(i, 1))
+	}
+	data := Dataset{X: X, Y: y, ColNames: []string{"x1", "x2"}}
+
+	for _, scaleTarget := range []bool{false, true} {
+		s := NewStandardization(data, scaleTarget)
+		stdData := s.Apply(data)
+		coeff := Fit(stdData.X, stdData.Y)
+
+		model := s.Unscale(Model{Coeffs: join2map(data.ColNames, coeff.RawVector().Data)})
+
+		tol := 1e-8
+		if math.Abs(model.Coeffs["x1"]-3.0) > tol || math.Abs(model.Coeffs["x2"]+1.0) > tol {
+			t.Errorf("scaleTarget=%v: Unexpected coefficients %v\n", scaleTarget, model.Coeffs)
+		}
+
+		if math.Abs(model.Intercept-2.0) > tol {
+			t.Errorf("scaleTarget=%v: Expected intercept 2.0 got %f\n", scaleTarget, model.Intercept)
+		}
+
+		pred := model.Predict(data)
+		if !mat.EqualApprox(pred, y, tol) {
+			t.Errorf("scaleTarget=%v: Want\n%v\ngot\n%v\n", scaleTarget, mat.Formatted(y), mat.Formatted(pred))
+		}
+	}
+}
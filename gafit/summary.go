@@ -0,0 +1,327 @@
+package gafit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// InterceptName is the name used for the intercept in coefficient tables
+const InterceptName = "(Intercept)"
+
+// CoefficientStats holds the estimate and the statistics of one coefficient. Statistics that
+// are not defined (e.g. when the coefficient covariance is not available) are nil
+type CoefficientStats struct {
+	Name      string
+	Estimate  float64
+	StdErr    *float64 `json:",omitempty"`
+	TValue    *float64 `json:",omitempty"`
+	PValue    *float64 `json:",omitempty"`
+	ConfLower *float64 `json:",omitempty"`
+	ConfUpper *float64 `json:",omitempty"`
+
+	// VIF is the variance inflation factor of the feature. It is nil for the intercept, for
+	// constant features and when the training data is not available
+	VIF *float64 `json:",omitempty"`
+}
+
+// ModelSummary is a regression table of a fitted model
+type ModelSummary struct {
+	Target       string
+	Level        float64
+	Coefficients []CoefficientStats
+
+	// ResidualStdErr is the square root of the residual variance, with DegreesOfFreedom
+	// degrees of freedom
+	ResidualStdErr   float64
+	DegreesOfFreedom int
+
+	// NumData is the number of rows in the training data. The remaining fields require the
+	// training data, and are zero or nil if it is not available
+	NumData int
+	R2      *float64 `json:",omitempty"`
+	AdjR2   *float64 `json:",omitempty"`
+
+	// FStatistic tests if any coefficient other than the constant term is non-zero. If the
+	// model has no constant term, all coefficients are tested and R2 is calculated relative
+	// to zero instead of the mean of the target
+	FStatistic *float64 `json:",omitempty"`
+	FNumDof    int
+	FDenDof    int
+	FPValue    *float64 `json:",omitempty"`
+
+	// Bootstrap holds the bootstrap intervals of the coefficients in the same order as
+	// Coefficients. It is empty unless AddBootstrap is called
+	BootstrapMethod string              `json:",omitempty"`
+	NumResamples    int                 `json:",omitempty"`
+	Reselected      bool                `json:",omitempty"`
+	Bootstrap       []BootstrapInterval `json:",omitempty"`
+}
+
+// NewModelSummary calculates the coefficient statistics of a regression model at the given
+// confidence level (e.g. 0.95). If data is nil, the inference quantities embedded in the model
+// are used, and the variance inflation factors, R2 and the F-statistic are omitted. Otherwise
+// data must be the training data of the model
+func NewModelSummary(model Model, data *Dataset, level float64) (ModelSummary, error) {
+	if model.IsClassifier() {
+		return ModelSummary{}, errors.New("Summaries are only supported for regression models")
+	}
+
+	inf := model.Inference
+	if data != nil {
+		inf = NewInference(model, *data)
+	}
+	if inf == nil {
+		return ModelSummary{}, errors.New("The model holds no covariance information. Refit the model or pass the training data")
+	}
+
+	summary := ModelSummary{
+		Target:           model.TargetName,
+		Level:            level,
+		ResidualStdErr:   math.Sqrt(inf.ResidualVariance),
+		DegreesOfFreedom: inf.DegreesOfFreedom,
+	}
+
+	for _, name := range inf.Features {
+		summary.Coefficients = append(summary.Coefficients, CoefficientStats{Name: name, Estimate: model.Coeffs[name]})
+	}
+	if model.HasIntercept() {
+		summary.Coefficients = append(summary.Coefficients, CoefficientStats{Name: InterceptName, Estimate: model.Intercept})
+	}
+
+	cov := inf.CovarianceMatrix()
+	if cov != nil && cov.Symmetric() != len(summary.Coefficients) {
+		return ModelSummary{}, errors.New("The size of the covariance matrix does not match the number of coefficients")
+	}
+
+	dist := distuv.StudentsT{Mu: 0.0, Sigma: 1.0, Nu: float64(inf.DegreesOfFreedom)}
+	t := dist.Quantile(0.5 + 0.5*level)
+	for i := range summary.Coefficients {
+		if cov == nil {
+			break
+		}
+		c := &summary.Coefficients[i]
+		se := math.Sqrt(cov.At(i, i))
+		c.StdErr = finiteOrNil(se)
+		c.ConfLower = finiteOrNil(c.Estimate - t*se)
+		c.ConfUpper = finiteOrNil(c.Estimate + t*se)
+		if tValue := finiteOrNil(c.Estimate / se); tValue != nil {
+			c.TValue = tValue
+			c.PValue = finiteOrNil(2.0 * dist.Survival(math.Abs(*tValue)))
+		}
+	}
+
+	if data != nil {
+		summary.addFitStatistics(model, *data)
+	}
+
+	// Show the intercept first as in the regression tables of R
+	if model.HasIntercept() {
+		n := len(summary.Coefficients)
+		summary.Coefficients = append(summary.Coefficients[n-1:], summary.Coefficients[:n-1]...)
+	}
+	return summary, nil
+}
+
+// AddBootstrap adds the bootstrap standard errors and percentile intervals of the coefficients
+// at the level of the summary
+func (s *ModelSummary) AddBootstrap(b Bootstrap) {
+	intervals := b.CoefficientIntervals(s.Level)
+	if b.Model.HasIntercept() {
+		n := len(intervals)
+		intervals = append(intervals[n-1:], intervals[:n-1]...)
+	}
+
+	s.BootstrapMethod = b.Method
+	s.NumResamples = len(b.Models)
+	s.Reselected = b.Reselected
+	s.Bootstrap = intervals
+}
+
+// addFitStatistics adds the variance inflation factors, R2 and the F-statistic calculated
+// from the training data
+func (s *ModelSummary) addFitStatistics(model Model, data Dataset) {
+	features := model.Features()
+	sub := data.Submatrix(features)
+	for i, vif := range VarianceInflationFactors(sub) {
+		s.Coefficients[i].VIF = finiteOrNil(vif)
+	}
+
+	// A constant feature acts as an intercept
+	hasConstant := model.HasIntercept()
+	for i := range features {
+		hasConstant = hasConstant || allConstant(sub.ColView(i), 1e-10)
+	}
+
+	X := model.DesignMatrix(data)
+	n, p := X.Dims()
+	rss := Rss(X, data.Y, model.CoeffVector())
+	tss := mat.Dot(data.Y, data.Y)
+	numConst := 0
+	if hasConstant {
+		tss = meanSumOfSquares(data.Y) * float64(n)
+		numConst = 1
+	}
+
+	s.NumData = n
+	s.R2 = finiteOrNil(1.0 - rss/tss)
+	if s.R2 == nil || n <= p {
+		return
+	}
+	s.AdjR2 = finiteOrNil(1.0 - (1.0-*s.R2)*float64(n-numConst)/float64(n-p))
+
+	s.FNumDof = p - numConst
+	s.FDenDof = n - p
+	if s.FNumDof == 0 {
+		return
+	}
+
+	f := ((tss - rss) / float64(s.FNumDof)) / (rss / float64(s.FDenDof))
+	s.FStatistic = finiteOrNil(f)
+	if s.FStatistic != nil {
+		s.FPValue = finiteOrNil(distuv.F{D1: float64(s.FNumDof), D2: float64(s.FDenDof)}.Survival(f))
+	}
+}
+
+// VarianceInflationFactors returns the variance inflation factor 1/(1 - R_j^2) of each column
+// in X, where R_j^2 is the coefficient of determination when column j is regressed on the
+// other columns and a constant. The factors are the diagonal of the inverse correlation
+// matrix. Constant columns are not included in the regressions and their factor is NaN. If
+// the columns are linearly dependent, the factors are +Inf
+func VarianceInflationFactors(X *mat.Dense) []float64 {
+	_, cols := X.Dims()
+	vif := make([]float64, cols)
+
+	varying := []int{}
+	for j := 0; j < cols; j++ {
+		vif[j] = math.NaN()
+		if !allConstant(X.ColView(j), 1e-10) {
+			varying = append(varying, j)
+		}
+	}
+
+	if len(varying) == 0 {
+		return vif
+	}
+
+	var corr mat.SymDense
+	stat.CorrelationMatrix(&corr, subMatrix(X, varying), nil)
+
+	var chol mat.Cholesky
+	var inv mat.SymDense
+	if ok := chol.Factorize(&corr); !ok || chol.InverseTo(&inv) != nil {
+		for _, j := range varying {
+			vif[j] = math.Inf(1)
+		}
+		return vif
+	}
+
+	for i, j := range varying {
+		vif[j] = inv.At(i, i)
+	}
+	return vif
+}
+
+// significanceCode returns the significance stars used in regression tables
+func significanceCode(p float64) string {
+	switch {
+	case p < 0.001:
+		return "***"
+	case p < 0.01:
+		return "**"
+	case p < 0.05:
+		return "*"
+	case p < 0.1:
+		return "."
+	}
+	return ""
+}
+
+// formatPValue formats a p-value. Values below the machine precision are shown as a bound
+func formatPValue(p float64) string {
+	if p < 2.2e-16 {
+		return "<2.2e-16"
+	}
+	return fmt.Sprintf("%.4g", p)
+}
+
+// WriteTable writes the summary as a regression table in the style of R
+func (s ModelSummary) WriteTable(w io.Writer) error {
+	optional := func(v *float64) string {
+		if v == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.6g", *v)
+	}
+
+	lower := 100.0 * (0.5 - 0.5*s.Level)
+	fmt.Fprintf(w, "Target: %s\n\nCoefficients:\n", s.Target)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\tEstimate\tStd. Error\tt value\tPr(>|t|)\t\t%.4g %%\t%.4g %%\tVIF\n", lower, 100.0-lower)
+	for _, c := range s.Coefficients {
+		pValue, code := "-", ""
+		if c.PValue != nil {
+			pValue, code = formatPValue(*c.PValue), significanceCode(*c.PValue)
+		}
+		fmt.Fprintf(tw, "%s\t%.6g\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Name, c.Estimate, optional(c.StdErr),
+			optional(c.TValue), pValue, code, optional(c.ConfLower), optional(c.ConfUpper), optional(c.VIF))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "---\nSignif. codes:  0 '***' 0.001 '**' 0.01 '*' 0.05 '.' 0.1 ' ' 1\n\n")
+	fmt.Fprintf(w, "Residual standard error: %.4g on %d degrees of freedom\n", s.ResidualStdErr, s.DegreesOfFreedom)
+	if s.NumData == 0 {
+		_, err := fmt.Fprintf(w, "R-squared and F-statistic not available without the training data\n")
+		return err
+	}
+
+	fmt.Fprintf(w, "Multiple R-squared: %s, Adjusted R-squared: %s\n", optional(s.R2), optional(s.AdjR2))
+	if s.FStatistic == nil {
+		fmt.Fprintf(w, "F-statistic: -\n")
+	} else {
+		pValue := "-"
+		if s.FPValue != nil {
+			pValue = formatPValue(*s.FPValue)
+		}
+		fmt.Fprintf(w, "F-statistic: %.4g on %d and %d DF, p-value: %s\n", *s.FStatistic, s.FNumDof, s.FDenDof, pValue)
+	}
+	return s.writeBootstrapTable(w)
+}
+
+// writeBootstrapTable writes the bootstrap intervals of the coefficients. Nothing is written
+// if the summary holds no bootstrap
+func (s ModelSummary) writeBootstrapTable(w io.Writer) error {
+	if len(s.Bootstrap) == 0 {
+		return nil
+	}
+
+	features := "fixed features"
+	if s.Reselected {
+		features = "features reselected on each resample"
+	}
+	fmt.Fprintf(w, "\nBootstrap (%s resampling, %d resamples, %s):\n", s.BootstrapMethod, s.NumResamples, features)
+
+	lower := 100.0 * (0.5 - 0.5*s.Level)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\tStd. Error\t%.4g %%\t%.4g %%", lower, 100.0-lower)
+	if s.Reselected {
+		fmt.Fprintf(tw, "\tSelected")
+	}
+	fmt.Fprintf(tw, "\n")
+	for _, b := range s.Bootstrap {
+		fmt.Fprintf(tw, "%s\t%.6g\t%.6g\t%.6g", b.Name, b.StdErr, b.Lower, b.Upper)
+		if s.Reselected {
+			fmt.Fprintf(tw, "\t%.3f", b.Frequency)
+		}
+		fmt.Fprintf(tw, "\n")
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,124 @@
+package gafit
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestVarianceInflationFactors(t *testing.T) {
+	for i, test := range []struct {
+		X    *mat.Dense
+		want []float64
+	}{
+		// Orthogonal columns
+		{mat.NewDense(4, 2, []float64{1.0, 1.0, -1.0, 1.0, 1.0, -1.0, -1.0, -1.0}), []float64{1.0, 1.0}},
+
+		// Correlation 0.8 between the columns gives 1/(1 - 0.64)
+		{mat.NewDense(5, 2, []float64{1.0, 2.0, 2.0, 1.0, 3.0, 4.0, 4.0, 3.0, 5.0, 5.0}), []float64{1.0 / 0.36, 1.0 / 0.36}},
+
+		// Constant columns are excluded
+		{mat.NewDense(3, 2, []float64{1.0, 1.0, 1.0, 2.0, 1.0, 4.0}), []float64{math.NaN(), 1.0}},
+	} {
+		got := VarianceInflationFactors(test.X)
+		for j := range test.want {
+			if math.IsNaN(test.want[j]) {
+				if !math.IsNaN(got[j]) {
+					t.Errorf("Test #%d: Expected NaN for column %d got %f\n", i, j, got[j])
+				}
+			} else if math.Abs(got[j]-test.want[j]) > 1e-10 {
+				t.Errorf("Test #%d: Expected %v got %v\n", i, test.want, got)
+			}
+		}
+	}
+}
+
+func TestModelSummary(t *testing.T) {
+	// Same regression problem as in TestInferenceRoundTrip
+	data := Dataset{
+		X:          mat.NewDense(4, 1, []float64{0.0, 1.0, 2.0, 3.0}),
+		Y:          mat.NewVecDense(4, []float64{0.1, 0.9, 2.1, 2.9}),
+		ColNames:   []string{"x"},
+		TargetName: "y",
+	}
+	model := Model{
+		TargetName:      "y",
+		Coeffs:          map[string]float64{"x": 0.96},
+		Intercept:       0.06,
+		Standardization: &Standardization{Means: map[string]float64{"x": 0.0}, Scales: map[string]float64{"x": 1.0}},
+	}
+
+	summary, err := NewModelSummary(model, &data, DefaultIntervalLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(summary.Coefficients) != 2 || summary.Coefficients[0].Name != InterceptName {
+		t.Fatalf("Expected the intercept as the first of two coefficients, got %v\n", summary.Coefficients)
+	}
+
+	slope := summary.Coefficients[1]
+	tValue := 0.96 / math.Sqrt(0.0032)
+	tol := 1e-8
+	for _, test := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"std. error", *slope.StdErr, math.Sqrt(0.0032)},
+		{"t value", *slope.TValue, tValue},
+		// Closed form of the two-sided p-value with two degrees of freedom
+		{"p value", *slope.PValue, 1.0 - tValue/math.Sqrt(tValue*tValue+2.0)},
+		{"lower", *slope.ConfLower, 0.96 - 4.302652729911*math.Sqrt(0.0032)},
+		{"vif", *slope.VIF, 1.0},
+		{"residual std. error", summary.ResidualStdErr, math.Sqrt(0.016)},
+		{"r2", *summary.R2, 1.0 - 0.032/4.64},
+		{"adj. r2", *summary.AdjR2, 1.0 - 3.0*0.032/(2.0*4.64)},
+
+		// For simple regression, the F-statistic is the square of the t value of the slope
+		{"F", *summary.FStatistic, tValue * tValue},
+		{"F p-value", *summary.FPValue, *slope.PValue},
+	} {
+		if math.Abs(test.got-test.want) > tol*math.Max(1.0, math.Abs(test.want)) {
+			t.Errorf("%s: Expected %f got %f\n", test.name, test.want, test.got)
+		}
+	}
+
+	if summary.FNumDof != 1 || summary.FDenDof != 2 {
+		t.Errorf("Expected 1 and 2 degrees of freedom got %d and %d\n", summary.FNumDof, summary.FDenDof)
+	}
+
+	var buf bytes.Buffer
+	if err := summary.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	table := buf.String()
+	for _, line := range []string{
+		"Estimate  Std. Error  t value   Pr(>|t|)      2.5 %     97.5 %   VIF",
+		"(Intercept)  0.06      0.10583     0.566947  0.6279        -0.39535  0.51535  -",
+		"x            0.96      0.0565685   16.9706   0.003454  **  0.716605  1.20339  1",
+		"F-statistic: 288 on 1 and 2 DF, p-value: 0.003454",
+	} {
+		if !strings.Contains(table, line) {
+			t.Errorf("Expected the table to contain\n%s\ngot\n%s\n", line, table)
+		}
+	}
+
+	// Without data, the embedded inference is used
+	summary, err = NewModelSummary(model.WithInference(data), nil, DefaultIntervalLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.NumData != 0 || summary.FStatistic != nil || summary.Coefficients[1].VIF != nil {
+		t.Errorf("Expected no fit statistics without data\n")
+	}
+	if math.Abs(*summary.Coefficients[1].TValue-tValue) > tol {
+		t.Errorf("Expected t value %f got %f\n", tValue, *summary.Coefficients[1].TValue)
+	}
+
+	if _, err := NewModelSummary(model, nil, DefaultIntervalLevel); err == nil {
+		t.Errorf("Expected error when neither data nor inference is available\n")
+	}
+}
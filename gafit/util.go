@@ -10,9 +10,14 @@ import (
 	"log"
 	"math"
 	"os"
+	"sort"
 	"strconv"
 
 	"github.com/MaxHalford/eaopt"
+	"github.com/davidkleiven/gogafit/elm"
+	"gonum.org/v1/gonum/blas"
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/lapack/lapack64"
 	"gonum.org/v1/gonum/mat"
 )
 
@@ -246,6 +251,51 @@ func HatMatrix(X *mat.Dense) *mat.Dense {
 	return H
 }
 
+// Leverages returns the diagonal of the hat matrix (see HatMatrix). The leverages are the
+// squared row norms of the thin Q factor (n x p) in the QR decomposition of X, such that
+// neither the full Q nor the n x n hat matrix is formed
+func Leverages(X *mat.Dense) []float64 {
+	r, c := X.Dims()
+	h := make([]float64, r)
+
+	// If ther number of columns is larger than the number of rows, all leverages are one
+	if c > r {
+		for i := range h {
+			h[i] = 1.0
+		}
+		return h
+	}
+
+	if c == 0 {
+		return h
+	}
+
+	a := mat.DenseCopyOf(X).RawMatrix()
+	tau := make([]float64, c)
+	work := make([]float64, 1)
+	lapack64.Geqrf(a, tau, work, -1)
+	work = make([]float64, int(work[0]))
+	lapack64.Geqrf(a, tau, work, len(work))
+
+	// The thin Q is obtained by applying Q to the first c columns of the identity matrix
+	Q1 := mat.NewDense(r, c, nil)
+	for j := 0; j < c; j++ {
+		Q1.Set(j, j, 1.0)
+	}
+	q := Q1.RawMatrix()
+	lapack64.Ormqr(blas.Left, blas.NoTrans, a, tau, q, work, -1)
+	if int(work[0]) > len(work) {
+		work = make([]float64, int(work[0]))
+	}
+	lapack64.Ormqr(blas.Left, blas.NoTrans, a, tau, q, work, len(work))
+
+	for i := range h {
+		row := Q1.RawRowView(i)
+		h[i] = floats.Dot(row, row)
+	}
+	return h
+}
+
 func numericRange(x *mat.VecDense) (float64, float64) {
 	if x.Len() == 0 {
 		return 0.0, 0.0
@@ -332,21 +382,18 @@ func CovMatrix(X *mat.Dense, rss float64) (*mat.SymDense, error) {
 	return res, nil
 }
 
-// ReadModel reads a model from a JSON file
+// ReadModel reads a model from a JSON file. Models written with an older schema version are
+// upgraded, and the model is validated (see ParseModel)
 func ReadModel(fname string) (Model, error) {
-	jsonFile, err := os.Open(fname)
+	bytes, err := ioutil.ReadFile(fname)
 	if err != nil {
-		return Model{}, nil
+		return Model{}, err
 	}
-	defer jsonFile.Close()
-	bytes, err := ioutil.ReadAll(jsonFile)
 
+	model, _, err := ParseModel(bytes)
 	if err != nil {
-		return Model{}, err
+		return model, fmt.Errorf("%s: %s", fname, err)
 	}
-
-	var model Model
-	json.Unmarshal(bytes, &model)
 	return model, nil
 }
 
@@ -358,10 +405,204 @@ type Score struct {
 
 // Model is convenience type used to store information about a model
 type Model struct {
+	// SchemaVersion is the version of the file format (see ModelSchemaVersion)
+	SchemaVersion int
+
 	Datafile   string
 	TargetName string
 	Coeffs     map[string]float64
 	Score      Score
+
+	// Categories holds the encodings of categorical columns in the training data. The same
+	// encodings must be applied to data used for prediction
+	Categories []CategoricalEncoding `json:",omitempty"`
+
+	// Intercept is a constant added to all predictions. It is non-zero when the model
+	// was fitted to standardized data
+	Intercept float64 `json:",omitempty"`
+
+	// Standardization holds the means and scales used when the model was fitted to
+	// standardized data. Coeffs and Intercept are always given in the original units.
+	Standardization *Standardization `json:",omitempty"`
+
+	// Pipeline holds the transformations that converts raw input data into the features
+	// of the model (e.g. polynomial expansions and ELM hidden layers)
+	Pipeline *Pipeline `json:",omitempty"`
+
+	// Classes holds the class labels of classification models. The first class is the
+	// reference class. Coeffs is empty for classification models
+	Classes []string `json:",omitempty"`
+
+	// ClassCoeffs holds the coefficients of the log-odds relative to the reference class for
+	// each of the other classes, and ClassIntercepts the corresponding intercepts
+	ClassCoeffs     []map[string]float64 `json:",omitempty"`
+	ClassIntercepts []float64            `json:",omitempty"`
+
+	// Inference holds the coefficient covariance and the residual variance of regression
+	// models, such that the uncertainty of predictions can be calculated without the
+	// training data
+	Inference *Inference `json:",omitempty"`
+}
+
+// IsClassifier returns true if the model is a classification model
+func (m Model) IsClassifier() bool {
+	return len(m.Classes) > 0
+}
+
+// IsNetwork returns true if the features of the model are the outputs of an ELM hidden layer
+// or a kernel, i.e. the last step of the pipeline is an ELM or kernel step
+func (m Model) IsNetwork() bool {
+	if m.Pipeline == nil || len(m.Pipeline.Steps) == 0 {
+		return false
+	}
+	last := m.Pipeline.Steps[len(m.Pipeline.Steps)-1]
+	return last.ELM != nil || last.Kernel != nil
+}
+
+// Prepare converts data into the features used by the model. If data already contains all
+// features, it is returned unchanged. Otherwise data is assumed to hold raw input columns
+// and the pipeline of the model is applied.
+func (m Model) Prepare(data Dataset) (Dataset, error) {
+	if _, err := columnIndices(data, m.Features()); err == nil {
+		return data, nil
+	}
+
+	if m.Pipeline == nil {
+		_, err := columnIndices(data, m.Features())
+		return data, err
+	}
+	return m.Pipeline.Apply(data)
+}
+
+// ReadForModel reads a datafile with the categorical encodings used by the model, and
+// converts it into the features used by the model (see Prepare)
+func ReadForModel(fname string, targetName string, model Model) (Dataset, error) {
+	data, err := ReadWithOptions(fname, targetName, model.ReadOptions())
+	if err != nil {
+		return data, err
+	}
+	return model.Prepare(data)
+}
+
+// HasIntercept returns true if the model has an intercept term that is not part of Coeffs.
+// Classification models always have an intercept
+func (m Model) HasIntercept() bool {
+	return m.Standardization != nil || m.IsClassifier()
+}
+
+// Features returns the names of the features in the model in alphabetical order
+func (m Model) Features() []string {
+	coeffs := m.Coeffs
+	if m.IsClassifier() && len(m.ClassCoeffs) > 0 {
+		coeffs = m.ClassCoeffs[0]
+	}
+
+	names := make([]string, 0, len(coeffs))
+	for k := range coeffs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DesignMatrix returns the columns of data corresponding to the features in the model (in the
+// order given by Features). If the model has an intercept, a column of ones is appended
+func (m Model) DesignMatrix(data Dataset) *mat.Dense {
+	sub := data.Submatrix(m.Features())
+	if !m.HasIntercept() {
+		return sub
+	}
+
+	r, c := sub.Dims()
+	X := sub.Grow(0, 1).(*mat.Dense)
+	for i := 0; i < r; i++ {
+		X.Set(i, c, 1.0)
+	}
+	return X
+}
+
+// CoeffVector returns the coefficients ordered consistently with the columns in DesignMatrix
+func (m Model) CoeffVector() *mat.VecDense {
+	names := m.Features()
+	n := len(names)
+	if m.HasIntercept() {
+		n++
+	}
+	coeff := mat.NewVecDense(n, nil)
+	for i, name := range names {
+		coeff.SetVec(i, m.Coeffs[name])
+	}
+	if m.HasIntercept() {
+		coeff.SetVec(n-1, m.Intercept)
+	}
+	return coeff
+}
+
+// Predict returns the predictions of the model for all rows in data
+func (m Model) Predict(data Dataset) *mat.VecDense {
+	pred := data.Dot(m.Coeffs)
+	for i := 0; i < pred.Len(); i++ {
+		pred.SetVec(i, pred.AtVec(i)+m.Intercept)
+	}
+	return pred
+}
+
+// ClassCoeffMatrix returns the coefficients of a classification model. The rows are ordered
+// consistently with the columns in DesignMatrix, and there is one column for each class
+// except the reference class
+func (m Model) ClassCoeffMatrix() *mat.Dense {
+	names := m.Features()
+	beta := mat.NewDense(len(names)+1, len(m.ClassCoeffs), nil)
+	for k, coeffs := range m.ClassCoeffs {
+		for j, name := range names {
+			beta.Set(j, k, coeffs[name])
+		}
+		beta.Set(len(names), k, m.ClassIntercepts[k])
+	}
+	return beta
+}
+
+// PredictProba returns the probability of each class for all rows in data. There is one
+// column per class, ordered as in Classes
+func (m Model) PredictProba(data Dataset) *mat.Dense {
+	return ClassProbabilities(m.DesignMatrix(data), m.ClassCoeffMatrix())
+}
+
+// PredictClass returns the index of the most probable class for all rows in data
+func (m Model) PredictClass(data Dataset) []int {
+	prob := m.PredictProba(data)
+	r, _ := prob.Dims()
+	classes := make([]int, r)
+	for i := range classes {
+		classes[i] = argMax(prob.RawRowView(i))
+	}
+	return classes
+}
+
+func argMax(values []float64) int {
+	best := 0
+	for i, v := range values {
+		if v > values[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// ReadOptions returns options that reads a datafile with the same categorical encoding as
+// used when the model was trained. This includes the encoding of the raw data if the model
+// has a pipeline
+func (m Model) ReadOptions() ReadOptions {
+	encodings := []CategoricalEncoding{}
+	encodings = append(encodings, m.Categories...)
+	if m.Pipeline != nil {
+		encodings = append(encodings, m.Pipeline.Categories...)
+	}
+	return ReadOptions{
+		Encodings:   encodings,
+		ClassTarget: m.IsClassifier(),
+		Classes:     m.Classes,
+	}
 }
 
 // NewModel creates a new fitted model from the best individual of a GA run
@@ -370,6 +611,10 @@ func NewModel(best eaopt.Individual, dataset Dataset, cost string, datafile stri
 	res := bestMod.Optimize()
 	coeff := res.Coeff.RawVector().Data
 	features := dataset.IncludedFeatures(res.Include)
+	if bestMod.Config.Classifier != nil {
+		return newClassificationModel(res, features, dataset, cost, datafile)
+	}
+
 	model := Model{
 		Datafile:   datafile,
 		TargetName: dataset.TargetName,
@@ -377,11 +622,78 @@ func NewModel(best eaopt.Individual, dataset Dataset, cost string, datafile stri
 			Name:  cost,
 			Value: res.Score,
 		},
-		Coeffs: join2map(features, coeff),
+		Coeffs:     join2map(features, coeff),
+		Categories: dataset.Categories,
+	}
+	return model
+}
+
+// newClassificationModel creates a classification model from the result of optimizeClassifier
+func newClassificationModel(res OptimizeResult, features []string, dataset Dataset, cost string, datafile string) Model {
+	model := Model{
+		Datafile:   datafile,
+		TargetName: dataset.TargetName,
+		Score: Score{
+			Name:  cost,
+			Value: res.Score,
+		},
+		Coeffs:     map[string]float64{},
+		Categories: dataset.Categories,
+		Classes:    dataset.Classes,
+	}
+
+	p := len(features) + 1
+	for k := 0; k < len(dataset.Classes)-1; k++ {
+		block := res.Coeff.RawVector().Data[k*p : (k+1)*p]
+		model.ClassCoeffs = append(model.ClassCoeffs, join2map(features, block[:p-1]))
+		model.ClassIntercepts = append(model.ClassIntercepts, block[p-1])
+	}
+	return model
+}
+
+// NewELMModel converts a trained extreme learning machine into a model. The hidden layer is
+// stored as the last step of the pipeline, such that predictions can be made from data with
+// the input columns of the layer. The score is the in-sample RMSE on data.
+func NewELMModel(m elm.Model, pipeline Pipeline, data Dataset, datafile string) Model {
+	model := Model{
+		Datafile:   datafile,
+		TargetName: data.TargetName,
+		Coeffs:     join2map(m.Layer.Names, m.OutputWeights),
+		Categories: data.Categories,
 	}
+	p := pipeline.Append(PipelineStep{ELM: &ELMStep{Layer: m.Layer}})
+	model.Pipeline = &p
+	model.Score = rmseScore(m.Predict(data.Submatrix(m.Layer.Inputs)), data.Y)
 	return model
 }
 
+// NewKernelELMModel converts a trained kernel ELM into a model. The kernel is appended as the
+// last step of the pipeline, and the coefficients are the weights of the kernel columns
+func NewKernelELMModel(m elm.KernelModel, pipeline Pipeline, data Dataset, datafile string) Model {
+	model := Model{
+		Datafile:   datafile,
+		TargetName: data.TargetName,
+		Coeffs:     join2map(m.Kernel.Names, m.Weights),
+		Categories: data.Categories,
+	}
+	p := pipeline.Append(PipelineStep{Kernel: &KernelStep{Kernel: m.Kernel}})
+	model.Pipeline = &p
+	model.Score = rmseScore(m.Predict(data.Submatrix(m.Kernel.Inputs)), data.Y)
+	return model
+}
+
+// rmseScore returns the root mean square error between the predictions and the target values
+func rmseScore(pred *mat.VecDense, y *mat.VecDense) Score {
+	rss := 0.0
+	for i := 0; i < pred.Len(); i++ {
+		rss += math.Pow(pred.AtVec(i)-y.AtVec(i), 2)
+	}
+	return Score{
+		Name:  "rmse",
+		Value: math.Sqrt(rss / float64(pred.Len())),
+	}
+}
+
 func join2map(keys []string, values []float64) map[string]float64 {
 	res := make(map[string]float64)
 	for i := range keys {
@@ -390,8 +702,10 @@ func join2map(keys []string, values []float64) map[string]float64 {
 	return res
 }
 
-// SaveModel writes a JSON version of the model to file
+// SaveModel writes a JSON version of the model to file. The schema version is set to
+// ModelSchemaVersion
 func SaveModel(fname string, model Model) error {
+	model.SchemaVersion = ModelSchemaVersion
 	modelSerialized, err := json.MarshalIndent(model, "", "  ")
 	if err != nil {
 		return err
@@ -407,6 +721,13 @@ type GABackupCB struct {
 	DataFile   string
 	Rate       uint
 	BackupFile string
+
+	// Standardization is used to convert the coefficients back to original units before
+	// the backup is written. Leave it nil if the data is not standardized.
+	Standardization *Standardization
+
+	// Pipeline is stored in the backup model if not nil
+	Pipeline *Pipeline
 }
 
 // Build constructs the callback function
@@ -415,6 +736,10 @@ func (gab *GABackupCB) Build() func(ga *eaopt.GA) {
 		if ga.Generations%gab.Rate == 0 {
 			log.Printf("Best %s at generation %d: %f\n", gab.Cost, ga.Generations, ga.HallOfFame[0].Fitness)
 			model := NewModel(ga.HallOfFame[0], gab.Dataset, gab.Cost, gab.DataFile)
+			if gab.Standardization != nil {
+				model = gab.Standardization.Unscale(model)
+			}
+			model.Pipeline = gab.Pipeline
 			SaveModel(gab.BackupFile, model)
 		}
 	}
@@ -426,10 +751,24 @@ func GAProgressLogger(ga *eaopt.GA) {
 	log.Printf("Best fitness at generation %d: %f\n", ga.Generations, ga.HallOfFame[0].Fitness)
 }
 
+// DefaultIntervalLevel is the confidence level of the intervals returned by GetPredictions
+const DefaultIntervalLevel = 0.95
+
 // Prediction is a type that represent a prediction (the expected valud and the standard deviation)
 type Prediction struct {
 	Value float64
-	Std   float64
+
+	// Std is the standard deviation of a new observation, which includes both the uncertainty
+	// of the coefficients and the residual variance
+	Std float64
+
+	// ConfLower and ConfUpper are the bounds of the confidence interval of the mean response
+	ConfLower float64
+	ConfUpper float64
+
+	// PredLower and PredUpper are the bounds of the prediction interval of a new observation
+	PredLower float64
+	PredUpper float64
 }
 
 // IsEqual returns true of the two predictions are equal
@@ -448,13 +787,12 @@ func SavePredictions(fname string, pred []Prediction) error {
 
 	writer := csv.NewWriter(ofile)
 	defer writer.Flush()
-	err = writer.Write([]string{"prediction, stddev"})
+	err = writer.Write([]string{"prediction", "stddev", "conf_lower", "conf_upper", "pred_lower", "pred_upper"})
 	if err != nil {
 		return err
 	}
 	for _, p := range pred {
-		record := []string{fmt.Sprintf("%f", p.Value), fmt.Sprintf("%f", p.Std)}
-		err = writer.Write(record)
+		err = writer.Write(p.record())
 		if err != nil {
 			return err
 		}
@@ -462,7 +800,53 @@ func SavePredictions(fname string, pred []Prediction) error {
 	return nil
 }
 
-// ReadPredictions reads the predictions from a csv file (same as stored by SavePredictions)
+// record returns the prediction, the standard deviation and the interval bounds formatted
+// as in the columns written by SavePredictions
+func (p Prediction) record() []string {
+	record := []string{}
+	for _, v := range []float64{p.Value, p.Std, p.ConfLower, p.ConfUpper, p.PredLower, p.PredUpper} {
+		record = append(record, fmt.Sprintf("%f", v))
+	}
+	return record
+}
+
+// SaveClassPredictions stores the predicted class and the probability of each class for a
+// classification model. The probability columns are named p(<class>)
+func SaveClassPredictions(fname string, classes []string, prob *mat.Dense) error {
+	ofile, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer ofile.Close()
+
+	writer := csv.NewWriter(ofile)
+	defer writer.Flush()
+
+	header := []string{"prediction"}
+	for _, c := range classes {
+		header = append(header, fmt.Sprintf("p(%s)", c))
+	}
+	if err = writer.Write(header); err != nil {
+		return err
+	}
+
+	r, _ := prob.Dims()
+	for i := 0; i < r; i++ {
+		row := prob.RawRowView(i)
+		record := []string{classes[argMax(row)]}
+		for _, p := range row {
+			record = append(record, fmt.Sprintf("%f", p))
+		}
+		if err = writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadPredictions reads the predictions from a csv file (same as stored by SavePredictions).
+// Files without the interval columns (written by older versions) are also accepted, in which
+// case the interval bounds are zero
 func ReadPredictions(fname string) ([]Prediction, error) {
 	infile, err := os.Open(fname)
 	if err != nil {
@@ -484,61 +868,44 @@ func ReadPredictions(fname string) ([]Prediction, error) {
 		if err == io.EOF {
 			return pred, nil
 		}
-		value, err := strconv.ParseFloat(line[0], 64)
-		if err != nil {
-			return pred, err
-		}
-		std, err := strconv.ParseFloat(line[1], 64)
 		if err != nil {
 			return pred, err
 		}
 
-		pred = append(pred, Prediction{Value: value, Std: std})
+		var p Prediction
+		fields := []*float64{&p.Value, &p.Std, &p.ConfLower, &p.ConfUpper, &p.PredLower, &p.PredUpper}
+		if len(line) < 2 {
+			return pred, fmt.Errorf("Line %d: Expected at least 2 columns got %d", lineNo, len(line))
+		}
+		for i := 0; i < len(line) && i < len(fields); i++ {
+			if *fields[i], err = strconv.ParseFloat(line[i], 64); err != nil {
+				return pred, err
+			}
+		}
+		pred = append(pred, p)
 	}
 
 }
 
 // GetPredictions together with the standard deviations for all data in predData. If predData
-// is nil, data will be used (e.g. in sample prediction errors)
-func GetPredictions(data Dataset, model Model, predData *Dataset) []Prediction {
-	names := []string{}
-	coeffs := mat.NewVecDense(len(model.Coeffs), nil)
-	counter := 0
-
-	for k, v := range model.Coeffs {
-		names = append(names, k)
-		coeffs.SetVec(counter, v)
-		counter++
-	}
-
-	sub := data.Submatrix(names)
-
+// is nil, data will be used (e.g. in sample prediction errors). The intervals are given at
+// DefaultIntervalLevel
+func GetPredictions(data Dataset, model Model, predData *Dataset) ([]Prediction, error) {
+	return GetPredictionsWithLevel(data, model, predData, DefaultIntervalLevel)
+}
+
+// GetPredictionsWithLevel returns the predictions for all data in predData together with the
+// standard deviations and the confidence and prediction intervals at the given level (e.g. 0.95).
+// The model is assumed to be fitted to data. If predData is nil, data will be used. The
+// intervals are based on the quantile of the Student-t distribution with n - p degrees of
+// freedom, where n is the number of rows in data and p the number of coefficients. An error
+// is returned for classification models and if predData lacks features of the model
+func GetPredictionsWithLevel(data Dataset, model Model, predData *Dataset, level float64) ([]Prediction, error) {
 	if predData == nil {
 		predData = &data
 	}
 
-	rss := Rss(sub, data.Y, coeffs)
-	pred := Pred(sub, coeffs)
-	numData, numFeat := sub.Dims()
-	denum := numData - numFeat
-	if denum <= 0 {
-		denum = 1
-	}
-	correctedRss := rss / float64(denum)
-	cov, err := CovMatrix(sub, rss)
-	if err != nil {
-		panic(err)
-	}
-
-	subPred := predData.Submatrix(names)
-	r, _ := subPred.Dims()
-	variance := mat.NewDense(r, r, nil)
-	variance.Product(subPred, cov, subPred.T())
-
-	predictions := make([]Prediction, pred.Len())
-	for i := 0; i < pred.Len(); i++ {
-		predictions[i].Value = pred.AtVec(i)
-		predictions[i].Std = math.Sqrt(correctedRss + variance.At(i, i))
-	}
-	return predictions
+	// The inference quantities are always calculated from data, such that models fitted to
+	// other data can be evaluated
+	return model.WithInference(data).PredictWithUncertainty(*predData, level)
 }
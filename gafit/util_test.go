@@ -1,6 +1,7 @@
 package gafit
 
 import (
+	"io/ioutil"
 	"math"
 	"math/rand"
 	"os"
@@ -197,6 +198,31 @@ func TestHatMatrix(t *testing.T) {
 	}
 }
 
+func TestLeverages(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tall := mat.NewDense(20, 4, nil)
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 4; j++ {
+			tall.Set(i, j, rng.NormFloat64())
+		}
+	}
+
+	for i, X := range []*mat.Dense{
+		mat.NewDense(2, 2, []float64{1.0, 2.0, 3.0, 4.0}),
+		mat.NewDense(2, 3, []float64{1.0, 2.0, 3.0, 4.0, 5.0, 6.0}),
+		mat.NewDense(3, 2, []float64{1.0, 0.0, 1.0, 1.0, 1.0, 2.0}),
+		tall,
+	} {
+		H := HatMatrix(X)
+		h := Leverages(X)
+		for j := range h {
+			if math.Abs(h[j]-H.At(j, j)) > 1e-10 {
+				t.Errorf("Test #%d: Row %d: Expected leverage %f got %f\n", i, j, H.At(j, j), h[j])
+			}
+		}
+	}
+}
+
 func TestSubmatrixView(t *testing.T) {
 	sub := SubMatrix{
 		X:    mat.NewDense(3, 3, []float64{1.0, 2.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0, 9.0}),
@@ -284,7 +310,10 @@ func TestGetPredictions(t *testing.T) {
 
 	coeffs := mat.NewVecDense(3, []float64{1.0, 0.0, -2.0})
 	pred := Pred(dataset.X, coeffs)
-	predictions := GetPredictions(dataset, model, nil)
+	predictions, err := GetPredictions(dataset, model, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// Check that all agree
 	tol := 1e-8
@@ -295,15 +324,95 @@ func TestGetPredictions(t *testing.T) {
 	}
 }
 
+func TestGetPredictionsWithLevel(t *testing.T) {
+	// Simple linear regression y = a + bx. The least squares solution is a = 0.06 and b = 0.96,
+	// the residual sum of squares is 0.032 and the residual variance s^2 = 0.032/2 = 0.016
+	dataset := Dataset{
+		X:          mat.NewDense(4, 2, []float64{1.0, 0.0, 1.0, 1.0, 1.0, 2.0, 1.0, 3.0}),
+		Y:          mat.NewVecDense(4, []float64{0.1, 0.9, 2.1, 2.9}),
+		ColNames:   []string{"one", "x"},
+		TargetName: "y",
+	}
+	model := Model{Coeffs: map[string]float64{"one": 0.06, "x": 0.96}}
+
+	predData := Dataset{
+		X:        mat.NewDense(2, 2, []float64{1.0, 1.5, 1.0, 10.0}),
+		ColNames: []string{"one", "x"},
+	}
+
+	s2 := 0.016
+	for i, test := range []struct {
+		level float64
+
+		// Quantile of the Student-t distribution with 2 degrees of freedom
+		t float64
+	}{
+		{0.95, 4.302652729911275},
+		{0.9, 2.919985580355516},
+	} {
+		pred, err := GetPredictionsWithLevel(dataset, model, &predData, test.level)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+		if len(pred) != 2 {
+			t.Errorf("Test #%d: Expected 2 predictions got %d\n", i, len(pred))
+			continue
+		}
+
+		for j, x := range []float64{1.5, 10.0} {
+			// Variance of the mean response s^2(1/n + (x - mean(x))^2/Sxx) with Sxx = 5
+			meanVar := s2 * (0.25 + (x-1.5)*(x-1.5)/5.0)
+			value := 0.06 + 0.96*x
+			want := Prediction{
+				Value:     value,
+				Std:       math.Sqrt(s2 + meanVar),
+				ConfLower: value - test.t*math.Sqrt(meanVar),
+				ConfUpper: value + test.t*math.Sqrt(meanVar),
+				PredLower: value - test.t*math.Sqrt(s2+meanVar),
+				PredUpper: value + test.t*math.Sqrt(s2+meanVar),
+			}
+
+			got := pred[j]
+			for k, v := range [][2]float64{
+				{want.Value, got.Value},
+				{want.Std, got.Std},
+				{want.ConfLower, got.ConfLower},
+				{want.ConfUpper, got.ConfUpper},
+				{want.PredLower, got.PredLower},
+				{want.PredUpper, got.PredUpper},
+			} {
+				if math.Abs(v[0]-v[1]) > 1e-8 {
+					t.Errorf("Test #%d: Row %d: Field #%d: Want %f got %f\n", i, j, k, v[0], v[1])
+				}
+			}
+		}
+	}
+
+	// Classification models have no prediction intervals
+	classifier := Model{Classes: []string{"a", "b"}, ClassCoeffs: []map[string]float64{{"x": 1.0}}}
+	if _, err := GetPredictionsWithLevel(dataset, classifier, &predData, 0.95); err == nil {
+		t.Errorf("Expected error for a classification model\n")
+	}
+}
+
 func TestSaveReadRoundTrip(t *testing.T) {
 	predOrig := []Prediction{
 		{
-			Value: 1.0,
-			Std:   2.0,
+			Value:     1.0,
+			Std:       2.0,
+			ConfLower: 0.5,
+			ConfUpper: 1.5,
+			PredLower: -3.0,
+			PredUpper: 5.0,
 		},
 		{
-			Value: -2.0,
-			Std:   0.01,
+			Value:     -2.0,
+			Std:       0.01,
+			ConfLower: -2.005,
+			ConfUpper: -1.995,
+			PredLower: -2.02,
+			PredUpper: -1.98,
 		},
 		{
 			Value: 3.0,
@@ -330,11 +439,19 @@ func TestSaveReadRoundTrip(t *testing.T) {
 		return
 	}
 	for i := 0; i < len(predOrig); i++ {
-		if !predOrig[i].IsEqual(predRead[i]) {
+		if predOrig[i] != predRead[i] {
 			t.Errorf("Expected\n%+v\ngot\n%+v\n", predOrig[i], predRead[i])
 		}
 	}
 
+	// Files written by older versions only hold the prediction and the standard deviation
+	if err := ioutil.WriteFile(outfile, []byte("prediction,stddev\n1.0,2.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	predRead, err = ReadPredictions(outfile)
+	if err != nil || len(predRead) != 1 || predRead[0] != (Prediction{Value: 1.0, Std: 2.0}) {
+		t.Errorf("Unexpected predictions %v (%v) from a file without intervals\n", predRead, err)
+	}
 }
 
 func TestCovarianceConsistency(t *testing.T) {
@@ -371,3 +488,36 @@ func TestGADefaultLogger(t *testing.T) {
 	}
 	ga.Minimize(factory.Generate)
 }
+
+func TestIsNetwork(t *testing.T) {
+	for i, test := range []struct {
+		pipeline *Pipeline
+		want     bool
+	}{
+		{
+			pipeline: nil,
+			want:     false,
+		},
+		{
+			pipeline: &Pipeline{Steps: []PipelineStep{{Poly: &PolyStep{}}}},
+			want:     false,
+		},
+		{
+			pipeline: &Pipeline{Steps: []PipelineStep{{Poly: &PolyStep{}}, {ELM: &ELMStep{}}}},
+			want:     true,
+		},
+		{
+			pipeline: &Pipeline{Steps: []PipelineStep{{Kernel: &KernelStep{}}}},
+			want:     true,
+		},
+		{
+			pipeline: &Pipeline{Steps: []PipelineStep{{ELM: &ELMStep{}}, {Features: &FeatureManifest{}}}},
+			want:     false,
+		},
+	} {
+		model := Model{Pipeline: test.pipeline}
+		if got := model.IsNetwork(); got != test.want {
+			t.Errorf("Test #%d: Expected %v got %v\n", i, test.want, got)
+		}
+	}
+}
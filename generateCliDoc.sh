@@ -21,6 +21,11 @@ echo "\`\`\`" >> $FILE
 go run main.go rmse -h >> $FILE
 echo "\`\`\`" >> $FILE
 
+echo "## Metrics command" >> $FILE
+echo "\`\`\`" >> $FILE
+go run main.go metrics -h >> $FILE
+echo "\`\`\`" >> $FILE
+
 echo "## Plot command" >> $FILE
 echo "\`\`\`" >> $FILE
 go run main.go plot -h >> $FILE
@@ -31,6 +36,11 @@ echo "\`\`\`" >> $FILE
 go run main.go poly -h >> $FILE
 echo "\`\`\`" >> $FILE
 
+echo "## Features command" >> $FILE
+echo "\`\`\`" >> $FILE
+go run main.go features -h >> $FILE
+echo "\`\`\`" >> $FILE
+
 echo "## ELM command" >> $FILE
 echo "\`\`\`" >> $FILE
 go run main.go elm -h >> $FILE
@@ -40,3 +50,30 @@ echo "## Hook command" >> $FILE
 echo "\`\`\`" >> $FILE
 go run main.go hook -h >> $FILE
 echo "\`\`\`" >> $FILE 
+
+echo "## Model command" >> $FILE
+echo "\`\`\`" >> $FILE
+go run main.go model -h >> $FILE
+go run main.go model validate -h >> $FILE
+go run main.go model upgrade -h >> $FILE
+echo "\`\`\`" >> $FILE
+
+echo "## Export command" >> $FILE
+echo "\`\`\`" >> $FILE
+go run main.go export -h >> $FILE
+echo "\`\`\`" >> $FILE
+
+echo "## Compare command" >> $FILE
+echo "\`\`\`" >> $FILE
+go run main.go compare -h >> $FILE
+echo "\`\`\`" >> $FILE
+
+echo "## Summary command" >> $FILE
+echo "\`\`\`" >> $FILE
+go run main.go summary -h >> $FILE
+echo "\`\`\`" >> $FILE
+
+echo "## Stability command" >> $FILE
+echo "\`\`\`" >> $FILE
+go run main.go stability -h >> $FILE
+echo "\`\`\`" >> $FILE